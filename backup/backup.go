@@ -0,0 +1,269 @@
+// Package backup writes the task keyspace to compressed archives and loads
+// it back from them.
+//
+// An archive is a gzip-compressed tar file holding manifest.json, which
+// describes the snapshot, followed by keys.ndjson, with one stored key per
+// line. Secondary indexes are not archived; they are rebuilt on restore.
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"task-organizer/models"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Version is the archive format version written by this package.
+const Version = 1
+
+// Names of the files in an archive.
+const (
+	manifestFile = "manifest.json"
+	keysFile     = "keys.ndjson"
+)
+
+// pageSize is the number of keys read from etcd at a time.
+const pageSize = 1000
+
+// DefaultMaxSize is the limit on the uncompressed size of archives read when
+// MAX_BACKUP_SIZE is not set, in bytes.
+const DefaultMaxSize = 1 << 30
+
+// tarOverhead is the room left for tar headers and padding above the size of
+// the files of an archive.
+const tarOverhead = 64 << 10
+
+// Prefixes lists the key prefixes included in backups.
+var Prefixes = []string{models.TaskPrefix, models.TrashPrefix, models.AuditPrefix, models.FilterPrefix, models.CounterPrefix, models.WorkflowPrefix, models.CommentPrefix, models.AttachmentPrefix, models.LabelPrefix, models.LabelNamePrefix}
+
+// ErrInvalidArchive is returned for archives that are damaged or not backups.
+var ErrInvalidArchive = errors.New("Invalid backup archive")
+
+// Manifest describes the snapshot held by an archive.
+type Manifest struct {
+	Version   int       `json:"version"`    // Archive format version
+	CreatedAt time.Time `json:"created_at"` // When the snapshot was taken
+	Revision  int64     `json:"revision"`   // etcd revision the snapshot was taken at
+	Prefixes  []string  `json:"prefixes"`   // Key prefixes included in the snapshot
+	Keys      int       `json:"keys"`       // Number of keys in the snapshot
+	Checksum  string    `json:"checksum"`   // Hex SHA-256 of keys.ndjson
+}
+
+// Entry is a key of the snapshot.
+type Entry struct {
+	Key       string     `json:"key"`                  // Stored key
+	Value     string     `json:"value"`                // Stored value
+	Lease     int64      `json:"lease,omitempty"`      // Lease the key was attached to, shared by keys expiring together
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // When the lease of the key runs out
+}
+
+// Snapshot reads all keys under Prefixes at a single etcd revision.
+func Snapshot(ctx context.Context, h *models.Handler) (*Manifest, []Entry, error) {
+	m := &Manifest{Version: Version, CreatedAt: time.Now().UTC(), Prefixes: Prefixes}
+	var entries []Entry
+	expiries := map[int64]*time.Time{}
+
+	for _, prefix := range Prefixes {
+		key, end := prefix, clientv3.GetPrefixRangeEnd(prefix)
+		for {
+			opts := []clientv3.OpOption{clientv3.WithRange(end), clientv3.WithLimit(pageSize)}
+			if m.Revision != 0 {
+				opts = append(opts, clientv3.WithRev(m.Revision))
+			}
+			resp, err := h.Client.Get(ctx, key, opts...)
+			if err != nil {
+				return nil, nil, err
+			}
+			if m.Revision == 0 {
+				m.Revision = resp.Header.Revision
+			}
+
+			for _, kv := range resp.Kvs {
+				e := Entry{Key: string(kv.Key), Value: string(kv.Value), Lease: kv.Lease}
+				if kv.Lease != 0 {
+					// Keys sharing a lease share its expiry, so each lease is looked up once
+					expiresAt, ok := expiries[kv.Lease]
+					if !ok {
+						ttl, err := h.Client.TimeToLive(ctx, clientv3.LeaseID(kv.Lease))
+						if err != nil {
+							return nil, nil, err
+						}
+						t := time.Now().UTC().Add(time.Duration(ttl.TTL) * time.Second)
+						expiresAt = &t
+						expiries[kv.Lease] = expiresAt
+					}
+					e.ExpiresAt = expiresAt
+				}
+				entries = append(entries, e)
+			}
+
+			if !resp.More {
+				break
+			}
+			key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+		}
+	}
+
+	m.Keys = len(entries)
+	return m, entries, nil
+}
+
+// Write takes a snapshot of the keyspace and writes it to w as an archive.
+func Write(ctx context.Context, h *models.Handler, w io.Writer) (*Manifest, error) {
+	m, entries, err := Snapshot(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys bytes.Buffer
+	enc := json.NewEncoder(&keys)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return nil, err
+		}
+	}
+	sum := sha256.Sum256(keys.Bytes())
+	m.Checksum = hex.EncodeToString(sum[:])
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{{manifestFile, manifest}, {keysFile, keys.Bytes()}} {
+		hdr := &tar.Header{Name: file.name, Mode: 0o600, Size: int64(len(file.data)), ModTime: m.CreatedAt}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// MaxSize returns the limit on the uncompressed size of archives read, in
+// bytes. It is read from the MAX_BACKUP_SIZE environment variable, falling
+// back to DefaultMaxSize when unset or invalid.
+func MaxSize() int64 {
+	if v := os.Getenv("MAX_BACKUP_SIZE"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return DefaultMaxSize
+}
+
+// Read reads an archive and validates its version, checksum and keys.
+// Archives expanding beyond MaxSize are refused before they are read in full.
+// Errors about the archive itself wrap ErrInvalidArchive.
+func Read(r io.Reader) (*Manifest, []Entry, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, invalid("not a gzip file")
+	}
+	limit := MaxSize()
+	tr := tar.NewReader(io.LimitReader(gz, limit+tarOverhead))
+
+	files := map[string][]byte{}
+	var size int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, invalid(err.Error())
+		}
+		if hdr.Name != manifestFile && hdr.Name != keysFile {
+			return nil, nil, invalid("unexpected file " + hdr.Name)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, nil, invalid(hdr.Name + " is not a regular file")
+		}
+		if files[hdr.Name] != nil {
+			return nil, nil, invalid("duplicate file " + hdr.Name)
+		}
+		if size += hdr.Size; size > limit {
+			return nil, nil, invalid(fmt.Sprintf("archive expands beyond %d bytes", limit))
+		}
+		if files[hdr.Name], err = io.ReadAll(tr); err != nil {
+			return nil, nil, invalid(err.Error())
+		}
+	}
+	if files[manifestFile] == nil || files[keysFile] == nil {
+		return nil, nil, invalid("missing " + manifestFile + " or " + keysFile)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(files[manifestFile], &m); err != nil {
+		return nil, nil, invalid("malformed manifest: " + err.Error())
+	}
+	if m.Version != Version {
+		return nil, nil, invalid(fmt.Sprintf("unsupported version %d, expected %d", m.Version, Version))
+	}
+	sum := sha256.Sum256(files[keysFile])
+	if hex.EncodeToString(sum[:]) != m.Checksum {
+		return nil, nil, invalid("checksum mismatch")
+	}
+
+	var entries []Entry
+	dec := json.NewDecoder(bytes.NewReader(files[keysFile]))
+	for dec.More() {
+		var e Entry
+		if err := dec.Decode(&e); err != nil {
+			return nil, nil, invalid("malformed key: " + err.Error())
+		}
+		if !hasPrefix(e.Key, m.Prefixes) || !hasPrefix(e.Key, Prefixes) {
+			return nil, nil, invalid("unexpected key " + e.Key)
+		}
+		if strings.HasPrefix(e.Key, models.TaskPrefix) {
+			var task models.Task
+			if err := json.Unmarshal([]byte(e.Value), &task); err != nil {
+				return nil, nil, invalid("malformed task " + e.Key + ": " + err.Error())
+			}
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != m.Keys {
+		return nil, nil, invalid(fmt.Sprintf("expected %d keys, found %d", m.Keys, len(entries)))
+	}
+	return &m, entries, nil
+}
+
+func invalid(msg string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidArchive, msg)
+}
+
+func hasPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
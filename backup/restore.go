@@ -0,0 +1,162 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"task-organizer/models"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// ErrNotEmpty is returned when restoring into a keyspace that already holds keys.
+var ErrNotEmpty = errors.New("Keyspace is not empty; restore with force to replace it")
+
+// RestoreResult reports the outcome of a restore.
+type RestoreResult struct {
+	Manifest *Manifest `json:"manifest"` // Manifest of the restored archive
+	Restored int       `json:"restored"` // Number of keys loaded
+	Expired  int       `json:"expired"`  // Number of keys left out because they expired since the backup
+	Replaced int       `json:"replaced"` // Number of keys deleted before loading, when forced
+	DryRun   bool      `json:"dry_run"`  // Whether the archive was only validated
+}
+
+// Restore loads the keys of a validated archive into etcd and rebuilds the
+// secondary indexes. Keys whose lease ran out since the backup are left out.
+//
+// The keyspace must be empty unless force is set, in which case the keys
+// under Prefixes are replaced. Keys are loaded in transactions of at most
+// MaxTxnOps keys; if one fails, the keys loaded so far are removed again and
+// replaced keys are put back, and errors doing so are returned as well.
+//
+// A restore is not transactional as a whole: while it runs, clients see the
+// keyspace emptied and then partly loaded, and a failed rollback can leave it
+// that way. Restores are meant for a service that is not otherwise in use.
+func Restore(ctx context.Context, h *models.Handler, m *Manifest, entries []Entry, force bool) (*RestoreResult, error) {
+	result := &RestoreResult{Manifest: m}
+
+	// Keep what is stored now, to replace it or to refuse the restore
+	_, current, err := Snapshot(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	if len(current) > 0 && !force {
+		return nil, ErrNotEmpty
+	}
+	if len(current) > 0 {
+		if err := clearKeyspace(ctx, h); err != nil {
+			return nil, err
+		}
+		result.Replaced = len(current)
+	}
+
+	result.Restored, result.Expired, err = load(ctx, h, entries)
+	if err != nil {
+		// Put back what was stored before
+		if len(current) > 0 {
+			if _, _, rerr := load(ctx, h, current); rerr != nil {
+				return nil, errors.Join(err, rerr)
+			}
+		}
+		return nil, err
+	}
+
+	if err := h.RebuildIndexes(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// clearKeyspace deletes the keys under Prefixes and their secondary indexes.
+func clearKeyspace(ctx context.Context, h *models.Handler) error {
+	var ops []clientv3.Op
+	for _, prefix := range append([]string{models.IndexPrefix}, Prefixes...) {
+		ops = append(ops, clientv3.OpDelete(prefix, clientv3.WithPrefix()))
+	}
+	_, err := h.Client.Txn(ctx).Then(ops...).Commit()
+	return err
+}
+
+// load puts entries into etcd, each with a fresh lease for the remainder of
+// its original one. It returns the number of keys loaded and the number of
+// expired ones left out. If loading fails, the loaded keys are removed again.
+func load(ctx context.Context, h *models.Handler, entries []Entry) (int, int, error) {
+	leases := map[int64]clientv3.LeaseID{}
+	var loaded []string
+	expired := 0
+
+	fail := func(err error) (int, int, error) {
+		errs := []error{err}
+		var ops []clientv3.Op
+		for _, key := range loaded {
+			ops = append(ops, clientv3.OpDelete(key))
+		}
+		limit := models.MaxTxnOps()
+		for start := 0; start < len(ops); start += limit {
+			end := start + limit
+			if end > len(ops) {
+				end = len(ops)
+			}
+			if _, err := h.Client.Txn(ctx).Then(ops[start:end]...).Commit(); err != nil {
+				errs = append(errs, fmt.Errorf("removing loaded keys: %w", err))
+			}
+		}
+		for _, lease := range leases {
+			if _, err := h.Client.Revoke(ctx, lease); err != nil {
+				errs = append(errs, fmt.Errorf("revoking lease %x: %w", lease, err))
+			}
+		}
+		return 0, 0, errors.Join(errs...)
+	}
+
+	var cmps []clientv3.Cmp
+	var ops []clientv3.Op
+	var keys []string
+	commit := func() error {
+		txn, err := h.Client.Txn(ctx).If(cmps...).Then(ops...).Commit()
+		if err != nil {
+			return err
+		}
+		if !txn.Succeeded {
+			return models.ErrConflict
+		}
+		loaded = append(loaded, keys...)
+		cmps, ops, keys = nil, nil, nil
+		return nil
+	}
+
+	for _, e := range entries {
+		var lease clientv3.LeaseID
+		if e.Lease != 0 {
+			if e.ExpiresAt == nil || !e.ExpiresAt.After(time.Now()) {
+				expired++
+				continue
+			}
+			var ok bool
+			if lease, ok = leases[e.Lease]; !ok {
+				var err error
+				if lease, err = h.GrantLease(ctx, *e.ExpiresAt); err != nil {
+					return fail(err)
+				}
+				leases[e.Lease] = lease
+			}
+		}
+
+		// Every key must still be free, in case it was written since the restore began
+		cmps = append(cmps, clientv3.Compare(clientv3.CreateRevision(e.Key), "=", 0))
+		ops = append(ops, clientv3.OpPut(e.Key, e.Value, clientv3.WithLease(lease)))
+		keys = append(keys, e.Key)
+		if len(ops) == models.MaxTxnOps() {
+			if err := commit(); err != nil {
+				return fail(err)
+			}
+		}
+	}
+	if len(ops) > 0 {
+		if err := commit(); err != nil {
+			return fail(err)
+		}
+	}
+	return len(loaded), expired, nil
+}
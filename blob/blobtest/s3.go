@@ -0,0 +1,180 @@
+// Package blobtest provides an in-memory S3-compatible service to test blob
+// stores against.
+package blobtest
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"task-organizer/blob"
+)
+
+// Credentials the stores returned by S3Server.Store sign their requests with.
+const (
+	AccessKey = "test-access-key"
+	SecretKey = "test-secret-key"
+)
+
+// S3Server serves a single bucket kept in memory, supporting the requests
+// blob.S3Store sends. Requests must carry a Signature Version 4 made with
+// AccessKey; the signature itself is not verified.
+type S3Server struct {
+	*httptest.Server
+	Bucket   string // Name of the bucket served
+	PageSize int    // Maximum number of objects listed per page
+
+	mu      sync.Mutex
+	objects map[string]object
+}
+
+// object is a stored object.
+type object struct {
+	data    []byte
+	modTime time.Time
+}
+
+// NewS3Server starts a server of an empty bucket. The caller must close it.
+func NewS3Server(bucket string) *S3Server {
+	s := &S3Server{Bucket: bucket, PageSize: 1000, objects: map[string]object{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Store returns a store of the bucket.
+func (s *S3Server) Store() *blob.S3Store {
+	return &blob.S3Store{
+		Endpoint:  s.URL,
+		Bucket:    s.Bucket,
+		Region:    blob.DefaultRegion,
+		AccessKey: AccessKey,
+		SecretKey: SecretKey,
+		Client:    s.Client(),
+	}
+}
+
+// Keys returns the keys of the stored objects in order.
+func (s *S3Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys()
+}
+
+func (s *S3Server) serve(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential="+AccessKey+"/") ||
+		r.Header.Get("X-Amz-Date") == "" || r.Header.Get("X-Amz-Content-Sha256") == "" {
+		s.error(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != s.Bucket {
+		s.error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	if key == "" {
+		if r.Method != http.MethodGet || r.URL.Query().Get("list-type") != "2" {
+			s.error(w, http.StatusNotImplemented, "NotImplemented")
+			return
+		}
+		s.list(w, r.URL.Query().Get("continuation-token"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil || int64(len(data)) != r.ContentLength {
+			s.error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		s.mu.Lock()
+		s.objects[key] = object{data: data, modTime: time.Now().UTC().Truncate(time.Second)}
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		s.mu.Lock()
+		obj, ok := s.objects[key]
+		s.mu.Unlock()
+		if !ok {
+			s.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("Last-Modified", obj.modTime.Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(obj.data)
+		}
+	case http.MethodDelete:
+		// Deleting a missing object succeeds, as on S3
+		s.mu.Lock()
+		delete(s.objects, key)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// listResult is a ListObjectsV2 response.
+type listResult struct {
+	XMLName               xml.Name     `xml:"ListBucketResult"`
+	Contents              []listObject `xml:"Contents"`
+	IsTruncated           bool         `xml:"IsTruncated"`
+	NextContinuationToken string       `xml:"NextContinuationToken,omitempty"`
+}
+
+type listObject struct {
+	Key          string    `xml:"Key"`
+	Size         int       `xml:"Size"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+// list writes the page of objects following the one ended by token, which
+// is the last key of the previous page.
+func (s *S3Server) list(w http.ResponseWriter, token string) {
+	var result listResult
+	s.mu.Lock()
+	for _, key := range s.keys() {
+		if key <= token {
+			continue
+		}
+		if len(result.Contents) == s.PageSize {
+			result.IsTruncated = true
+			result.NextContinuationToken = result.Contents[len(result.Contents)-1].Key
+			break
+		}
+		obj := s.objects[key]
+		result.Contents = append(result.Contents, listObject{Key: key, Size: len(obj.data), LastModified: obj.modTime})
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+// keys returns the keys of the stored objects in order; s.mu must be held.
+func (s *S3Server) keys() []string {
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// error writes an S3 error response with the given code.
+func (s *S3Server) error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+	}{Code: code})
+}
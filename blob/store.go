@@ -0,0 +1,90 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultDir is where FileStore keeps blobs when BLOB_DIR is not set.
+const DefaultDir = "data/blobs"
+
+// ErrNotFound is returned when no blob is stored under a key.
+var ErrNotFound = errors.New("Blob not found")
+
+// Info describes a stored blob.
+type Info struct {
+	Key     string    // Key the blob is stored under
+	Size    int64     // Size of the content in bytes
+	ModTime time.Time // When the blob was last written
+}
+
+// Store keeps large contents outside of etcd. Keys are made of ASCII letters,
+// digits, - and _ only.
+type Store interface {
+	// Put stores size bytes read from r under key, replacing any previous content.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Open returns a reader of the content stored under key, ErrNotFound if there is none.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Stat describes the blob stored under key, ErrNotFound if there is none.
+	Stat(ctx context.Context, key string) (Info, error)
+	// Delete removes the blob stored under key, if any.
+	Delete(ctx context.Context, key string) error
+	// List calls fn for every stored blob, stopping at the first error.
+	List(ctx context.Context, fn func(Info) error) error
+}
+
+// FromEnv configures a store from the environment:
+//
+//   - BLOB_STORE selects the store: "file", the default, or "s3";
+//   - BLOB_DIR sets the directory of the file store, DefaultDir if unset;
+//   - S3_ENDPOINT, S3_BUCKET, S3_REGION, S3_ACCESS_KEY and S3_SECRET_KEY
+//     configure the S3 store, which works with any S3-compatible service.
+func FromEnv() (Store, error) {
+	switch store := envOr("BLOB_STORE", "file"); store {
+	case "file":
+		return NewFileStore(envOr("BLOB_DIR", DefaultDir))
+	case "s3":
+		s := &S3Store{
+			Endpoint:  strings.TrimSuffix(os.Getenv("S3_ENDPOINT"), "/"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    envOr("S3_REGION", DefaultRegion),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			Client:    http.DefaultClient,
+		}
+		if s.Endpoint == "" || s.Bucket == "" {
+			return nil, errors.New("S3_ENDPOINT and S3_BUCKET are required by the s3 blob store")
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("invalid blob store %q: expected file or s3", store)
+	}
+}
+
+func envOr(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// validKey reports whether a key is usable as a file name and an object name as is.
+func validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	return strings.Trim(key, "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_") == ""
+}
+
+func checkKey(key string) error {
+	if !validKey(key) {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package blob_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"task-organizer/blob"
+	"task-organizer/blob/blobtest"
+)
+
+// stores returns the stores to test, each empty.
+func stores(t *testing.T) map[string]blob.Store {
+	files, err := blob.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := blobtest.NewS3Server("blobs")
+	t.Cleanup(server.Close)
+	// A small page size makes List follow continuation tokens
+	server.PageSize = 2
+	return map[string]blob.Store{"file": files, "s3": server.Store()}
+}
+
+// read returns the content stored under key.
+func read(t *testing.T, s blob.Store, key string) string {
+	t.Helper()
+	r, err := s.Open(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Open(%s): %v", key, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading %s: %v", key, err)
+	}
+	return string(data)
+}
+
+// list returns the keys and sizes of the stored blobs.
+func list(t *testing.T, s blob.Store) map[string]int64 {
+	t.Helper()
+	blobs := map[string]int64{}
+	err := s.List(context.Background(), func(info blob.Info) error {
+		if _, ok := blobs[info.Key]; ok {
+			t.Errorf("List reported %s twice", info.Key)
+		}
+		blobs[info.Key] = info.Size
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	return blobs
+}
+
+func TestPutOpen(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := s.Put(ctx, "ab12", strings.NewReader("first"), 5); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			if got := read(t, s, "ab12"); got != "first" {
+				t.Errorf("content = %q, want %q", got, "first")
+			}
+
+			// Putting again replaces the content
+			if err := s.Put(ctx, "ab12", strings.NewReader("second!"), 7); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			if got := read(t, s, "ab12"); got != "second!" {
+				t.Errorf("content = %q, want %q", got, "second!")
+			}
+			info, err := s.Stat(ctx, "ab12")
+			if err != nil {
+				t.Fatalf("Stat: %v", err)
+			}
+			if info.Key != "ab12" || info.Size != 7 || info.ModTime.IsZero() {
+				t.Errorf("Stat = %+v", info)
+			}
+
+			if err := s.Put(ctx, "empty", strings.NewReader(""), 0); err != nil {
+				t.Fatalf("Put of an empty blob: %v", err)
+			}
+			if got := read(t, s, "empty"); got != "" {
+				t.Errorf("empty blob = %q", got)
+			}
+		})
+	}
+}
+
+func TestPutWrongSize(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := s.Put(ctx, "short", strings.NewReader("abc"), 5); err == nil {
+				t.Error("Put of 3 bytes as 5 succeeded")
+			}
+			if _, err := s.Stat(ctx, "short"); !errors.Is(err, blob.ErrNotFound) {
+				t.Errorf("Stat after a failed Put: %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := s.Open(ctx, "missing"); !errors.Is(err, blob.ErrNotFound) {
+				t.Errorf("Open: %v, want ErrNotFound", err)
+			}
+			if _, err := s.Stat(ctx, "missing"); !errors.Is(err, blob.ErrNotFound) {
+				t.Errorf("Stat: %v, want ErrNotFound", err)
+			}
+			if err := s.Delete(ctx, "missing"); err != nil {
+				t.Errorf("Delete: %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestInvalidKey(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, key := range []string{"", "../etc", "a/b", "a.b", "a b"} {
+				if err := s.Put(ctx, key, strings.NewReader("x"), 1); err == nil {
+					t.Errorf("Put(%q) succeeded", key)
+				}
+				if _, err := s.Open(ctx, key); err == nil || errors.Is(err, blob.ErrNotFound) {
+					t.Errorf("Open(%q): %v, want an invalid key", key, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteList(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			want := map[string]int64{}
+			for _, key := range []string{"a", "b1", "b2", "c3", "cd4"} {
+				if err := s.Put(ctx, key, strings.NewReader(key), int64(len(key))); err != nil {
+					t.Fatalf("Put(%s): %v", key, err)
+				}
+				want[key] = int64(len(key))
+			}
+			if got := list(t, s); !reflect.DeepEqual(got, want) {
+				t.Errorf("List = %v, want %v", got, want)
+			}
+
+			if err := s.Delete(ctx, "b2"); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			delete(want, "b2")
+			if _, err := s.Stat(ctx, "b2"); !errors.Is(err, blob.ErrNotFound) {
+				t.Errorf("Stat after Delete: %v, want ErrNotFound", err)
+			}
+			if got := list(t, s); !reflect.DeepEqual(got, want) {
+				t.Errorf("List after Delete = %v, want %v", got, want)
+			}
+
+			// List stops at the first error of fn
+			stop := errors.New("stop")
+			calls := 0
+			err := s.List(ctx, func(blob.Info) error {
+				calls++
+				return stop
+			})
+			if !errors.Is(err, stop) || calls != 1 {
+				t.Errorf("List returned %v after %d calls, want stop after 1", err, calls)
+			}
+		})
+	}
+}
+
+func TestFileStoreSkipsPartialFiles(t *testing.T) {
+	s, err := blob.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(context.Background(), "ab12", strings.NewReader("done"), 4); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.Dir, "ab", ".tmp-123"), []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := list(t, s); !reflect.DeepEqual(got, map[string]int64{"ab12": 4}) {
+		t.Errorf("List = %v, want only ab12", got)
+	}
+}
+
+func TestS3StoreSignsRequests(t *testing.T) {
+	server := blobtest.NewS3Server("blobs")
+	defer server.Close()
+
+	// Requests without credentials are refused
+	s := server.Store()
+	s.AccessKey = ""
+	if err := s.Put(context.Background(), "ab12", strings.NewReader("x"), 1); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("unsigned Put: %v, want 403", err)
+	}
+
+	// A missing bucket is an error rather than an empty listing
+	s = server.Store()
+	s.Bucket = "other"
+	if err := s.List(context.Background(), func(blob.Info) error { return nil }); err == nil || errors.Is(err, blob.ErrNotFound) {
+		t.Errorf("List of a missing bucket: %v, want an error", err)
+	}
+}
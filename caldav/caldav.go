@@ -0,0 +1,184 @@
+// Package caldav implements the parts of WebDAV (RFC 4918) and CalDAV
+// (RFC 4791) needed to expose the tasks as a calendar of VTODO components.
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/url"
+	"strconv"
+	"strings"
+	"task-organizer/models"
+	"task-organizer/transfer"
+)
+
+// Paths of the CalDAV resources. The root doubles as principal and calendar
+// home; the tasks are the resources of a single calendar collection.
+const (
+	RootPath     = "/caldav/"
+	CalendarPath = "/caldav/tasks/"
+)
+
+// Statuses reported for properties and resources.
+const (
+	StatusOK       = "HTTP/1.1 200 OK"
+	StatusNotFound = "HTTP/1.1 404 Not Found"
+)
+
+// Reports supported on the calendar collection.
+const (
+	ReportCalendarQuery    = "calendar-query"
+	ReportCalendarMultiget = "calendar-multiget"
+)
+
+// Multistatus is the body of a 207 Multi-Status response.
+type Multistatus struct {
+	XMLName   xml.Name   `xml:"d:multistatus"`
+	DAV       string     `xml:"xmlns:d,attr"`
+	CalDAV    string     `xml:"xmlns:c,attr"`
+	CS        string     `xml:"xmlns:cs,attr"`
+	Responses []Response `xml:"d:response"`
+}
+
+// Response describes a single resource of a multistatus body.
+type Response struct {
+	Href     string     `xml:"d:href"`
+	Propstat []Propstat `xml:"d:propstat,omitempty"`
+	Status   string     `xml:"d:status,omitempty"` // Set instead of Propstat for missing resources
+}
+
+// Propstat groups properties of a resource with their status.
+type Propstat struct {
+	Prop   Prop   `xml:"d:prop"`
+	Status string `xml:"d:status"`
+}
+
+// Prop holds the properties of a resource; unset ones are left out.
+type Prop struct {
+	ResourceType         *ResourceType `xml:"d:resourcetype,omitempty"`
+	DisplayName          string        `xml:"d:displayname,omitempty"`
+	CurrentUserPrincipal *Href         `xml:"d:current-user-principal,omitempty"`
+	CalendarHomeSet      *Href         `xml:"c:calendar-home-set,omitempty"`
+	SupportedComponents  *CompSet      `xml:"c:supported-calendar-component-set,omitempty"`
+	CTag                 string        `xml:"cs:getctag,omitempty"`
+	ETag                 string        `xml:"d:getetag,omitempty"`
+	ContentType          string        `xml:"d:getcontenttype,omitempty"`
+	CalendarData         string        `xml:"c:calendar-data,omitempty"`
+}
+
+// ResourceType tells collections and calendars apart from plain resources.
+type ResourceType struct {
+	Collection *struct{} `xml:"d:collection,omitempty"`
+	Calendar   *struct{} `xml:"c:calendar,omitempty"`
+}
+
+// Href is a property holding a link to another resource.
+type Href struct {
+	Href string `xml:"d:href"`
+}
+
+// CompSet lists the calendar components a collection holds.
+type CompSet struct {
+	Comps []Comp `xml:"c:comp"`
+}
+
+// Comp names a calendar component.
+type Comp struct {
+	Name string `xml:"name,attr"`
+}
+
+// NewMultistatus returns a multistatus body with the given responses.
+func NewMultistatus(responses ...Response) Multistatus {
+	return Multistatus{
+		DAV:       "DAV:",
+		CalDAV:    "urn:ietf:params:xml:ns:caldav",
+		CS:        "http://calendarserver.org/ns/",
+		Responses: responses,
+	}
+}
+
+// Marshal renders a multistatus body as an XML document.
+func (ms Multistatus) Marshal() ([]byte, error) {
+	data, err := xml.Marshal(ms)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// OK returns a response reporting properties found on a resource.
+func OK(href string, prop Prop) Response {
+	return Response{Href: href, Propstat: []Propstat{{Prop: prop, Status: StatusOK}}}
+}
+
+// Calendar returns the properties of the calendar collection; ctag changes
+// whenever any of its tasks does.
+func Calendar(ctag int64) Prop {
+	return Prop{
+		ResourceType:        &ResourceType{Collection: &struct{}{}, Calendar: &struct{}{}},
+		DisplayName:         "Tasks",
+		SupportedComponents: &CompSet{Comps: []Comp{{Name: "VTODO"}}},
+		CTag:                strconv.FormatInt(ctag, 10),
+	}
+}
+
+// Principal returns the properties of the root, which is both the principal
+// and its calendar home.
+func Principal() Prop {
+	return Prop{
+		ResourceType:         &ResourceType{Collection: &struct{}{}},
+		DisplayName:          "Task organizer",
+		CurrentUserPrincipal: &Href{RootPath},
+		CalendarHomeSet:      &Href{RootPath},
+	}
+}
+
+// ReportReq is the body of a REPORT request on the calendar collection.
+type ReportReq struct {
+	XMLName xml.Name
+	Hrefs   []string `xml:"DAV: href"` // Requested resources of a calendar-multiget
+}
+
+// TaskHref returns the path of the resource of a task.
+func TaskHref(id string) string {
+	return CalendarPath + url.PathEscape(id) + ".ics"
+}
+
+// TaskID returns the ID of the task an escaped resource path or URL refers to.
+func TaskID(href string) (string, bool) {
+	if u, err := url.Parse(href); err == nil {
+		href = u.EscapedPath()
+	}
+	name := strings.TrimPrefix(href, CalendarPath)
+	if len(name) == len(href) || strings.Contains(name, "/") || !strings.HasSuffix(name, ".ics") {
+		return "", false
+	}
+	id, err := url.PathUnescape(strings.TrimSuffix(name, ".ics"))
+	if err != nil || id == "" {
+		return "", false
+	}
+	return id, true
+}
+
+// TaskProp returns the properties of the resource of a task stored at an etcd
+// revision, with its calendar data if given.
+func TaskProp(modRevision int64, data string) Prop {
+	return Prop{ETag: models.ETag(modRevision), ContentType: ContentType, CalendarData: data}
+}
+
+// ContentType is the media type of task resources.
+const ContentType = "text/calendar; charset=utf-8; component=VTODO"
+
+// CalendarData renders a task as an iCalendar object holding a single VTODO.
+func CalendarData(task models.Task) (string, error) {
+	format, _ := transfer.Lookup("ics")
+	var b bytes.Buffer
+	enc := format.NewEncoder(&b)
+	if err := enc.Encode(task); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,358 @@
+// Package client is a typed Go client for the task organizer HTTP API.
+//
+// Requests that are safe to repeat are retried with exponential backoff when
+// the server answers with a 5xx status or the request times out. Tasks carry
+// ETags, which Update and Delete accept as preconditions and Patch uses to
+// apply changes without losing concurrent ones.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"task-organizer/models"
+	"time"
+)
+
+// Defaults of new clients.
+const (
+	DefaultRetries = 3                      // Retries after the first attempt of a request
+	DefaultBackoff = 200 * time.Millisecond // Delay before the first retry, doubled for each further one
+	DefaultTimeout = 30 * time.Second       // Timeout of each attempt
+)
+
+// Client sends requests to a task organizer server.
+type Client struct {
+	BaseURL    string        // URL of the server, such as http://localhost:8080
+	Token      string        // Bearer token sent with every request, if set
+	Actor      string        // Actor recorded in the audit log for mutations, if set
+	Retries    int           // Retries of failed idempotent requests
+	Backoff    time.Duration // Delay before the first retry, doubled for each further one
+	HTTPClient *http.Client  // Client used for requests, http.DefaultClient if nil
+}
+
+// New returns a client for the server at baseURL.
+func New(baseURL, token string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Token:      token,
+		Retries:    DefaultRetries,
+		Backoff:    DefaultBackoff,
+		HTTPClient: &http.Client{Timeout: DefaultTimeout},
+	}
+}
+
+// Error is returned for requests the server answered with an error status.
+type Error struct {
+	StatusCode int    // HTTP status of the response
+	Message    string // Error reported by the server
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d %s)", e.Message, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// IsNotFound reports whether err is an error for a task that does not exist.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsPreconditionFailed reports whether err is an error for a task whose
+// ETag did not match the one required.
+func IsPreconditionFailed(err error) bool {
+	return hasStatus(err, http.StatusPreconditionFailed)
+}
+
+func hasStatus(err error, status int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// ListOptions selects the tasks returned by List. Zero values do not filter.
+type ListOptions struct {
+	Completed *bool  // Only tasks with this completion status
+	Tag       string // Only tasks with this tag
+	Assignee  string // Only tasks assigned to this person
+	Filter    string // Filter expression, such as priority >= high AND tag:backend
+	Saved     string // Name of a saved filter to apply
+}
+
+// ImportOptions controls an import.
+type ImportOptions struct {
+	Conflict string // Strategy for taken IDs: skip (default), overwrite or regenerate
+	DryRun   bool   // Only validate the import
+}
+
+// Patch lists the fields of a task to change; nil fields keep their values.
+type Patch struct {
+	Title       *string    // New title
+	Description *string    // New description
+	Completed   *bool      // New completion status
+	Priority    *string    // New priority
+	Tags        *[]string  // New tags
+	Due         *time.Time // New due date
+	ClearDue    bool       // Removes the due date
+	Assignee    *string    // New assignee
+	Recurrence  *string    // New recurrence rule
+	TTL         *int64     // New lifetime in seconds; 0 removes the expiry
+	ExpiresAt   *time.Time // New absolute expiry time
+}
+
+// List returns the tasks selected by opts.
+func (c *Client) List(ctx context.Context, opts ListOptions) ([]models.Task, error) {
+	q := url.Values{}
+	if opts.Completed != nil {
+		q.Set("completed", strconv.FormatBool(*opts.Completed))
+	}
+	for name, value := range map[string]string{"tag": opts.Tag, "assignee": opts.Assignee, "filter": opts.Filter, "saved": opts.Saved} {
+		if value != "" {
+			q.Set(name, value)
+		}
+	}
+
+	tasks := []models.Task{}
+	_, err := c.do(ctx, &request{method: http.MethodGet, path: "/tasks", query: q}, &tasks)
+	return tasks, err
+}
+
+// Get returns the task with the given ID and its ETag, which changes with every write.
+func (c *Client) Get(ctx context.Context, id string) (*models.Task, string, error) {
+	var task models.Task
+	resp, err := c.do(ctx, &request{method: http.MethodGet, path: taskPath(id)}, &task)
+	if err != nil {
+		return nil, "", err
+	}
+	return &task, resp.Header.Get("ETag"), nil
+}
+
+// Create creates a task and returns it as stored. It is not retried, since
+// repeating it could create the task twice.
+func (c *Client) Create(ctx context.Context, task models.Task) (*models.Task, error) {
+	var created models.Task
+	if _, err := c.doJSON(ctx, http.MethodPost, "/tasks", nil, task, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Update applies an update request to the task with the given ID and returns
+// its new ETag. If ifMatch is set, the task is only updated if its ETag still
+// matches; otherwise an error satisfying IsPreconditionFailed is returned.
+func (c *Client) Update(ctx context.Context, id string, req models.UpdateReq, ifMatch string) (string, error) {
+	resp, err := c.doJSON(ctx, http.MethodPut, taskPath(id), precondition(ifMatch), req, nil)
+	if err != nil {
+		return "", err
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+// Patch changes the given fields of the task with the given ID and returns
+// its new ETag. The task is read and written back only if it was not changed
+// in between, retrying if it was.
+func (c *Client) Patch(ctx context.Context, id string, p Patch) (string, error) {
+	for attempt := 0; ; attempt++ {
+		task, etag, err := c.Get(ctx, id)
+		if err != nil {
+			return "", err
+		}
+
+		req := models.UpdateReq{
+			Title:       task.Title,
+			Completed:   p.Completed,
+			Description: p.Description,
+			Priority:    p.Priority,
+			Tags:        p.Tags,
+			Due:         p.Due,
+			ClearDue:    p.ClearDue,
+			Assignee:    p.Assignee,
+			Recurrence:  p.Recurrence,
+			TTL:         p.TTL,
+			ExpiresAt:   p.ExpiresAt,
+		}
+		if p.Title != nil {
+			req.Title = *p.Title
+		}
+
+		etag, err = c.Update(ctx, id, req, etag)
+		if IsPreconditionFailed(err) && attempt < c.Retries {
+			continue
+		}
+		return etag, err
+	}
+}
+
+// Delete moves the task with the given ID to the trash. If ifMatch is set,
+// the task is only deleted if its ETag still matches.
+func (c *Client) Delete(ctx context.Context, id string, ifMatch string) error {
+	_, err := c.do(ctx, &request{method: http.MethodDelete, path: taskPath(id), header: precondition(ifMatch)}, nil)
+	return err
+}
+
+// Export returns all tasks in the given format (json, ndjson, csv, md, ics, todotxt).
+// The caller must close the returned reader.
+func (c *Client) Export(ctx context.Context, format string) (io.ReadCloser, error) {
+	resp, err := c.send(ctx, &request{method: http.MethodGet, path: "/tasks/export", query: url.Values{"format": {format}}})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Import imports the tasks read from r in the given format.
+func (c *Client) Import(ctx context.Context, format string, r io.Reader, opts ImportOptions) (*models.ImportResp, error) {
+	q := url.Values{"format": {format}}
+	if opts.Conflict != "" {
+		q.Set("conflict", opts.Conflict)
+	}
+	if opts.DryRun {
+		q.Set("dry_run", "true")
+	}
+
+	var resp models.ImportResp
+	req := &request{method: http.MethodPost, path: "/tasks/import", query: q, stream: r, contentType: "application/octet-stream"}
+	if _, err := c.do(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// taskPath returns the path of the task with the given ID.
+func taskPath(id string) string {
+	return "/tasks/" + url.PathEscape(id)
+}
+
+// precondition returns the header requiring the given ETag, if any.
+func precondition(ifMatch string) http.Header {
+	if ifMatch == "" {
+		return nil
+	}
+	return http.Header{"If-Match": {ifMatch}}
+}
+
+// request describes a request to send.
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	header      http.Header
+	body        []byte    // Body of the request, sent again on retries
+	stream      io.Reader // Body of the request that cannot be sent again
+	contentType string
+}
+
+// doJSON sends in as a JSON body and decodes the response into out, if given.
+func (c *Client) doJSON(ctx context.Context, method, path string, header http.Header, in, out interface{}) (*http.Response, error) {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(ctx, &request{method: method, path: path, header: header, body: body, contentType: "application/json"}, out)
+}
+
+// do sends a request and decodes the JSON response into out, if given.
+func (c *Client) do(ctx context.Context, r *request, out interface{}) (*http.Response, error) {
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return resp, nil
+	}
+	return resp, json.NewDecoder(resp.Body).Decode(out)
+}
+
+// send sends a request, retrying it if it may be repeated, and returns the
+// response if its status is successful.
+func (c *Client) send(ctx context.Context, r *request) (*http.Response, error) {
+	retries := 0
+	if r.stream == nil && r.method != http.MethodPost {
+		retries = c.Retries
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(ctx, r)
+		if attempt == retries || !retryable(resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+
+		// Wait twice as long after every attempt, with jitter so that
+		// clients failing together do not retry together
+		delay := c.Backoff << attempt
+		delay += time.Duration(rand.Int63n(int64(delay)/2 + 1))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryable reports whether an attempt failed in a way that may pass on retry.
+func retryable(resp *http.Response, err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode >= 500
+}
+
+// attempt sends a request once.
+func (c *Client) attempt(ctx context.Context, r *request) (*http.Response, error) {
+	u := c.BaseURL + r.path
+	if len(r.query) > 0 {
+		u += "?" + r.query.Encode()
+	}
+	body := r.stream
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if c.Actor != "" {
+		req.Header.Set("X-Actor", c.Actor)
+	}
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		apiErr := &Error{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Error != "" {
+			apiErr.Message = body.Error
+		}
+		return nil, apiErr
+	}
+	return resp, nil
+}
@@ -0,0 +1,278 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"task-organizer/client"
+	"task-organizer/models"
+	"task-organizer/routers"
+
+	"github.com/gin-gonic/gin"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// server serves the real router, backed by the local etcd members; nil if
+// they are not reachable.
+var server *httptest.Server
+
+// skipReason tells why the tests needing the server are skipped.
+var skipReason string
+
+func TestMain(m *testing.M) {
+	// The router needs etcd on both ports; without it the tests are skipped one by one
+	for _, addr := range []string{"localhost:2379", "localhost:2380"} {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err != nil {
+			skipReason = "etcd is not reachable at " + addr
+			os.Exit(m.Run())
+		}
+		conn.Close()
+	}
+
+	dir, err := os.MkdirTemp("", "blobs")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("BLOB_DIR", dir)
+	os.Setenv("GRPC_ADDR", "127.0.0.1:0")
+	os.Setenv("RATE_LIMIT_READ", "off")
+	os.Setenv("RATE_LIMIT_WRITE", "off")
+	// Keep the keys of this run apart from those of other runs and deployments
+	os.Setenv("ETCD_NAMESPACE", fmt.Sprintf("client-test-%d/", time.Now().UnixNano()))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routers.IdeaRouter(r)
+	server = httptest.NewServer(r)
+
+	code := m.Run()
+	server.Close()
+	clearNamespace()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// clearNamespace deletes the keys written by the run.
+func clearNamespace() {
+	h1, h2, err := models.Init()
+	if err != nil {
+		fmt.Println("clearing the test keys:", err)
+		return
+	}
+	for _, h := range []*models.Handler{h1, h2} {
+		if _, err := h.Client.Delete(context.Background(), "", clientv3.WithPrefix()); err != nil {
+			fmt.Println("clearing the test keys:", err)
+		}
+		h.Client.Close()
+	}
+}
+
+// newClient returns a client of the test server, skipping the test if there is none.
+func newClient(t *testing.T) *client.Client {
+	t.Helper()
+	if server == nil {
+		t.Skip(skipReason)
+	}
+	c := client.New(server.URL, "")
+	c.Actor = "client-test"
+	return c
+}
+
+// uniqueTitle returns a title no other test run uses.
+func uniqueTitle(t *testing.T) string {
+	return fmt.Sprintf("%s %d", t.Name(), time.Now().UnixNano())
+}
+
+// createTask creates a task that is moved to the trash when the test ends.
+func createTask(t *testing.T, c *client.Client, task models.Task) *models.Task {
+	t.Helper()
+	created, err := c.Create(context.Background(), task)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := c.Delete(context.Background(), created.ID, ""); err != nil && !client.IsNotFound(err) {
+			t.Errorf("Delete %s: %v", created.ID, err)
+		}
+	})
+	return created
+}
+
+func TestCreateGetList(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+	tag := fmt.Sprintf("client-test-%d", time.Now().UnixNano())
+	created := createTask(t, c, models.Task{Title: uniqueTitle(t), Priority: "high", Tags: []string{tag}})
+	if created.ID == "" || created.Priority != "high" {
+		t.Fatalf("Create returned %+v", created)
+	}
+
+	got, etag, err := c.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Title != created.Title || etag == "" {
+		t.Errorf("Get = %+v, etag %q; want title %q and an etag", got, etag, created.Title)
+	}
+
+	tasks, err := c.List(ctx, client.ListOptions{Tag: tag})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != created.ID {
+		t.Errorf("List(tag %s) = %+v, want only %s", tag, tasks, created.ID)
+	}
+}
+
+func TestUpdateIfMatch(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+	created := createTask(t, c, models.Task{Title: uniqueTitle(t)})
+
+	_, etag, err := c.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	newEtag, err := c.Update(ctx, created.ID, models.UpdateReq{Title: created.Title + " updated"}, etag)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if newEtag == "" || newEtag == etag {
+		t.Errorf("Update returned etag %q, want one other than %q", newEtag, etag)
+	}
+
+	// The old ETag no longer matches
+	_, err = c.Update(ctx, created.ID, models.UpdateReq{Title: created.Title + " stale"}, etag)
+	if !client.IsPreconditionFailed(err) {
+		t.Errorf("Update with a stale etag: %v, want a failed precondition", err)
+	}
+	got, _, err := c.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Title != created.Title+" updated" {
+		t.Errorf("title = %q, want %q", got.Title, created.Title+" updated")
+	}
+}
+
+func TestPatch(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+	created := createTask(t, c, models.Task{Title: uniqueTitle(t), Description: "kept", Priority: "low"})
+
+	completed := true
+	priority := "urgent"
+	if _, err := c.Patch(ctx, created.ID, client.Patch{Completed: &completed, Priority: &priority}); err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	got, _, err := c.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !got.Completed || got.Priority != "urgent" || got.Title != created.Title || got.Description != "kept" {
+		t.Errorf("after Patch: %+v", got)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+	created := createTask(t, c, models.Task{Title: uniqueTitle(t)})
+
+	if err := c.Delete(ctx, created.ID, ""); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, _, err := c.Get(ctx, created.ID); !client.IsNotFound(err) {
+		t.Errorf("Get after Delete: %v, want not found", err)
+	}
+	if err := c.Delete(ctx, created.ID, ""); !client.IsNotFound(err) {
+		t.Errorf("second Delete: %v, want not found", err)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+	created := createTask(t, c, models.Task{Title: uniqueTitle(t)})
+
+	body, err := c.Export(ctx, "ndjson")
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	data, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		t.Fatalf("reading export: %v", err)
+	}
+	var exported string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.Contains(line, created.ID) {
+			exported = line
+		}
+	}
+	if exported == "" {
+		t.Fatalf("export does not contain task %s", created.ID)
+	}
+
+	// Importing the task again with its ID taken skips it
+	resp, err := c.Import(ctx, "ndjson", strings.NewReader(exported+"\n"), client.ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if !resp.DryRun || resp.Skipped != 1 || resp.Created != 0 {
+		t.Errorf("Import = %+v, want one skipped task in a dry run", resp)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	c := newClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	tag := fmt.Sprintf("client-test-%d", time.Now().UnixNano())
+
+	events, err := c.Watch(ctx, client.ListOptions{Tag: tag}, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	created := createTask(t, c, models.Task{Title: uniqueTitle(t), Tags: []string{tag}})
+
+	// next returns the next event of the created task
+	next := func() client.Event {
+		t.Helper()
+		for ev := range events {
+			if ev.Type == client.EventError {
+				t.Fatalf("watch error: %v", ev.Err)
+			}
+			if ev.Task.ID == created.ID {
+				return ev
+			}
+		}
+		t.Fatal("watch ended before the event")
+		return client.Event{}
+	}
+
+	if ev := next(); ev.Type != client.EventAdded {
+		t.Fatalf("first event = %s, want %s", ev.Type, client.EventAdded)
+	}
+	completed := true
+	if _, err := c.Patch(ctx, created.ID, client.Patch{Completed: &completed}); err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	if ev := next(); ev.Type != client.EventUpdated || !ev.Task.Completed {
+		t.Fatalf("second event = %s %+v, want a completed task %s", ev.Type, ev.Task, client.EventUpdated)
+	}
+	if err := c.Delete(ctx, created.ID, ""); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if ev := next(); ev.Type != client.EventRemoved {
+		t.Fatalf("third event = %s, want %s", ev.Type, client.EventRemoved)
+	}
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"task-organizer/models"
+)
+
+// Types of watch events.
+const (
+	EventAdded   = "added"   // A task appeared
+	EventUpdated = "updated" // A task was modified
+	EventRemoved = "removed" // A task disappeared
+	EventError   = "error"   // Polling failed; it is retried at the next interval
+)
+
+// DefaultWatchInterval is the polling interval of Watch when none is given.
+const DefaultWatchInterval = 2 * time.Second
+
+// Event is a change to the watched tasks.
+type Event struct {
+	Type string      `json:"type"` // added, updated, removed or error
+	Time time.Time   `json:"time"` // When the change was noticed
+	Task models.Task `json:"task"` // Task as it is now, or as last seen if removed
+	Err  error       `json:"-"`    // Why polling failed, for error events
+}
+
+// Watch reports changes to the tasks selected by opts until ctx is done,
+// when the returned channel is closed. The tasks are polled every interval;
+// the ones present at the start are reported as added.
+func (c *Client) Watch(ctx context.Context, opts ListOptions, interval time.Duration) (<-chan Event, error) {
+	if interval < 0 {
+		return nil, errors.New("watch interval must not be negative")
+	}
+	if interval == 0 {
+		interval = DefaultWatchInterval
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		seen := map[string]models.Task{}
+		for {
+			tasks, err := c.List(ctx, opts)
+			var changes []Event
+			switch {
+			case ctx.Err() != nil:
+				return
+			case err != nil:
+				changes = []Event{{Type: EventError, Time: time.Now(), Err: err}}
+			default:
+				changes = diffTasks(seen, tasks)
+			}
+			for _, e := range changes {
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return events, nil
+}
+
+// diffTasks returns the changes from the seen tasks to the current ones,
+// and records the current ones as seen.
+func diffTasks(seen map[string]models.Task, current []models.Task) []Event {
+	now := time.Now()
+	var events []Event
+	ids := map[string]bool{}
+	for _, t := range current {
+		ids[t.ID] = true
+		prev, ok := seen[t.ID]
+		switch {
+		case !ok:
+			events = append(events, Event{Type: EventAdded, Time: now, Task: t})
+		case !prev.UpdatedAt.Equal(t.UpdatedAt):
+			events = append(events, Event{Type: EventUpdated, Time: now, Task: t})
+		}
+		seen[t.ID] = t
+	}
+	for id, t := range seen {
+		if !ids[id] {
+			events = append(events, Event{Type: EventRemoved, Time: now, Task: t})
+			delete(seen, id)
+		}
+	}
+	return events
+}
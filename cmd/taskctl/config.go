@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the profiles of taskctl, stored as YAML.
+type Config struct {
+	Current  string             `yaml:"current,omitempty"`  // Profile used when none is given
+	Profiles map[string]Profile `yaml:"profiles,omitempty"` // Profiles by name
+}
+
+// Profile names a server and the credentials used with it.
+type Profile struct {
+	Server string `yaml:"server"`          // URL of the server
+	Token  string `yaml:"token,omitempty"` // Bearer token sent to the server
+	Actor  string `yaml:"actor,omitempty"` // Actor recorded in the audit log
+}
+
+// configPath returns the path of the config file, which TASKCTL_CONFIG overrides.
+func configPath() (string, error) {
+	if path := os.Getenv("TASKCTL_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "taskctl", "config.yaml"), nil
+}
+
+// loadConfig reads the config file; a missing file is an empty config.
+func loadConfig() (*Config, error) {
+	cfg := &Config{Profiles: map[string]Profile{}}
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]Profile{}
+	}
+	return cfg, nil
+}
+
+// saveConfig writes the config file, readable only by its owner since it holds tokens.
+func saveConfig(cfg *Config) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func configCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage server profiles",
+	}
+
+	var profile Profile
+	set := &cobra.Command{
+		Use:   "set NAME",
+		Short: "Create or change a profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			p := cfg.Profiles[args[0]]
+			if cmd.Flags().Changed("server") {
+				p.Server = profile.Server
+			}
+			if cmd.Flags().Changed("token") {
+				p.Token = profile.Token
+			}
+			if cmd.Flags().Changed("actor") {
+				p.Actor = profile.Actor
+			}
+			cfg.Profiles[args[0]] = p
+			if cfg.Current == "" {
+				cfg.Current = args[0]
+			}
+			return saveConfig(cfg)
+		},
+	}
+	// These shadow the global flags of the same names, which select a server rather than configure one
+	set.Flags().StringVar(&profile.Server, "server", "", "URL of the server")
+	set.Flags().StringVar(&profile.Token, "token", "", "bearer token sent to the server")
+	set.Flags().StringVar(&profile.Actor, "actor", "", "actor recorded in the audit log")
+
+	use := &cobra.Command{
+		Use:               "use NAME",
+		Short:             "Make a profile the default",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeProfiles,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if _, ok := cfg.Profiles[args[0]]; !ok {
+				return fmt.Errorf("unknown profile %q", args[0])
+			}
+			cfg.Current = args[0]
+			return saveConfig(cfg)
+		},
+	}
+
+	rm := &cobra.Command{
+		Use:               "rm NAME",
+		Short:             "Delete a profile",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeProfiles,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if _, ok := cfg.Profiles[args[0]]; !ok {
+				return fmt.Errorf("unknown profile %q", args[0])
+			}
+			delete(cfg.Profiles, args[0])
+			if cfg.Current == args[0] {
+				cfg.Current = ""
+			}
+			return saveConfig(cfg)
+		},
+	}
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the profiles",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if outputFlag != "table" {
+				return printValue(cmd.OutOrStdout(), cfg)
+			}
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "CURRENT\tNAME\tSERVER\tACTOR")
+			for _, name := range profileNames(cfg) {
+				current := ""
+				if name == cfg.Current {
+					current = "*"
+				}
+				p := cfg.Profiles[name]
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", current, name, p.Server, p.Actor)
+			}
+			return tw.Flush()
+		},
+	}
+
+	cmd.AddCommand(set, use, rm, list)
+	return cmd
+}
+
+// profileNames returns the names of the profiles in order.
+func profileNames(cfg *Config) []string {
+	var names []string
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// completeProfiles completes profile names.
+func completeProfiles(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return profileNames(cfg), cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,104 @@
+// Command taskctl manages tasks on a task organizer server from the command line.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"task-organizer/client"
+
+	"github.com/spf13/cobra"
+)
+
+// Default server used when neither a flag, the environment nor a profile names one.
+const defaultServer = "http://localhost:8080"
+
+// Global flags shared by all subcommands.
+var (
+	profileFlag string
+	serverFlag  string
+	tokenFlag   string
+	actorFlag   string
+	outputFlag  string
+)
+
+func main() {
+	if err := rootCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// rootCmd builds the command tree.
+func rootCmd() *cobra.Command {
+	root := &cobra.Command{
+		Use:          "taskctl",
+		Short:        "Manage tasks on a task organizer server",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			switch outputFlag {
+			case "table", "json", "yaml":
+				return nil
+			}
+			return fmt.Errorf("invalid output format %q, expected table, json or yaml", outputFlag)
+		},
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&profileFlag, "profile", "", "config profile to use (env TASKCTL_PROFILE)")
+	flags.StringVar(&serverFlag, "server", "", "URL of the server (env TASKCTL_SERVER)")
+	flags.StringVar(&tokenFlag, "token", "", "bearer token sent to the server (env TASKCTL_TOKEN)")
+	flags.StringVar(&actorFlag, "actor", "", "actor recorded in the audit log (env TASKCTL_ACTOR)")
+	flags.StringVarP(&outputFlag, "output", "o", "table", "output format: table, json or yaml")
+	root.RegisterFlagCompletionFunc("output", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return []string{"table", "json", "yaml"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	root.RegisterFlagCompletionFunc("profile", completeProfiles)
+
+	root.AddCommand(
+		listCmd(), getCmd(), addCmd(), doneCmd(), editCmd(), rmCmd(),
+		watchCmd(), exportCmd(), importCmd(), configCmd(), completionCmd(),
+	)
+	return root
+}
+
+// newClient returns a client for the server selected by the flags, the
+// environment and the config profile, in that order of precedence.
+func newClient() (*client.Client, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	name := firstNonEmpty(profileFlag, os.Getenv("TASKCTL_PROFILE"), cfg.Current)
+	var profile Profile
+	if name != "" {
+		var ok bool
+		if profile, ok = cfg.Profiles[name]; !ok {
+			return nil, fmt.Errorf("unknown profile %q", name)
+		}
+	}
+
+	c := client.New(
+		firstNonEmpty(serverFlag, os.Getenv("TASKCTL_SERVER"), profile.Server, defaultServer),
+		firstNonEmpty(tokenFlag, os.Getenv("TASKCTL_TOKEN"), profile.Token),
+	)
+	c.Actor = firstNonEmpty(actorFlag, os.Getenv("TASKCTL_ACTOR"), profile.Actor)
+	return c, nil
+}
+
+// firstNonEmpty returns the first of values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ctx returns the context for requests of a command.
+func ctx(cmd *cobra.Command) context.Context {
+	if c := cmd.Context(); c != nil {
+		return c
+	}
+	return context.Background()
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"task-organizer/models"
+	"task-organizer/routers"
+
+	"github.com/gin-gonic/gin"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// skipReason tells why the tests needing the server are skipped, empty if they run.
+var skipReason string
+
+func TestMain(m *testing.M) {
+	// The router needs etcd on both ports; without it the tests are skipped one by one
+	for _, addr := range []string{"localhost:2379", "localhost:2380"} {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err != nil {
+			skipReason = "etcd is not reachable at " + addr
+			os.Exit(m.Run())
+		}
+		conn.Close()
+	}
+
+	dir, err := os.MkdirTemp("", "taskctl")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("BLOB_DIR", filepath.Join(dir, "blobs"))
+	os.Setenv("GRPC_ADDR", "127.0.0.1:0")
+	os.Setenv("RATE_LIMIT_READ", "off")
+	os.Setenv("RATE_LIMIT_WRITE", "off")
+	os.Setenv("TASKCTL_CONFIG", filepath.Join(dir, "config.yaml"))
+	os.Unsetenv("TASKCTL_PROFILE")
+	os.Unsetenv("TASKCTL_TOKEN")
+	// Keep the keys of this run apart from those of other runs and deployments
+	os.Setenv("ETCD_NAMESPACE", fmt.Sprintf("taskctl-test-%d/", time.Now().UnixNano()))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routers.IdeaRouter(r)
+	server := httptest.NewServer(r)
+	os.Setenv("TASKCTL_SERVER", server.URL)
+
+	code := m.Run()
+	server.Close()
+	clearNamespace()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// clearNamespace deletes the keys written by the run.
+func clearNamespace() {
+	h1, h2, err := models.Init()
+	if err != nil {
+		fmt.Println("clearing the test keys:", err)
+		return
+	}
+	for _, h := range []*models.Handler{h1, h2} {
+		if _, err := h.Client.Delete(context.Background(), "", clientv3.WithPrefix()); err != nil {
+			fmt.Println("clearing the test keys:", err)
+		}
+		h.Client.Close()
+	}
+}
+
+// run runs taskctl with the given arguments and returns what it printed,
+// skipping the test if there is no server to run it against.
+func run(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	if skipReason != "" {
+		t.Skip(skipReason)
+	}
+	var out bytes.Buffer
+	cmd := rootCmd()
+	cmd.SetArgs(args)
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+// runTask runs a taskctl command printing a task as JSON and decodes it.
+func runTask(t *testing.T, args ...string) models.Task {
+	t.Helper()
+	out, err := run(t, append(args, "-o", "json")...)
+	if err != nil {
+		t.Fatalf("taskctl %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	var task models.Task
+	if err := json.Unmarshal([]byte(out), &task); err != nil {
+		t.Fatalf("taskctl %s printed %q: %v", strings.Join(args, " "), out, err)
+	}
+	return task
+}
+
+// runTasks runs a taskctl command printing tasks as JSON and decodes them.
+func runTasks(t *testing.T, args ...string) []models.Task {
+	t.Helper()
+	out, err := run(t, append(args, "-o", "json")...)
+	if err != nil {
+		t.Fatalf("taskctl %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	var tasks []models.Task
+	if err := json.Unmarshal([]byte(out), &tasks); err != nil {
+		t.Fatalf("taskctl %s printed %q: %v", strings.Join(args, " "), out, err)
+	}
+	return tasks
+}
+
+func TestTaskCommands(t *testing.T) {
+	tag := fmt.Sprintf("taskctl-test-%d", time.Now().UnixNano())
+
+	added := runTask(t, "add", "Write", "the", "tests", "--priority", "high", "--tag", tag, "--due", "2030-01-02")
+	t.Cleanup(func() { run(t, "rm", added.ID) })
+	if added.ID == "" || added.Title != "Write the tests" || added.Priority != "high" || added.Due == nil {
+		t.Fatalf("add printed %+v", added)
+	}
+
+	if got := runTask(t, "get", added.ID); got.ID != added.ID || got.Title != added.Title {
+		t.Errorf("get printed %+v, want %+v", got, added)
+	}
+
+	tasks := runTasks(t, "list", "--tag", tag)
+	if len(tasks) != 1 || tasks[0].ID != added.ID {
+		t.Errorf("list --tag %s printed %+v, want only %s", tag, tasks, added.ID)
+	}
+	out, err := run(t, "list", "--tag", tag)
+	if err != nil || !strings.Contains(out, "ID") || !strings.Contains(out, added.ID) {
+		t.Errorf("list as a table printed %q, %v", out, err)
+	}
+
+	edited := runTask(t, "edit", added.ID, "--title", "Rewrite the tests", "--due", "", "--assignee", "ada")
+	if edited.Title != "Rewrite the tests" || edited.Due != nil || edited.Assignee != "ada" || edited.Priority != "high" {
+		t.Errorf("edit printed %+v", edited)
+	}
+
+	if out, err := run(t, "done", added.ID); err != nil {
+		t.Fatalf("done: %v\n%s", err, out)
+	}
+	if tasks := runTasks(t, "list", "--tag", tag, "--completed"); len(tasks) != 1 || !tasks[0].Completed {
+		t.Errorf("list --completed printed %+v, want the completed task", tasks)
+	}
+	if out, err := run(t, "done", "--undo", added.ID); err != nil {
+		t.Fatalf("done --undo: %v\n%s", err, out)
+	}
+	if got := runTask(t, "get", added.ID); got.Completed {
+		t.Errorf("task still completed after done --undo")
+	}
+
+	if out, err := run(t, "rm", added.ID); err != nil {
+		t.Fatalf("rm: %v\n%s", err, out)
+	}
+	if _, err := run(t, "get", added.ID); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("get after rm: %v, want not found", err)
+	}
+}
+
+func TestExportImportCommands(t *testing.T) {
+	added := runTask(t, "add", fmt.Sprintf("Export me %d", time.Now().UnixNano()))
+	t.Cleanup(func() { run(t, "rm", added.ID) })
+
+	path := filepath.Join(t.TempDir(), "tasks.ndjson")
+	if out, err := run(t, "export", path); err != nil {
+		t.Fatalf("export: %v\n%s", err, out)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), added.ID) {
+		t.Fatalf("export does not contain task %s", added.ID)
+	}
+
+	// The exported tasks all exist, so a dry run skips every one
+	out, err := run(t, "import", path, "--dry-run", "-o", "json")
+	if err != nil {
+		t.Fatalf("import: %v\n%s", err, out)
+	}
+	var resp models.ImportResp
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		t.Fatalf("import printed %q: %v", out, err)
+	}
+	if !resp.DryRun || resp.Created != 0 || resp.Skipped == 0 {
+		t.Errorf("import printed %+v, want only skipped tasks", resp)
+	}
+}
+
+func TestInvalidOutput(t *testing.T) {
+	if _, err := run(t, "list", "-o", "xml"); err == nil {
+		t.Error("list -o xml succeeded")
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"task-organizer/models"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// printValue writes v in the JSON or YAML output format.
+// YAML is converted from the JSON form, so both use the same field names.
+func printValue(w io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if outputFlag == "json" {
+		_, err := fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(generic); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// printTasks writes tasks in the output format, as one row per task for tables.
+func printTasks(w io.Writer, tasks []models.Task) error {
+	if outputFlag != "table" {
+		return printValue(w, tasks)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE\tDONE\tPRIORITY\tDUE\tASSIGNEE\tTAGS")
+	for _, t := range tasks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			t.ID, t.Title, check(t.Completed), t.Priority, formatTime(t.Due), t.Assignee, strings.Join(t.Tags, ","))
+	}
+	return tw.Flush()
+}
+
+// printTask writes a task in the output format, as one row per field for tables.
+func printTask(w io.Writer, t *models.Task) error {
+	if outputFlag != "table" {
+		return printValue(w, t)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, field := range [][2]string{
+		{"ID", t.ID},
+		{"Title", t.Title},
+		{"Description", t.Description},
+		{"Done", check(t.Completed)},
+		{"Priority", t.Priority},
+		{"Tags", strings.Join(t.Tags, ", ")},
+		{"Due", formatTime(t.Due)},
+		{"Assignee", t.Assignee},
+		{"Recurrence", t.Recurrence},
+		{"Created", formatTime(&t.CreatedAt)},
+		{"Updated", formatTime(&t.UpdatedAt)},
+		{"Expires", formatTime(t.ExpiresAt)},
+	} {
+		if field[1] != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", field[0], field[1])
+		}
+	}
+	return tw.Flush()
+}
+
+// formatTime renders a time in local time for tables, or nothing if unset.
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// check renders a completion status for tables.
+func check(done bool) string {
+	if done {
+		return "x"
+	}
+	return ""
+}
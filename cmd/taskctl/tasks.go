@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"task-organizer/client"
+	"task-organizer/models"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// listFlags binds the flags selecting tasks to list options.
+func listFlags(flags *pflag.FlagSet, opts *client.ListOptions, completed *bool) {
+	flags.BoolVar(completed, "completed", false, "only tasks with this completion status")
+	flags.StringVar(&opts.Tag, "tag", "", "only tasks with this tag")
+	flags.StringVar(&opts.Assignee, "assignee", "", "only tasks assigned to this person")
+	flags.StringVar(&opts.Filter, "filter", "", "filter expression, such as 'priority >= high AND tag:backend'")
+	flags.StringVar(&opts.Saved, "saved", "", "name of a saved filter to apply")
+}
+
+func listCmd() *cobra.Command {
+	var opts client.ListOptions
+	var completed bool
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List tasks",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("completed") {
+				opts.Completed = &completed
+			}
+			tasks, err := c.List(ctx(cmd), opts)
+			if err != nil {
+				return err
+			}
+			return printTasks(cmd.OutOrStdout(), tasks)
+		},
+	}
+	listFlags(cmd.Flags(), &opts, &completed)
+	return cmd
+}
+
+func getCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "get ID",
+		Short:             "Show a task",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeTaskIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			task, _, err := c.Get(ctx(cmd), args[0])
+			if err != nil {
+				return err
+			}
+			return printTask(cmd.OutOrStdout(), task)
+		},
+	}
+}
+
+func addCmd() *cobra.Command {
+	var task models.Task
+	var due string
+	cmd := &cobra.Command{
+		Use:   "add TITLE...",
+		Short: "Create a task",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			task.Title = strings.Join(args, " ")
+			if due != "" {
+				t, err := parseDue(due)
+				if err != nil {
+					return err
+				}
+				task.Due = &t
+			}
+			created, err := c.Create(ctx(cmd), task)
+			if err != nil {
+				return err
+			}
+			return printTask(cmd.OutOrStdout(), created)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&task.Description, "description", "d", "", "longer description of the task")
+	flags.StringVarP(&task.Priority, "priority", "p", "", "priority: low, medium, high or urgent")
+	flags.StringSliceVarP(&task.Tags, "tag", "t", nil, "tag of the task, may be repeated")
+	flags.StringVar(&due, "due", "", "due date, as YYYY-MM-DD or RFC 3339")
+	flags.StringVarP(&task.Assignee, "assignee", "a", "", "who the task is assigned to")
+	flags.StringVar(&task.Recurrence, "recurrence", "", "recurrence rule, such as FREQ=WEEKLY")
+	flags.Int64Var(&task.TTL, "ttl", 0, "seconds until the task is removed automatically")
+	cmd.RegisterFlagCompletionFunc("priority", completePriorities)
+	return cmd
+}
+
+func doneCmd() *cobra.Command {
+	var undo bool
+	cmd := &cobra.Command{
+		Use:               "done ID...",
+		Short:             "Mark tasks as completed",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeTaskIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			completed := !undo
+			for _, id := range args {
+				if _, err := c.Patch(ctx(cmd), id, client.Patch{Completed: &completed}); err != nil {
+					return fmt.Errorf("%s: %w", id, err)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&undo, "undo", false, "mark the tasks as not completed instead")
+	return cmd
+}
+
+func editCmd() *cobra.Command {
+	var (
+		title, description, priority, due, assignee, recurrence string
+		tags                                                    []string
+		completed                                               bool
+		ttl                                                     int64
+	)
+	cmd := &cobra.Command{
+		Use:               "edit ID",
+		Short:             "Change fields of a task",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeTaskIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			// Only the fields given as flags change
+			changed := cmd.Flags().Changed
+			var req client.Patch
+			if changed("title") {
+				req.Title = &title
+			}
+			if changed("completed") {
+				req.Completed = &completed
+			}
+			if changed("description") {
+				req.Description = &description
+			}
+			if changed("priority") {
+				req.Priority = &priority
+			}
+			if changed("tag") {
+				req.Tags = &tags
+			}
+			switch {
+			case changed("due") && due == "":
+				req.ClearDue = true
+			case changed("due"):
+				t, err := parseDue(due)
+				if err != nil {
+					return err
+				}
+				req.Due = &t
+			}
+			if changed("assignee") {
+				req.Assignee = &assignee
+			}
+			if changed("recurrence") {
+				req.Recurrence = &recurrence
+			}
+			if changed("ttl") {
+				req.TTL = &ttl
+			}
+
+			if _, err := c.Patch(ctx(cmd), args[0], req); err != nil {
+				return err
+			}
+			updated, _, err := c.Get(ctx(cmd), args[0])
+			if err != nil {
+				return err
+			}
+			return printTask(cmd.OutOrStdout(), updated)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&title, "title", "", "new title")
+	flags.StringVarP(&description, "description", "d", "", "new description")
+	flags.BoolVar(&completed, "completed", false, "new completion status")
+	flags.StringVarP(&priority, "priority", "p", "", "new priority: low, medium, high or urgent")
+	flags.StringSliceVarP(&tags, "tag", "t", nil, "new tags, replacing the current ones; may be repeated")
+	flags.StringVar(&due, "due", "", "new due date, as YYYY-MM-DD or RFC 3339; empty removes it")
+	flags.StringVarP(&assignee, "assignee", "a", "", "new assignee; empty removes it")
+	flags.StringVar(&recurrence, "recurrence", "", "new recurrence rule, such as FREQ=WEEKLY")
+	flags.Int64Var(&ttl, "ttl", 0, "new lifetime in seconds; 0 removes the expiry")
+	cmd.RegisterFlagCompletionFunc("priority", completePriorities)
+	return cmd
+}
+
+func rmCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "rm ID...",
+		Short:             "Move tasks to the trash",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeTaskIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			for _, id := range args {
+				if err := c.Delete(ctx(cmd), id, ""); err != nil {
+					return fmt.Errorf("%s: %w", id, err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// parseDue parses a due date given as a day or as an RFC 3339 time.
+func parseDue(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return t, fmt.Errorf("invalid due date %q, expected YYYY-MM-DD or RFC 3339", s)
+	}
+	return t, nil
+}
+
+// completeTaskIDs completes task IDs, described by their titles.
+func completeTaskIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	c, err := newClient()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	tasks, err := c.List(ctx(cmd), client.ListOptions{})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var ids []string
+	for _, t := range tasks {
+		if strings.HasPrefix(t.ID, toComplete) {
+			ids = append(ids, t.ID+"\t"+t.Title)
+		}
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePriorities completes task priorities.
+func completePriorities(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return []string{"low", "medium", "high", "urgent"}, cobra.ShellCompDirectiveNoFileComp
+}
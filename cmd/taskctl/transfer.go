@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"task-organizer/client"
+	"task-organizer/transfer"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func exportCmd() *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "export [FILE]",
+		Short: "Export all tasks to a file or stdout",
+		Long: `Export all tasks to a file, or to stdout if none is given.
+
+The format is taken from the file extension unless --format is given,
+and defaults to json.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := ""
+			if len(args) > 0 && args[0] != "-" {
+				path = args[0]
+			}
+			format, err := transferFormat(format, path)
+			if err != nil {
+				return err
+			}
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			body, err := c.Export(ctx(cmd), format)
+			if err != nil {
+				return err
+			}
+			defer body.Close()
+
+			var w io.Writer = cmd.OutOrStdout()
+			if path != "" {
+				f, err := os.Create(path)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			_, err = io.Copy(w, body)
+			return err
+		},
+	}
+	cmd.Flags().StringVarP(&format, "format", "f", "", "format: "+strings.Join(transfer.Names(), ", "))
+	cmd.RegisterFlagCompletionFunc("format", completeFormats)
+	return cmd
+}
+
+func importCmd() *cobra.Command {
+	var format string
+	var opts client.ImportOptions
+	cmd := &cobra.Command{
+		Use:   "import FILE",
+		Short: "Import tasks from a file or stdin",
+		Long: `Import tasks from a file, or from stdin if FILE is -.
+
+The format is taken from the file extension unless --format is given,
+and defaults to json.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			if path == "-" {
+				path = ""
+			}
+			format, err := transferFormat(format, path)
+			if err != nil {
+				return err
+			}
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			var r io.Reader = cmd.InOrStdin()
+			if path != "" {
+				f, err := os.Open(path)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				r = f
+			}
+			resp, err := c.Import(ctx(cmd), format, r, opts)
+			if err != nil {
+				return err
+			}
+
+			if outputFlag != "table" {
+				return printValue(cmd.OutOrStdout(), resp)
+			}
+			out := cmd.OutOrStdout()
+			if resp.DryRun {
+				fmt.Fprint(out, "Dry run: ")
+			}
+			fmt.Fprintf(out, "%d created, %d overwritten, %d skipped, %d failed\n",
+				resp.Created, resp.Overwritten, resp.Skipped, resp.Failed)
+			if resp.Failed == 0 {
+				return nil
+			}
+			tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "ROW\tID\tERROR")
+			for _, r := range resp.Results {
+				if r.Error != "" {
+					fmt.Fprintf(tw, "%d\t%s\t%s\n", r.Row, r.ID, r.Error)
+				}
+			}
+			return tw.Flush()
+		},
+	}
+	cmd.Flags().StringVarP(&format, "format", "f", "", "format: "+strings.Join(transfer.Names(), ", "))
+	cmd.Flags().StringVar(&opts.Conflict, "conflict", "", "strategy for taken IDs: skip, overwrite or regenerate")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "only validate the import")
+	cmd.RegisterFlagCompletionFunc("format", completeFormats)
+	cmd.RegisterFlagCompletionFunc("conflict", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return []string{"skip", "overwrite", "regenerate"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	return cmd
+}
+
+// transferFormat returns the named format, or the one matching the extension
+// of path, or json.
+func transferFormat(name, path string) (string, error) {
+	if name != "" {
+		if _, ok := transfer.Lookup(name); !ok {
+			return "", fmt.Errorf("unknown format %q, expected one of %s", name, strings.Join(transfer.Names(), ", "))
+		}
+		return name, nil
+	}
+	if ext := strings.TrimPrefix(filepath.Ext(path), "."); ext != "" {
+		for _, name := range transfer.Names() {
+			if f, _ := transfer.Lookup(name); f.Extension == ext {
+				return name, nil
+			}
+		}
+		return "", fmt.Errorf("cannot tell the format of %s, give it with --format", path)
+	}
+	return "json", nil
+}
+
+// completeFormats completes transfer format names.
+func completeFormats(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return transfer.Names(), cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"task-organizer/client"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func watchCmd() *cobra.Command {
+	var opts client.ListOptions
+	var completed bool
+	var interval time.Duration
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Print changes to tasks as they happen",
+		Long: `Print changes to tasks as they happen, until interrupted.
+
+The tasks are polled at the given interval. Tasks present at the start are
+reported as added. Failed polls are reported and retried.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("completed") {
+				opts.Completed = &completed
+			}
+
+			ctx, stop := signal.NotifyContext(ctx(cmd), os.Interrupt)
+			defer stop()
+
+			events, err := c.Watch(ctx, opts, interval)
+			if err != nil {
+				return err
+			}
+			for e := range events {
+				if e.Type == client.EventError {
+					fmt.Fprintln(cmd.ErrOrStderr(), "watch:", e.Err)
+					continue
+				}
+				if err := printEvent(cmd.OutOrStdout(), e); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	listFlags(cmd.Flags(), &opts, &completed)
+	cmd.Flags().DurationVar(&interval, "interval", client.DefaultWatchInterval, "time between polls")
+	return cmd
+}
+
+// printEvent writes an event in the output format: a line for tables and
+// JSON, and a document for YAML.
+func printEvent(w io.Writer, e client.Event) error {
+	switch outputFlag {
+	case "json":
+		return json.NewEncoder(w).Encode(e)
+	case "yaml":
+		if _, err := io.WriteString(w, "---\n"); err != nil {
+			return err
+		}
+		return printValue(w, e)
+	}
+	_, err := fmt.Fprintf(w, "%s  %-7s  %s  %s\n", e.Time.Format("15:04:05"), e.Type, e.Task.ID, e.Task.Title)
+	return err
+}
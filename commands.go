@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"task-organizer/backup"
+	"task-organizer/models"
+	"time"
+)
+
+// usage describes the subcommands of the binary.
+const usage = `usage:
+  app                                    start the HTTP server
+  app backup [-o file]                   write a backup archive (default: stdout)
+  app restore [-force] [-dry-run] file   restore a backup archive ("-" reads stdin)`
+
+// runCommand runs a subcommand of the binary with its arguments.
+func runCommand(name string, args []string) error {
+	switch name {
+	case "backup":
+		return backupCommand(args)
+	case "restore":
+		return restoreCommand(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Println(usage)
+		return nil
+	default:
+		return errors.New("unknown command " + name + "\n" + usage)
+	}
+}
+
+// backupCommand writes a backup archive of the task keyspace to a file or stdout.
+func backupCommand(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	output := fs.String("o", "-", "archive to write, - for stdout")
+	fs.Parse(args)
+
+	handler, other, err := models.Init()
+	if err != nil {
+		return err
+	}
+	defer handler.Client.Close()
+	defer other.Client.Close()
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	manifest, err := backup.Write(ctx, handler, w)
+	if err != nil {
+		if *output != "-" {
+			os.Remove(*output)
+		}
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Backed up %d keys at revision %d (sha256 %s)\n", manifest.Keys, manifest.Revision, manifest.Checksum)
+	return nil
+}
+
+// restoreCommand validates a backup archive and loads it into etcd.
+func restoreCommand(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	force := fs.Bool("force", false, "replace the tasks currently stored")
+	dryRun := fs.Bool("dry-run", false, "only validate the archive")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("restore takes the archive to restore\n" + usage)
+	}
+
+	var r io.Reader = os.Stdin
+	if path := fs.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	manifest, entries, err := backup.Read(r)
+	if err != nil {
+		return err
+	}
+	result := &backup.RestoreResult{Manifest: manifest, DryRun: true}
+
+	if !*dryRun {
+		handler, other, err := models.Init()
+		if err != nil {
+			return err
+		}
+		defer handler.Client.Close()
+		defer other.Client.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+
+		if result, err = backup.Restore(ctx, handler, manifest, entries, *force); err != nil {
+			return err
+		}
+	}
+
+	out, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
@@ -16,9 +16,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/tasks": {
+        "/admin/audit": {
             "get": {
-                "description": "Returns the data of all the tasks",
+                "description": "Returns audit entries of task mutations, oldest first, optionally filtered by task, actor and time range.\nEntries are read a page of limit entries at a time; pass the returned next key as after to get the following ones.\nEntries filtered out by task or actor count towards the limit, so a page may hold fewer entries while next is set.\nThe actor of an entry is taken from the X-Actor header of the request that made the mutation, which is not verified.",
                 "consumes": [
                     "application/json"
                 ],
@@ -26,66 +26,188 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Tasks"
+                    "Admin"
+                ],
+                "summary": "Get the audit log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only entries for this task ID",
+                        "name": "task",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only entries made by this actor",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only entries at or after this time (RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only entries before this time (RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of entries to read, 100 by default and at most 1000",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Next key returned by the previous page",
+                        "name": "after",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get a list of all tasks",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Task"
-                            }
+                            "$ref": "#/definitions/models.AuditLog"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/admin/backup": {
+            "get": {
+                "description": "Takes a consistent snapshot of the tasks, trash, audit log and saved filters at a single etcd revision\nand returns it as a versioned, gzip-compressed tar archive with a SHA-256 checksum.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Back up the task keyspace",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
                 }
-            },
+            }
+        },
+        "/admin/restore": {
             "post": {
-                "description": "Creates a new task with a unique ID if not provided",
+                "description": "Validates a backup archive and loads it in transactions, rolling back if loading fails.\nThe keyspace must be empty unless force is set, in which case it is replaced. Secondary indexes are rebuilt.\nA restore is not transactional as a whole: clients see the keyspace emptied and partly loaded while it runs,\nand errors rolling back a failed restore are reported along with the cause.",
                 "consumes": [
-                    "application/json"
+                    "application/octet-stream"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Tasks"
+                    "Admin"
                 ],
-                "summary": "Create a new task",
+                "summary": "Restore the task keyspace from a backup",
                 "parameters": [
                     {
-                        "description": "Task object to be created",
-                        "name": "task",
+                        "description": "Backup archive",
+                        "name": "archive",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Task"
+                            "type": "string"
                         }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Replace the tasks currently stored",
+                        "name": "force",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only validate the archive",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Task"
+                            "$ref": "#/definitions/backup.RestoreResult"
                         }
                     },
                     "400": {
                         "description": "Bad Request"
                     },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
                 }
             }
         },
-        "/tasks/": {
-            "delete": {
-                "description": "Deletes All Data",
+        "/admin/search/rebuild": {
+            "post": {
+                "description": "Reloads the full-text search index from the tasks stored in etcd",
                 "consumes": [
                     "application/json"
                 ],
@@ -93,15 +215,27 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Tasks"
+                    "Admin"
+                ],
+                "summary": "Rebuild the search index",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
                 ],
-                "summary": "Deletes All Tasks",
                 "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/models.Task"
-                        }
+                    "200": {
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
                     },
                     "500": {
                         "description": "Internal Server Error"
@@ -109,25 +243,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/tasks/{id}": {
-            "get": {
-                "description": "Retrieves a task with the specified ID",
-                "consumes": [
-                    "application/json"
+        "/caldav/tasks/": {
+            "options": {
+                "description": "Advertises the WebDAV and CalDAV features and the methods supported on the CalDAV resources",
+                "tags": [
+                    "CalDAV"
                 ],
+                "summary": "Describe the CalDAV capabilities",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/caldav/tasks/{file}": {
+            "get": {
+                "description": "Retrieves the task with the specified ID as an iCalendar object holding a single VTODO, with its ETag",
                 "produces": [
-                    "application/json"
+                    "text/plain"
                 ],
                 "tags": [
-                    "Tasks"
+                    "CalDAV"
                 ],
-                "summary": "Get a task by ID",
+                "summary": "Get a task as an iCalendar object",
                 "parameters": [
                     {
                         "type": "string",
-                        "format": "int64",
-                        "description": "Task ID",
-                        "name": "id",
+                        "description": "Task ID followed by .ics",
+                        "name": "file",
                         "in": "path",
                         "required": true
                     }
@@ -136,7 +280,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Task"
+                            "type": "string"
                         }
                     },
                     "404": {
@@ -148,45 +292,171 @@ const docTemplate = `{
                 }
             },
             "put": {
-                "description": "Updates a task with the specified ID",
+                "description": "Stores the single VTODO of an iCalendar object as the task with the specified ID. Fields that iCalendar\ndoes not carry, such as the assignee, are kept when a task is replaced. If-Match and If-None-Match are honored.",
                 "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
+                    "text/plain"
                 ],
                 "tags": [
-                    "Tasks"
+                    "CalDAV"
                 ],
-                "summary": "Update a task by ID",
+                "summary": "Create or replace a task from an iCalendar object",
                 "parameters": [
                     {
                         "type": "string",
-                        "format": "int64",
-                        "description": "Task ID",
-                        "name": "id",
+                        "description": "Task ID followed by .ics",
+                        "name": "file",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Task object with fields to be updated",
+                        "description": "iCalendar object with a single VTODO",
                         "name": "task",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UpdateReq"
+                            "type": "string"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only replace the task if its ETag matches",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Use * to only create the task if it does not exist",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Actor recorded in the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "412": {
+                        "description": "Precondition Failed"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Moves the task with the specified ID to the trash. If-Match is honored.",
+                "tags": [
+                    "CalDAV"
+                ],
+                "summary": "Delete a task through CalDAV",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID followed by .ics",
+                        "name": "file",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only delete the task if its ETag matches",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Actor recorded in the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "412": {
+                        "description": "Precondition Failed"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
                     }
+                }
+            }
+        },
+        "/filters": {
+            "get": {
+                "description": "Returns all saved filter expressions",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Filters"
                 ],
+                "summary": "Get a list of saved filters",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.UpdateReq"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SavedFilter"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request"
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/filters/{name}": {
+            "get": {
+                "description": "Retrieves the saved filter with the specified name",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Filters"
+                ],
+                "summary": "Get a saved filter by name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SavedFilter"
+                        }
                     },
                     "404": {
                         "description": "Not Found"
@@ -196,8 +466,8 @@ const docTemplate = `{
                     }
                 }
             },
-            "delete": {
-                "description": "Deletes a task with the specified ID",
+            "put": {
+                "description": "Creates or replaces the filter expression stored under the specified name",
                 "consumes": [
                     "application/json"
                 ],
@@ -205,67 +475,3100 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Tasks"
+                    "Filters"
                 ],
-                "summary": "Delete a task by ID",
+                "summary": "Save a named filter",
                 "parameters": [
                     {
                         "type": "string",
-                        "format": "int64",
-                        "description": "Task ID",
-                        "name": "id",
+                        "description": "Filter name",
+                        "name": "name",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Filter expression",
+                        "name": "filter",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.FilterReq"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Task"
+                            "$ref": "#/definitions/models.SavedFilter"
                         }
                     },
                     "400": {
                         "description": "Bad Request"
                     },
-                    "404": {
-                        "description": "Not Found"
+                    "413": {
+                        "description": "Request Entity Too Large"
                     },
                     "500": {
                         "description": "Internal Server Error"
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "models.Task": {
-            "type": "object",
-            "properties": {
-                "completed": {
-                    "description": "Completion status of the task",
-                    "type": "boolean"
-                },
+            },
+            "delete": {
+                "description": "Deletes the saved filter with the specified name",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Filters"
+                ],
+                "summary": "Delete a saved filter by name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/graphql": {
+            "post": {
+                "description": "Executes a GraphQL query or mutation over tasks, given as JSON with query, operationName and variables. GET requests take them as query parameters and may only run queries. Subscriptions are served over a WebSocket speaking the graphql-transport-ws protocol on the same path. Queries exceeding the depth or complexity limits are rejected.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GraphQL"
+                ],
+                "summary": "Run a GraphQL operation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who performs the mutations, recorded in the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "405": {
+                        "description": "Method Not Allowed"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/labels": {
+            "get": {
+                "description": "Returns all labels sorted by name, each with the number of tasks having it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Labels"
+                ],
+                "summary": "Get a list of labels",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.LabelInfo"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a label tasks can reference by its ID. Names are unique regardless of case",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Labels"
+                ],
+                "summary": "Create a label",
+                "parameters": [
+                    {
+                        "description": "Label to create",
+                        "name": "label",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LabelReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Label"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/labels/{id}": {
+            "get": {
+                "description": "Returns the label with the specified ID and the number of tasks having it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Labels"
+                ],
+                "summary": "Get a label by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Label ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LabelInfo"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "put": {
+                "description": "Renames the label with the specified ID and changes its color and description. Tasks reference labels by ID, so they all show the new name at once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Labels"
+                ],
+                "summary": "Update a label",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Label ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New name, color and description",
+                        "name": "label",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LabelReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Label"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes the label with the specified ID and removes it from all tasks having it, recording the changes in the audit log\nThe change is applied atomically in a single transaction. A label on more tasks than the etcd operation limit\nallows to relabel at once is refused with 400; remove it from some of the tasks first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Labels"
+                ],
+                "summary": "Delete a label",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Label ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Actor recorded in the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/labels/{id}/merge": {
+            "post": {
+                "description": "Gives the label with the specified ID to all tasks having any of the merged labels, then deletes them, recording the changes in the audit log\nThe change is applied atomically in a single transaction. A label on more tasks than the etcd operation limit\nallows to relabel at once is refused with 400; remove it from some of the tasks first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Labels"
+                ],
+                "summary": "Merge labels into a label",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the label to merge into",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Labels to merge",
+                        "name": "merge",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MergeReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Actor recorded in the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MergeResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/tasks": {
+            "get": {
+                "description": "Returns the data of all the tasks, optionally filtered through the secondary indexes, in their manual order unless sorted by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Get a list of all tasks",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Only tasks with this completion status",
+                        "name": "completed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only tasks with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only tasks assigned to this person",
+                        "name": "assignee",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only tasks in this workflow state",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only tasks with the label of this ID",
+                        "name": "label",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only tasks due at or after this time (RFC 3339)",
+                        "name": "due_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only tasks due before this time (RFC 3339)",
+                        "name": "due_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter expression, e.g. completed = false AND priority \u003e= high AND tag:backend",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of a saved filter to apply",
+                        "name": "saved",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "position",
+                        "description": "Order of the tasks: position, the manual order, or id",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Task"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a new task with a unique ID if not provided. A ttl (seconds) or expires_at makes the task expire automatically\nUnknown fields, duplicate keys and text fields over their length limits are rejected, with the invalid fields listed in the error\nRetries sent by the same client, told apart by API key or else IP address, with the same Idempotency-Key and body get the stored response of the first request; reusing the key with a different body fails with 422",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Create a new task",
+                "parameters": [
+                    {
+                        "description": "Task object to be created",
+                        "name": "task",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Actor recorded in the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key making retries of the request return the first response instead of creating another task",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/tasks.ics": {
+            "get": {
+                "description": "Streams all tasks as the VTODO components of an RFC 5545 iCalendar file, for subscription by calendar clients",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Export all tasks as a calendar",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/tasks/": {
+            "delete": {
+                "description": "Moves all tasks to the trash with their comments and attachments",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Deletes All Tasks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Actor recorded in the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/tasks/export": {
+            "get": {
+                "description": "Streams all tasks as a JSON array, newline-delimited JSON, CSV, a Markdown checklist, an iCalendar file or todo.txt",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Export all tasks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "json",
+                        "description": "Export format (json, ndjson, csv, md, ics, todotxt)",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Task"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/tasks/import": {
+            "post": {
+                "description": "Imports tasks from a JSON array, newline-delimited JSON, CSV, a Markdown checklist, an iCalendar file or todo.txt and reports the outcome of each row.\nRows without an ID get a new one. The conflict strategy decides what happens to rows whose ID is taken:\nskip them, overwrite the stored task or import them under a new ID. With dry_run set nothing is stored.\nMarkdown and iCalendar files leave out fields of the tasks, so they cannot overwrite stored tasks.",
+                "consumes": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Import tasks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "json",
+                        "description": "Import format (json, ndjson, csv, md, ics, todotxt)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "skip",
+                        "description": "Strategy for taken IDs (skip, overwrite, regenerate)",
+                        "name": "conflict",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only validate the import",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Tasks to import",
+                        "name": "tasks",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Actor recorded in the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/tasks/search": {
+            "get": {
+                "description": "Finds tasks whose title or description contain every word of the query, matching words by prefix, ranked by relevance",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Search tasks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Words to search for",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of results (default 20)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/search.Result"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/tasks/todo.txt": {
+            "get": {
+                "description": "Streams all tasks as todo.txt lines with priority, completion marker, dates, +project and @context tags.\nFields todo.txt has no syntax for are written as key:value extensions, so the file can be imported back without loss.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Export all tasks as todo.txt",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "post": {
+                "description": "Imports one task per todo.txt line and reports the outcome of each line, like POST /tasks/import with format todotxt.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Import tasks from todo.txt",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "skip",
+                        "description": "Strategy for taken IDs (skip, overwrite, regenerate)",
+                        "name": "conflict",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only validate the import",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "description": "todo.txt lines",
+                        "name": "tasks",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Actor recorded in the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/tasks/{id}": {
+            "get": {
+                "description": "Retrieves a task with the specified ID, with its ETag unless a revision is given",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Get a task by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "int64",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Return the task as of this etcd revision",
+                        "name": "revision",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Respond with 304 if the ETag of the task matches",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "410": {
+                        "description": "Gone"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "put": {
+                "description": "Updates a task with the specified ID. Expiring tasks have their lease renewed unless a new ttl or expires_at is given\nWith upsert set, a task that does not exist is created under the ID chosen by the client; If-None-Match: * makes the request create-only\nUnknown fields, duplicate keys and text fields over their length limits are rejected, with the invalid fields listed in the error",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Update a task by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "int64",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Task object with fields to be updated",
+                        "name": "task",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateReq"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Create the task under the ID if it does not exist",
+                        "name": "upsert",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Actor recorded in the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only update the task if its ETag matches",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateReq"
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "412": {
+                        "description": "Precondition Failed"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Moves the task with the specified ID to the trash, from where it can be restored until the retention period expires.\nIts comments and attachments stay with it in the trash",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Delete a task by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "int64",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Actor recorded in the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only delete the task if its ETag matches",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "412": {
+                        "description": "Precondition Failed"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/attachments": {
+            "get": {
+                "description": "Returns the metadata of the files attached to the task with the specified ID, oldest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Attachments"
+                ],
+                "summary": "Get the attachments of a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Attachment"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "post": {
+                "description": "Uploads a file as the \"file\" field of a multipart form and attaches it to the task with the specified ID.\nFiles are limited in size and media type; identical contents are stored once",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Attachments"
+                ],
+                "summary": "Attach a file to a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "File to attach",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Uploader of the file",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Attachment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/attachments/{attachmentId}": {
+            "get": {
+                "description": "Returns the content of a file attached to a task, with its media type and file name",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "Attachments"
+                ],
+                "summary": "Download an attachment of a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Attachment ID",
+                        "name": "attachmentId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Answer 304 if the ETag of the content matches",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes a file attached to a task. Its content is deleted too unless another attachment has the same",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Attachments"
+                ],
+                "summary": "Delete an attachment of a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Attachment ID",
+                        "name": "attachmentId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/comments": {
+            "get": {
+                "description": "Returns the comments posted on the task with the specified ID, oldest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Comments"
+                ],
+                "summary": "Get the comments of a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Comment"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "post": {
+                "description": "Posts a comment on the task with the specified ID. The author is taken from the X-Actor header and @name mentions are collected from the body",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Comments"
+                ],
+                "summary": "Comment on a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Comment to post",
+                        "name": "comment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CommentReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Author of the comment",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Comment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/comments/{commentId}": {
+            "get": {
+                "description": "Retrieves a comment posted on a task, with its edit history",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Comments"
+                ],
+                "summary": "Get a comment of a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comment ID",
+                        "name": "commentId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Comment"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "put": {
+                "description": "Replaces the body of a comment, keeping the previous one in its edit history",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Comments"
+                ],
+                "summary": "Edit a comment of a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comment ID",
+                        "name": "commentId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New body of the comment",
+                        "name": "comment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CommentReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Editor recorded in the edit history",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Comment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes a comment posted on a task",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Comments"
+                ],
+                "summary": "Delete a comment of a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comment ID",
+                        "name": "commentId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/history": {
+            "get": {
+                "description": "Lists the prior versions of a task kept by etcd, newest first, with a diff against the preceding version.\nVersions are listed a page at a time; pass the returned next revision as before to get older ones",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Get the history of a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of versions to return, 20 by default and at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only list versions written before this revision",
+                        "name": "before",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TaskHistory"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/move": {
+            "post": {
+                "description": "Places a task right after the task given as after, right before the task given as before, or between both.\nOnly the moved task is rewritten; tasks are listed in this order by default",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Move a task in the list",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tasks to place the task next to",
+                        "name": "move",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MoveReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Actor recorded in the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only move the task if its ETag matches",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "412": {
+                        "description": "Precondition Failed"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/revert": {
+            "post": {
+                "description": "Restores the task to the version stored at the given etcd revision",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Revert a task to a previous version",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Revision to restore",
+                        "name": "revert",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RevertReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Actor recorded in the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "410": {
+                        "description": "Gone"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/tasks:batch": {
+            "post": {
+                "description": "Applies up to 100 mixed create/update/delete operations.\nWith atomic set, they are applied in a single etcd transaction, either all or none, and batches not fitting in one are rejected with 400;\notherwise they are split in transactions respecting the transaction size limit and each operation succeeds or fails on its own.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Create, update and delete tasks in bulk",
+                "parameters": [
+                    {
+                        "description": "Operations to apply",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Actor recorded in the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchResp"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/trash": {
+            "get": {
+                "description": "Returns the tasks in the trash together with their deletion and purge times",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Trash"
+                ],
+                "summary": "Get a list of deleted tasks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.TrashedTask"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Permanently deletes every task in the trash with its comments and attachments",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Trash"
+                ],
+                "summary": "Empty the trash",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Actor recorded in the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/trash/{id}": {
+            "delete": {
+                "description": "Removes a deleted task, its comments and its attachments for good; it can no longer be restored",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Trash"
+                ],
+                "summary": "Permanently delete a task from the trash",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Actor recorded in the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/trash/{id}/restore": {
+            "post": {
+                "description": "Moves a task from the trash back to the task list together with its comments and attachments",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Trash"
+                ],
+                "summary": "Restore a deleted task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Actor recorded in the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/workflows": {
+            "get": {
+                "description": "Returns all workflows, including the built-in default one unless it was replaced",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Workflows"
+                ],
+                "summary": "Get a list of workflows",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Workflow"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/workflows/{name}": {
+            "get": {
+                "description": "Retrieves the workflow with the specified name, with the number of tasks in each of its states",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Workflows"
+                ],
+                "summary": "Get a workflow by name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workflow name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WorkflowInfo"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "put": {
+                "description": "Creates or replaces the workflow stored under the specified name. States still holding tasks, counting the ones in the trash, cannot be removed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Workflows"
+                ],
+                "summary": "Save a workflow",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workflow name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "States and transitions",
+                        "name": "workflow",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.WorkflowReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Workflow"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes the workflow with the specified name if no task follows it, counting the ones in the trash. Deleting the default workflow brings back the built-in one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Workflows"
+                ],
+                "summary": "Delete a workflow by name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workflow name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "backup.Manifest": {
+            "type": "object",
+            "properties": {
+                "checksum": {
+                    "description": "Hex SHA-256 of keys.ndjson",
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "When the snapshot was taken",
+                    "type": "string"
+                },
+                "keys": {
+                    "description": "Number of keys in the snapshot",
+                    "type": "integer"
+                },
+                "prefixes": {
+                    "description": "Key prefixes included in the snapshot",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "revision": {
+                    "description": "etcd revision the snapshot was taken at",
+                    "type": "integer"
+                },
+                "version": {
+                    "description": "Archive format version",
+                    "type": "integer"
+                }
+            }
+        },
+        "backup.RestoreResult": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "description": "Whether the archive was only validated",
+                    "type": "boolean"
+                },
+                "expired": {
+                    "description": "Number of keys left out because they expired since the backup",
+                    "type": "integer"
+                },
+                "manifest": {
+                    "description": "Manifest of the restored archive",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/backup.Manifest"
+                        }
+                    ]
+                },
+                "replaced": {
+                    "description": "Number of keys deleted before loading, when forced",
+                    "type": "integer"
+                },
+                "restored": {
+                    "description": "Number of keys loaded",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Attachment": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "description": "Media type of the content",
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "When the file was attached",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID of the attachment, ordered by upload time",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "File name",
+                    "type": "string"
+                },
+                "sha256": {
+                    "description": "Hex SHA-256 of the content, its key in the blob store",
+                    "type": "string"
+                },
+                "size": {
+                    "description": "Size of the content in bytes",
+                    "type": "integer"
+                },
+                "task_id": {
+                    "description": "ID of the task the file is attached to",
+                    "type": "string"
+                },
+                "uploaded_by": {
+                    "description": "Who attached the file",
+                    "type": "string"
+                }
+            }
+        },
+        "models.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "Kind of mutation (create, update, delete, revert, restore, purge)",
+                    "type": "string"
+                },
+                "actor": {
+                    "description": "Who performed the mutation, as claimed by the unverified X-Actor header",
+                    "type": "string"
+                },
+                "after": {
+                    "description": "Task after the mutation (nil on delete)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    ]
+                },
+                "before": {
+                    "description": "Task before the mutation (nil on create)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    ]
+                },
+                "changes": {
+                    "description": "Field level diff between Before and After",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Change"
+                    }
+                },
+                "id": {
+                    "description": "ID of the audit entry",
+                    "type": "string"
+                },
+                "request_id": {
+                    "description": "ID of the HTTP request that caused the mutation",
+                    "type": "string"
+                },
+                "task_id": {
+                    "description": "ID of the mutated task",
+                    "type": "string"
+                },
+                "timestamp": {
+                    "description": "When the mutation happened",
+                    "type": "string"
+                }
+            }
+        },
+        "models.AuditLog": {
+            "type": "object",
+            "properties": {
+                "entries": {
+                    "description": "Entries of the page matching the filters",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AuditEntry"
+                    }
+                },
+                "next": {
+                    "description": "Key to pass as after to read the next page; empty at the end of the log",
+                    "type": "string"
+                }
+            }
+        },
+        "models.BatchOp": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "ID of the task to update or delete",
+                    "type": "string"
+                },
+                "op": {
+                    "description": "Kind of operation (create, update, delete)",
+                    "type": "string"
+                },
+                "task": {
+                    "description": "Task to create",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    ]
+                },
+                "update": {
+                    "description": "Fields of the task to update",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.UpdateReq"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.BatchReq": {
+            "type": "object",
+            "properties": {
+                "atomic": {
+                    "description": "Apply either all operations or none of them",
+                    "type": "boolean"
+                },
+                "operations": {
+                    "description": "Operations to apply, in order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BatchOp"
+                    }
+                }
+            }
+        },
+        "models.BatchResp": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "description": "Number of operations not applied",
+                    "type": "integer"
+                },
+                "results": {
+                    "description": "Outcome of each operation, in request order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BatchResult"
+                    }
+                },
+                "succeeded": {
+                    "description": "Number of applied operations",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.BatchResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Why the operation failed",
+                    "type": "string"
+                },
+                "fields": {
+                    "description": "Invalid fields of the operation",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldError"
+                    }
+                },
+                "id": {
+                    "description": "ID of the affected task",
+                    "type": "string"
+                },
+                "index": {
+                    "description": "Position of the operation in the request",
+                    "type": "integer"
+                },
+                "op": {
+                    "description": "Kind of operation",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "HTTP status of the operation",
+                    "type": "integer"
+                },
+                "task": {
+                    "description": "Resulting task for creates and updates",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.Change": {
+            "type": "object",
+            "properties": {
+                "after": {
+                    "description": "Value after the mutation"
+                },
+                "before": {
+                    "description": "Value before the mutation"
+                },
+                "field": {
+                    "description": "Name of the changed field (JSON name)",
+                    "type": "string"
+                }
+            }
+        },
+        "models.Comment": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "description": "Who posted the comment",
+                    "type": "string"
+                },
+                "body": {
+                    "description": "Text of the comment",
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "When the comment was posted",
+                    "type": "string"
+                },
+                "edits": {
+                    "description": "Previous versions of the body, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CommentEdit"
+                    }
+                },
+                "id": {
+                    "description": "ID of the comment, ordered by creation time",
+                    "type": "string"
+                },
+                "mentions": {
+                    "description": "Names mentioned in the body as @name, in order of appearance",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "task_id": {
+                    "description": "ID of the task commented on",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "description": "When the comment was last edited",
+                    "type": "string"
+                }
+            }
+        },
+        "models.CommentEdit": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "description": "Body before the edit",
+                    "type": "string"
+                },
+                "edited_at": {
+                    "description": "When the body was replaced",
+                    "type": "string"
+                },
+                "edited_by": {
+                    "description": "Who edited the comment",
+                    "type": "string"
+                }
+            }
+        },
+        "models.CommentReq": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "description": "Text of the comment; @name mentions a person",
+                    "type": "string"
+                }
+            }
+        },
+        "models.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "description": "JSON name of the field, dotted for nested fields",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "What is wrong with the field",
+                    "type": "string"
+                }
+            }
+        },
+        "models.FilterReq": {
+            "type": "object",
+            "properties": {
+                "query": {
+                    "description": "Filter expression",
+                    "type": "string"
+                }
+            }
+        },
+        "models.ImportResp": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "description": "Number of tasks created",
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "description": "Whether the import was only validated",
+                    "type": "boolean"
+                },
+                "failed": {
+                    "description": "Number of rows that could not be imported",
+                    "type": "integer"
+                },
+                "overwritten": {
+                    "description": "Number of stored tasks replaced",
+                    "type": "integer"
+                },
+                "results": {
+                    "description": "Outcome of each row, in input order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ImportResult"
+                    }
+                },
+                "skipped": {
+                    "description": "Number of rows skipped due to ID conflicts",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ImportResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Why the row was not imported",
+                    "type": "string"
+                },
+                "fields": {
+                    "description": "Invalid fields of the row",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldError"
+                    }
+                },
+                "id": {
+                    "description": "ID the task is stored under",
+                    "type": "string"
+                },
+                "result": {
+                    "description": "Outcome (created, overwritten, skipped, failed)",
+                    "type": "string"
+                },
+                "row": {
+                    "description": "Position of the row in the input, from 1",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Label": {
+            "type": "object",
+            "properties": {
+                "color": {
+                    "description": "Color of the label as #rrggbb",
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "When the label was created",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "What the label is for",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID of the label",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the label, unique regardless of case",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "description": "When the label was last changed",
+                    "type": "string"
+                }
+            }
+        },
+        "models.LabelInfo": {
+            "type": "object",
+            "properties": {
+                "color": {
+                    "description": "Color of the label as #rrggbb",
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "When the label was created",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "What the label is for",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID of the label",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the label, unique regardless of case",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "description": "When the label was last changed",
+                    "type": "string"
+                },
+                "usage": {
+                    "description": "Number of tasks with the label",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.LabelReq": {
+            "type": "object",
+            "properties": {
+                "color": {
+                    "description": "Color of the label as #rrggbb; omitted uses the default color",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "What the label is for",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the label",
+                    "type": "string"
+                }
+            }
+        },
+        "models.MergeReq": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "description": "IDs of the labels to merge and delete",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.MergeResult": {
+            "type": "object",
+            "properties": {
+                "label": {
+                    "description": "Label the others were merged into",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.LabelInfo"
+                        }
+                    ]
+                },
+                "tasks": {
+                    "description": "Number of tasks relabeled",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.MoveReq": {
+            "type": "object",
+            "properties": {
+                "after": {
+                    "description": "ID of the task to place the moved task after",
+                    "type": "string"
+                },
+                "before": {
+                    "description": "ID of the task to place the moved task before",
+                    "type": "string"
+                }
+            }
+        },
+        "models.RevertReq": {
+            "type": "object",
+            "properties": {
+                "revision": {
+                    "description": "Revision holding the version to restore",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.SavedFilter": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "When the filter was first saved",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the filter",
+                    "type": "string"
+                },
+                "query": {
+                    "description": "Filter expression",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "description": "When the filter was last saved",
+                    "type": "string"
+                }
+            }
+        },
+        "models.Task": {
+            "type": "object",
+            "properties": {
+                "assignee": {
+                    "description": "Who the task is assigned to",
+                    "type": "string"
+                },
+                "completed": {
+                    "description": "Completion status of the task",
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "description": "When the task was created",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Longer description of the task",
+                    "type": "string"
+                },
+                "due": {
+                    "description": "When the task is due",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "When the task is removed automatically, if ever",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID of the task (string format)",
+                    "type": "string"
+                },
+                "labels": {
+                    "description": "IDs of the labels of the task",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "position": {
+                    "description": "Rank of the task in the manual order of the list",
+                    "type": "string"
+                },
+                "priority": {
+                    "description": "Priority of the task (low, medium, high, urgent)",
+                    "type": "string"
+                },
+                "recurrence": {
+                    "description": "How the task recurs, as an iCalendar RRULE value",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "State of the task in its workflow",
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "Free-form tags of the task",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "description": "Title of the task",
+                    "type": "string"
+                },
+                "transitions": {
+                    "description": "Changes of the status, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Transition"
+                    }
+                },
+                "ttl": {
+                    "description": "Seconds until the task expires (not stored)",
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "description": "When the task was last modified",
+                    "type": "string"
+                },
+                "workflow": {
+                    "description": "Workflow the status follows; empty for the default one",
+                    "type": "string"
+                }
+            }
+        },
+        "models.TaskHistory": {
+            "type": "object",
+            "properties": {
+                "compacted": {
+                    "description": "True if older versions were removed by etcd compaction",
+                    "type": "boolean"
+                },
+                "next": {
+                    "description": "Revision to pass as before to list older versions; 0 if there are none",
+                    "type": "integer"
+                },
+                "task_id": {
+                    "description": "ID of the task",
+                    "type": "string"
+                },
+                "versions": {
+                    "description": "Known versions, newest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TaskVersion"
+                    }
+                }
+            }
+        },
+        "models.TaskVersion": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "description": "Diff against the previous version",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Change"
+                    }
+                },
+                "revision": {
+                    "description": "etcd revision at which this version was written",
+                    "type": "integer"
+                },
+                "task": {
+                    "description": "The task as of this revision",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    ]
+                },
+                "timestamp": {
+                    "description": "When this version was written",
+                    "type": "string"
+                }
+            }
+        },
+        "models.Transition": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "description": "When the status changed",
+                    "type": "string"
+                },
+                "from": {
+                    "description": "Previous status, empty when the task was created",
+                    "type": "string"
+                },
+                "to": {
+                    "description": "New status",
+                    "type": "string"
+                }
+            }
+        },
+        "models.TrashedTask": {
+            "type": "object",
+            "properties": {
+                "assignee": {
+                    "description": "Who the task is assigned to",
+                    "type": "string"
+                },
+                "completed": {
+                    "description": "Completion status of the task",
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "description": "When the task was created",
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "description": "When the task was moved to the trash",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Longer description of the task",
+                    "type": "string"
+                },
+                "due": {
+                    "description": "When the task is due",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "When the task is removed automatically, if ever",
+                    "type": "string"
+                },
                 "id": {
                     "description": "ID of the task (string format)",
                     "type": "string"
                 },
+                "labels": {
+                    "description": "IDs of the labels of the task",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "position": {
+                    "description": "Rank of the task in the manual order of the list",
+                    "type": "string"
+                },
+                "priority": {
+                    "description": "Priority of the task (low, medium, high, urgent)",
+                    "type": "string"
+                },
+                "purge_at": {
+                    "description": "When the task is purged automatically",
+                    "type": "string"
+                },
+                "recurrence": {
+                    "description": "How the task recurs, as an iCalendar RRULE value",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "State of the task in its workflow",
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "Free-form tags of the task",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "title": {
                     "description": "Title of the task",
                     "type": "string"
+                },
+                "transitions": {
+                    "description": "Changes of the status, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Transition"
+                    }
+                },
+                "ttl": {
+                    "description": "Seconds until the task expires (not stored)",
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "description": "When the task was last modified",
+                    "type": "string"
+                },
+                "workflow": {
+                    "description": "Workflow the status follows; empty for the default one",
+                    "type": "string"
                 }
             }
         },
         "models.UpdateReq": {
             "type": "object",
             "properties": {
+                "assignee": {
+                    "description": "New assignee; omitted keeps the current one, empty removes it",
+                    "type": "string"
+                },
+                "clear_due": {
+                    "description": "Removes the due date",
+                    "type": "boolean"
+                },
                 "completed": {
-                    "description": "New completion status for the task update",
+                    "description": "New completion status; omitted keeps the current one, ignored if status is given",
                     "type": "boolean"
                 },
+                "description": {
+                    "description": "New description; omitted keeps the current one",
+                    "type": "string"
+                },
+                "due": {
+                    "description": "New due date; omitted keeps the current one",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "New absolute expiry time",
+                    "type": "string"
+                },
+                "labels": {
+                    "description": "New label IDs; omitted keeps the current ones",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "priority": {
+                    "description": "New priority; omitted keeps the current one",
+                    "type": "string"
+                },
+                "recurrence": {
+                    "description": "New recurrence rule; omitted keeps the current one",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "New status; omitted moves the task to match completed",
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "New tags; omitted keeps the current ones",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "title": {
                     "description": "New title for the task update",
                     "type": "string"
+                },
+                "ttl": {
+                    "description": "New lifetime in seconds; 0 removes the expiry",
+                    "type": "integer"
+                },
+                "workflow": {
+                    "description": "New workflow; omitted keeps the current one",
+                    "type": "string"
+                }
+            }
+        },
+        "models.Workflow": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "When the workflow was first saved",
+                    "type": "string"
+                },
+                "initial": {
+                    "description": "State of new tasks",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the workflow",
+                    "type": "string"
+                },
+                "states": {
+                    "description": "States of the workflow, in board order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WorkflowState"
+                    }
+                },
+                "transitions": {
+                    "description": "States reachable from each state; any state is reachable if empty",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "updated_at": {
+                    "description": "When the workflow was last saved",
+                    "type": "string"
+                }
+            }
+        },
+        "models.WorkflowInfo": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "When the workflow was first saved",
+                    "type": "string"
+                },
+                "initial": {
+                    "description": "State of new tasks",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the workflow",
+                    "type": "string"
+                },
+                "states": {
+                    "description": "States of the workflow, in board order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WorkflowState"
+                    }
+                },
+                "transitions": {
+                    "description": "States reachable from each state; any state is reachable if empty",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "updated_at": {
+                    "description": "When the workflow was last saved",
+                    "type": "string"
+                },
+                "usage": {
+                    "description": "Number of tasks by status",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "models.WorkflowReq": {
+            "type": "object",
+            "properties": {
+                "initial": {
+                    "description": "State of new tasks",
+                    "type": "string"
+                },
+                "states": {
+                    "description": "States of the workflow, in board order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WorkflowState"
+                    }
+                },
+                "transitions": {
+                    "description": "States reachable from each state; any state is reachable if empty",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "models.WorkflowState": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "description": "Name of the state",
+                    "type": "string"
+                },
+                "terminal": {
+                    "description": "Whether tasks in the state are completed",
+                    "type": "boolean"
+                }
+            }
+        },
+        "search.Highlights": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "description": "Escaped description with matches wrapped in \u003cmark\u003e tags",
+                    "type": "string"
+                },
+                "title": {
+                    "description": "Escaped title with matches wrapped in \u003cmark\u003e tags",
+                    "type": "string"
+                }
+            }
+        },
+        "search.Result": {
+            "type": "object",
+            "properties": {
+                "highlights": {
+                    "description": "Matched words in the task",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/search.Highlights"
+                        }
+                    ]
+                },
+                "score": {
+                    "description": "Relevance of the task, higher is better",
+                    "type": "number"
+                },
+                "task": {
+                    "description": "The matching task",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Task"
+                        }
+                    ]
                 }
             }
         }
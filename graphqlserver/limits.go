@@ -0,0 +1,557 @@
+package graphqlserver
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Default limits of the queries served, see MaxDepth and MaxComplexity.
+const (
+	DefaultMaxDepth      = 10
+	DefaultMaxComplexity = 20000
+)
+
+// MaxDepth returns how deeply the fields of a query may be nested. It is read
+// from the GRAPHQL_MAX_DEPTH environment variable.
+func MaxDepth() int {
+	return envLimit("GRAPHQL_MAX_DEPTH", DefaultMaxDepth)
+}
+
+// MaxComplexity returns the highest complexity a query may have. It is read
+// from the GRAPHQL_MAX_COMPLEXITY environment variable.
+//
+// Every field selected costs 1. The selections of list fields are counted once
+// per item they may return, taken from their first argument or its default.
+// Introspection fields are free, so that tools can always load the schema.
+func MaxComplexity() int {
+	return envLimit("GRAPHQL_MAX_COMPLEXITY", DefaultMaxComplexity)
+}
+
+func envLimit(name string, def int) int {
+	if v := os.Getenv(name); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return def
+}
+
+// listSizes holds the default of the first argument of the list fields, by field name.
+var listSizes = map[string]int{
+	"tasks":   DefaultPageSize,
+	"history": DefaultHistory,
+}
+
+// maxCost caps the computed complexity so that it cannot overflow.
+const maxCost = 1 << 40
+
+// selection is a field, fragment spread or inline fragment of a query.
+type selection struct {
+	name     string      // Field name, or fragment name of a spread; empty for inline fragments
+	spread   bool        // Whether this is a fragment spread
+	first    *int        // Literal value of the first argument
+	firstVar string      // Variable given as the first argument
+	children []selection // Selection set of a field or inline fragment
+}
+
+// operation is an operation defined in a query.
+type operation struct {
+	typ        string // query, mutation or subscription
+	name       string
+	selections []selection
+}
+
+// measure returns the type, depth and complexity of the operation of a query
+// to be executed, with variables holding the values of its variables.
+func measure(query, operationName string, variables map[string]interface{}) (typ string, depth, complexity int, err error) {
+	p := &docParser{lex: lexer{src: query}}
+	ops, fragments, err := p.document()
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	var op *operation
+	for i := range ops {
+		if ops[i].name == operationName || (operationName == "" && len(ops) == 1) {
+			op = &ops[i]
+			break
+		}
+	}
+	if op == nil {
+		return "", 0, 0, fmt.Errorf("operation %q not found", operationName)
+	}
+
+	m := &measurer{fragments: fragments, variables: variables, visiting: map[string]bool{}}
+	cost, depth := m.selections(op.selections, 1)
+	return op.typ, depth, int(cost), nil
+}
+
+// measurer sums up the cost of selections, expanding fragments.
+type measurer struct {
+	fragments map[string][]selection
+	variables map[string]interface{}
+	visiting  map[string]bool // Fragments being expanded, to stop at cycles
+}
+
+// selections returns the cost and depth of selections at the given depth.
+func (m *measurer) selections(sels []selection, depth int) (cost int64, maxDepth int) {
+	for _, sel := range sels {
+		var c int64
+		d := 0
+		switch {
+		case sel.spread:
+			if m.visiting[sel.name] {
+				continue
+			}
+			m.visiting[sel.name] = true
+			c, d = m.selections(m.fragments[sel.name], depth)
+			delete(m.visiting, sel.name)
+		case sel.name == "":
+			c, d = m.selections(sel.children, depth)
+		case strings.HasPrefix(sel.name, "__"):
+			continue
+		default:
+			c, d = m.selections(sel.children, depth+1)
+			if d < depth {
+				d = depth
+			}
+			c = mulCost(c, m.listSize(sel))
+			c = addCost(c, 1)
+		}
+		cost = addCost(cost, c)
+		if d > maxDepth {
+			maxDepth = d
+		}
+	}
+	return cost, maxDepth
+}
+
+// listSize returns how many items a field may return, 1 unless it is a list field.
+func (m *measurer) listSize(sel selection) int64 {
+	size, ok := listSizes[sel.name]
+	if !ok {
+		return 1
+	}
+	if sel.first != nil {
+		return int64(*sel.first)
+	}
+	switch v := m.variables[sel.firstVar].(type) {
+	case float64:
+		return int64(v)
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	}
+	return int64(size)
+}
+
+func addCost(a, b int64) int64 {
+	if a+b > maxCost {
+		return maxCost
+	}
+	return a + b
+}
+
+func mulCost(a, b int64) int64 {
+	if b <= 0 {
+		return 0
+	}
+	if a > maxCost/b {
+		return maxCost
+	}
+	return a * b
+}
+
+// docParser reads the operations and fragments of a GraphQL query document,
+// keeping only what the limits are computed from.
+type docParser struct {
+	lex lexer
+	tok token
+}
+
+// document parses a whole query document.
+func (p *docParser) document() ([]operation, map[string][]selection, error) {
+	var ops []operation
+	fragments := map[string][]selection{}
+	if err := p.next(); err != nil {
+		return nil, nil, err
+	}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.tok.is("{"):
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, nil, err
+			}
+			ops = append(ops, operation{typ: "query", selections: sels})
+		case p.tok.is("query"), p.tok.is("mutation"), p.tok.is("subscription"):
+			op := operation{typ: p.tok.text}
+			if err := p.next(); err != nil {
+				return nil, nil, err
+			}
+			if p.tok.kind == tokName {
+				op.name = p.tok.text
+				if err := p.next(); err != nil {
+					return nil, nil, err
+				}
+			}
+			if p.tok.is("(") {
+				if err := p.skipBalanced("(", ")"); err != nil {
+					return nil, nil, err
+				}
+			}
+			if err := p.directives(); err != nil {
+				return nil, nil, err
+			}
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, nil, err
+			}
+			op.selections = sels
+			ops = append(ops, op)
+		case p.tok.is("fragment"):
+			if err := p.next(); err != nil {
+				return nil, nil, err
+			}
+			name := p.tok.text
+			if err := p.expectName(); err != nil {
+				return nil, nil, err
+			}
+			if !p.tok.is("on") {
+				return nil, nil, p.errorf("expected on")
+			}
+			if err := p.next(); err != nil {
+				return nil, nil, err
+			}
+			if err := p.expectName(); err != nil {
+				return nil, nil, err
+			}
+			if err := p.directives(); err != nil {
+				return nil, nil, err
+			}
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, nil, err
+			}
+			fragments[name] = sels
+		default:
+			return nil, nil, p.errorf("unexpected %q", p.tok.text)
+		}
+	}
+	return ops, fragments, nil
+}
+
+// selectionSet parses a selection set enclosed in braces.
+func (p *docParser) selectionSet() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sels []selection
+	for !p.tok.is("}") {
+		if p.tok.kind == tokEOF {
+			return nil, p.errorf("unterminated selection set")
+		}
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	return sels, p.next()
+}
+
+// selection parses a field, fragment spread or inline fragment.
+func (p *docParser) selection() (selection, error) {
+	var sel selection
+	if p.tok.is("...") {
+		if err := p.next(); err != nil {
+			return sel, err
+		}
+		if p.tok.kind == tokName && !p.tok.is("on") {
+			sel.name, sel.spread = p.tok.text, true
+			if err := p.next(); err != nil {
+				return sel, err
+			}
+			return sel, p.directives()
+		}
+		if p.tok.is("on") {
+			if err := p.next(); err != nil {
+				return sel, err
+			}
+			if err := p.expectName(); err != nil {
+				return sel, err
+			}
+		}
+		if err := p.directives(); err != nil {
+			return sel, err
+		}
+		children, err := p.selectionSet()
+		sel.children = children
+		return sel, err
+	}
+
+	sel.name = p.tok.text
+	if err := p.expectName(); err != nil {
+		return sel, err
+	}
+	// The name read first was an alias
+	if p.tok.is(":") {
+		if err := p.next(); err != nil {
+			return sel, err
+		}
+		sel.name = p.tok.text
+		if err := p.expectName(); err != nil {
+			return sel, err
+		}
+	}
+	if p.tok.is("(") {
+		if err := p.arguments(&sel); err != nil {
+			return sel, err
+		}
+	}
+	if err := p.directives(); err != nil {
+		return sel, err
+	}
+	if p.tok.is("{") {
+		children, err := p.selectionSet()
+		if err != nil {
+			return sel, err
+		}
+		sel.children = children
+	}
+	return sel, nil
+}
+
+// arguments parses the arguments of a field, recording its first argument.
+func (p *docParser) arguments(sel *selection) error {
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	for !p.tok.is(")") {
+		name := p.tok.text
+		if err := p.expectName(); err != nil {
+			return err
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		if name == "first" {
+			switch {
+			case p.tok.kind == tokInt:
+				n, err := strconv.Atoi(p.tok.text)
+				if err != nil {
+					n = math.MaxInt32
+				}
+				sel.first = &n
+			case p.tok.is("$"):
+				if err := p.next(); err != nil {
+					return err
+				}
+				sel.firstVar = p.tok.text
+			}
+		}
+		if err := p.value(); err != nil {
+			return err
+		}
+	}
+	return p.next()
+}
+
+// directives skips the directives at the current token.
+func (p *docParser) directives() error {
+	for p.tok.is("@") {
+		if err := p.next(); err != nil {
+			return err
+		}
+		if err := p.expectName(); err != nil {
+			return err
+		}
+		if p.tok.is("(") {
+			if err := p.skipBalanced("(", ")"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// value skips an input value.
+func (p *docParser) value() error {
+	switch {
+	case p.tok.is("$"):
+		if err := p.next(); err != nil {
+			return err
+		}
+		return p.expectName()
+	case p.tok.is("["):
+		return p.skipBalanced("[", "]")
+	case p.tok.is("{"):
+		return p.skipBalanced("{", "}")
+	case p.tok.kind == tokEOF || p.tok.kind == tokPunct:
+		return p.errorf("expected a value")
+	default:
+		return p.next()
+	}
+}
+
+// skipBalanced skips tokens from an opening punctuator to its matching closing one.
+func (p *docParser) skipBalanced(open, close string) error {
+	level := 0
+	for {
+		switch {
+		case p.tok.kind == tokEOF:
+			return p.errorf("expected %q", close)
+		case p.tok.is(open):
+			level++
+		case p.tok.is(close):
+			level--
+		}
+		if err := p.next(); err != nil {
+			return err
+		}
+		if level == 0 {
+			return nil
+		}
+	}
+}
+
+func (p *docParser) next() error {
+	tok, err := p.lex.next()
+	p.tok = tok
+	return err
+}
+
+func (p *docParser) expect(punct string) error {
+	if !p.tok.is(punct) || p.tok.kind != tokPunct {
+		return p.errorf("expected %q", punct)
+	}
+	return p.next()
+}
+
+func (p *docParser) expectName() error {
+	if p.tok.kind != tokName {
+		return p.errorf("expected a name")
+	}
+	return p.next()
+}
+
+func (p *docParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("syntax error at offset %d: %s", p.tok.pos, fmt.Sprintf(format, args...))
+}
+
+// Kinds of tokens.
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// is reports whether the token is the punctuator or name s.
+func (t token) is(s string) bool {
+	return (t.kind == tokPunct || t.kind == tokName) && t.text == s
+}
+
+// lexer splits a GraphQL document into tokens, skipping ignored characters.
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() (token, error) {
+	// Skip white space, commas, byte order marks and comments
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "\ufeff"):
+			l.pos += len("\ufeff")
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			goto scan
+		}
+	}
+	return token{kind: tokEOF, pos: l.pos}, nil
+
+scan:
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: tokPunct, text: "...", pos: start}, nil
+	case strings.IndexByte("!$&():=@[]{}|", c) >= 0:
+		l.pos++
+		return token{kind: tokPunct, text: string(c), pos: start}, nil
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokName, text: l.src[start:l.pos], pos: start}, nil
+	case c == '-' || isDigit(c):
+		kind := tokInt
+		l.pos++
+		for l.pos < len(l.src) {
+			c := l.src[l.pos]
+			if c == '.' || c == 'e' || c == 'E' || ((c == '+' || c == '-') && (l.src[l.pos-1] == 'e' || l.src[l.pos-1] == 'E')) {
+				kind = tokFloat
+			} else if !isDigit(c) {
+				break
+			}
+			l.pos++
+		}
+		return token{kind: kind, text: l.src[start:l.pos], pos: start}, nil
+	case strings.HasPrefix(l.src[l.pos:], `"""`):
+		l.pos += 3
+		for {
+			switch {
+			case l.pos >= len(l.src):
+				return token{}, fmt.Errorf("syntax error at offset %d: unterminated string", start)
+			case strings.HasPrefix(l.src[l.pos:], `\"""`):
+				l.pos += 4
+			case strings.HasPrefix(l.src[l.pos:], `"""`):
+				l.pos += 3
+				return token{kind: tokString, pos: start}, nil
+			default:
+				l.pos++
+			}
+		}
+	case c == '"':
+		l.pos++
+		for {
+			switch {
+			case l.pos >= len(l.src) || l.src[l.pos] == '\n' || l.src[l.pos] == '\r':
+				return token{}, fmt.Errorf("syntax error at offset %d: unterminated string", start)
+			case l.src[l.pos] == '\\':
+				l.pos += 2
+			case l.src[l.pos] == '"':
+				l.pos++
+				return token{kind: tokString, pos: start}, nil
+			default:
+				l.pos++
+			}
+		}
+	}
+	return token{}, fmt.Errorf("syntax error at offset %d: unexpected character %q", start, c)
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
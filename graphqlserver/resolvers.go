@@ -0,0 +1,534 @@
+package graphqlserver
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"task-organizer/models"
+	"task-organizer/query"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+// resolver is the root resolver of the schema. Like the REST routes, it lists,
+// creates and watches tasks through the etcd client on port 2379 and reads,
+// updates and deletes single tasks through the one on port 2380.
+type resolver struct {
+	handler1 *models.Handler // etcd client on port 2379
+	handler2 *models.Handler // etcd client on port 2380
+}
+
+// Task returns a task by its ID.
+func (r *resolver) Task(ctx context.Context, args struct{ ID graphql.ID }) (*taskResolver, error) {
+	task, kv, err := r.handler2.GetTaskKV(ctx, string(args.ID))
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, gqlError(err)
+	}
+	return &taskResolver{task: *task, rev: kv.ModRevision, h: r.handler2}, nil
+}
+
+// cursor is the position of a listing to continue from.
+type cursor struct {
+	After    string `json:"after"` // ID of the last task examined
+	Revision int64  `json:"rev"`   // etcd revision the listing reads at
+}
+
+// Tasks returns a page of tasks, ordered by ID.
+func (r *resolver) Tasks(ctx context.Context, args struct {
+	Completed   *bool
+	Tag         *string
+	Assignee    *string
+	DueAfter    *graphql.Time
+	DueBefore   *graphql.Time
+	Filter      *string
+	SavedFilter *string
+	First       int32
+	After       *string
+}) (*connectionResolver, error) {
+	h := r.handler1
+	if args.First < 0 || args.First > MaxPageSize {
+		return nil, badInput(fmt.Sprintf("first must be between 0 and %d", MaxPageSize))
+	}
+
+	var pos cursor
+	if args.After != nil && *args.After != "" {
+		data, err := base64.RawURLEncoding.DecodeString(*args.After)
+		if err != nil || json.Unmarshal(data, &pos) != nil {
+			return nil, badInput("Invalid cursor")
+		}
+	}
+
+	filter := models.TaskFilter{
+		Completed: args.Completed,
+		Tag:       stringOf(args.Tag),
+		Assignee:  stringOf(args.Assignee),
+		DueAfter:  timeOf(args.DueAfter),
+		DueBefore: timeOf(args.DueBefore),
+	}
+	expr := stringOf(args.Filter)
+	if name := stringOf(args.SavedFilter); name != "" {
+		saved, err := h.GetSavedFilter(ctx, name)
+		if err != nil {
+			return nil, gqlError(err)
+		}
+		expr = saved.Query
+	}
+	match, err := matchExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &connectionResolver{nodes: []*taskResolver{}}
+	if args.First == 0 {
+		return conn, nil
+	}
+	page, err := h.ListPage(ctx, filter, match, pos.After, int(args.First), pos.Revision)
+	if errors.Is(err, rpctypes.ErrCompacted) {
+		return nil, &Error{Message: "Cursor has expired; list again from the start", Code: CodeBadInput}
+	}
+	if err != nil {
+		return nil, gqlError(err)
+	}
+
+	for _, kv := range page.Kvs {
+		var task models.Task
+		if err := json.Unmarshal(kv.Value, &task); err != nil {
+			return nil, gqlError(err)
+		}
+		conn.nodes = append(conn.nodes, &taskResolver{task: task, rev: kv.ModRevision, h: r.handler2})
+	}
+	if page.Next != "" {
+		data, _ := json.Marshal(cursor{After: page.Next, Revision: page.Revision})
+		next := base64.RawURLEncoding.EncodeToString(data)
+		conn.endCursor = &next
+	}
+	return conn, nil
+}
+
+// createInput holds the fields of a task to create. Fields left out are nil.
+type createInput struct {
+	Title       string
+	Description *string
+	Completed   *bool
+	Status      *string
+	Workflow    *string
+	Priority    *string
+	Tags        *[]string
+	Labels      *[]string
+	Due         *graphql.Time
+	Assignee    *string
+	Recurrence  *string
+	TTL         *int32
+	ExpiresAt   *graphql.Time
+}
+
+// updateInput holds the fields of a task to change. Fields left out are nil.
+type updateInput struct {
+	Title       *string
+	Description *string
+	Completed   *bool
+	Status      *string
+	Workflow    *string
+	Priority    *string
+	Tags        *[]string
+	Labels      *[]string
+	Due         *graphql.Time
+	ClearDue    *bool
+	Assignee    *string
+	Recurrence  *string
+	TTL         *int32
+	ExpiresAt   *graphql.Time
+}
+
+// CreateTask creates a task with a generated ID.
+func (r *resolver) CreateTask(ctx context.Context, args struct{ Input createInput }) (*taskResolver, error) {
+	h := r.handler1
+	in := args.Input
+	task := models.Task{
+		Title:       in.Title,
+		Description: stringOf(in.Description),
+		Completed:   in.Completed != nil && *in.Completed,
+		Status:      stringOf(in.Status),
+		Workflow:    stringOf(in.Workflow),
+		Priority:    stringOf(in.Priority),
+		Due:         timeOf(in.Due),
+		Assignee:    stringOf(in.Assignee),
+		Recurrence:  stringOf(in.Recurrence),
+		ExpiresAt:   timeOf(in.ExpiresAt),
+	}
+	if in.Tags != nil {
+		task.Tags = *in.Tags
+	}
+	if in.Labels != nil {
+		task.Labels = *in.Labels
+	}
+	if in.TTL != nil {
+		task.TTL = int64(*in.TTL)
+	}
+
+	// Validate the task, assign it a unique ID and prepare storing it with its audit entry
+	m, err := h.PrepareCreate(ctx, task, auditMetaFrom(ctx))
+	if err != nil {
+		return nil, gqlError(err)
+	}
+	rev, err := h.Commit(ctx, m)
+	h.Release(ctx, err == nil, m)
+	if errors.Is(err, models.ErrConflict) {
+		err = models.ErrExists
+	}
+	if err != nil {
+		return nil, gqlError(err)
+	}
+	return &taskResolver{task: *m.After, rev: rev, h: r.handler2}, nil
+}
+
+// UpdateTask changes the given fields of a task.
+func (r *resolver) UpdateTask(ctx context.Context, args struct {
+	ID       graphql.ID
+	Input    updateInput
+	Revision *Revision
+}) (*taskResolver, error) {
+	h := r.handler2
+	current, kv, err := h.GetTaskKV(ctx, string(args.ID))
+	if err != nil {
+		return nil, gqlError(err)
+	}
+	if args.Revision != nil && int64(*args.Revision) != kv.ModRevision {
+		return nil, gqlError(models.ErrConflict)
+	}
+
+	in := args.Input
+	update := models.UpdateReq{
+		Title:       current.Title,
+		Completed:   in.Completed,
+		Description: in.Description,
+		Status:      in.Status,
+		Workflow:    in.Workflow,
+		Priority:    in.Priority,
+		Tags:        in.Tags,
+		Labels:      in.Labels,
+		Due:         timeOf(in.Due),
+		ClearDue:    in.ClearDue != nil && *in.ClearDue,
+		Assignee:    in.Assignee,
+		Recurrence:  in.Recurrence,
+		ExpiresAt:   timeOf(in.ExpiresAt),
+	}
+	if in.Title != nil {
+		update.Title = *in.Title
+	}
+	if in.TTL != nil {
+		ttl := int64(*in.TTL)
+		update.TTL = &ttl
+	}
+
+	m, err := h.PrepareUpdate(ctx, string(args.ID), update, auditMetaFrom(ctx))
+	if err != nil {
+		return nil, gqlError(err)
+	}
+	// The fields left out of the input were taken from the task as read above
+	if m.Prev().ModRevision != kv.ModRevision {
+		h.Release(ctx, false, m)
+		return nil, gqlError(models.ErrConflict)
+	}
+	rev, err := h.Commit(ctx, m)
+	h.Release(ctx, err == nil, m)
+	if err != nil {
+		return nil, gqlError(err)
+	}
+	return &taskResolver{task: *m.After, rev: rev, h: h}, nil
+}
+
+// DeleteTask moves a task to the trash and returns it as it was.
+func (r *resolver) DeleteTask(ctx context.Context, args struct {
+	ID       graphql.ID
+	Revision *Revision
+}) (*taskResolver, error) {
+	h := r.handler2
+
+	// The trash lease purges the task once the retention period is over
+	lease, err := h.GrantTrashLease(ctx)
+	if err != nil {
+		return nil, gqlError(err)
+	}
+	m, err := h.PrepareDelete(ctx, string(args.ID), lease, auditMetaFrom(ctx))
+	if err == nil && args.Revision != nil && m.Prev().ModRevision != int64(*args.Revision) {
+		err = models.ErrConflict
+	}
+	if err == nil {
+		err = h.Apply(ctx, m)
+	}
+	if err != nil {
+		h.Client.Revoke(ctx, lease)
+		return nil, gqlError(err)
+	}
+	return &taskResolver{task: *m.Before, rev: m.Prev().ModRevision, h: h}, nil
+}
+
+// eventTypes maps the kinds of task changes to TaskEventType values.
+var eventTypes = map[string]string{
+	models.ChangeCreated: "CREATED",
+	models.ChangeUpdated: "UPDATED",
+	models.ChangeDeleted: "DELETED",
+}
+
+// TaskChanged streams changes to the selected tasks from the etcd watch on
+// the tasks prefix. The stream ends when the subscription is stopped or the
+// watch fails.
+func (r *resolver) TaskChanged(ctx context.Context, args struct {
+	Completed *bool
+	Tag       *string
+	Assignee  *string
+	Filter    *string
+}) (<-chan *eventResolver, error) {
+	filter := models.TaskFilter{Completed: args.Completed, Tag: stringOf(args.Tag), Assignee: stringOf(args.Assignee)}
+	match, err := matchExpr(stringOf(args.Filter))
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan *eventResolver)
+	go func() {
+		defer close(events)
+		err := r.handler1.WatchTasks(ctx, filter, match, 0, func(change models.TaskChange) error {
+			event := &eventResolver{
+				typ:  eventTypes[change.Type],
+				task: &taskResolver{task: change.Task, rev: change.TaskRevision, h: r.handler2},
+				rev:  change.Revision,
+			}
+			select {
+			case events <- event:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Println("GraphQL subscription watch failed:", err)
+		}
+	}()
+	return events, nil
+}
+
+// taskResolver resolves the fields of a task read at an etcd revision.
+type taskResolver struct {
+	task models.Task
+	rev  int64
+	h    *models.Handler // etcd client to read the history from
+}
+
+func (t *taskResolver) ID() graphql.ID           { return graphql.ID(t.task.ID) }
+func (t *taskResolver) Title() string            { return t.task.Title }
+func (t *taskResolver) Description() string      { return t.task.Description }
+func (t *taskResolver) Completed() bool          { return t.task.Completed }
+func (t *taskResolver) Status() *string          { return optional(t.task.Status) }
+func (t *taskResolver) Workflow() *string        { return optional(t.task.Workflow) }
+func (t *taskResolver) Priority() *string        { return optional(t.task.Priority) }
+func (t *taskResolver) Due() *graphql.Time       { return gqlTime(t.task.Due) }
+func (t *taskResolver) Assignee() *string        { return optional(t.task.Assignee) }
+func (t *taskResolver) Recurrence() *string      { return optional(t.task.Recurrence) }
+func (t *taskResolver) CreatedAt() graphql.Time  { return graphql.Time{Time: t.task.CreatedAt} }
+func (t *taskResolver) UpdatedAt() graphql.Time  { return graphql.Time{Time: t.task.UpdatedAt} }
+func (t *taskResolver) ExpiresAt() *graphql.Time { return gqlTime(t.task.ExpiresAt) }
+func (t *taskResolver) Revision() Revision       { return Revision(t.rev) }
+
+func (t *taskResolver) Tags() []string {
+	if t.task.Tags == nil {
+		return []string{}
+	}
+	return t.task.Tags
+}
+
+func (t *taskResolver) Labels() []string {
+	if t.task.Labels == nil {
+		return []string{}
+	}
+	return t.task.Labels
+}
+
+// History returns up to first prior versions of the task, newest first.
+func (t *taskResolver) History(ctx context.Context, args struct{ First int32 }) ([]*versionResolver, error) {
+	if args.First < 0 || args.First > MaxHistory {
+		return nil, badInput(fmt.Sprintf("first must be between 0 and %d", MaxHistory))
+	}
+	versions := []*versionResolver{}
+	if args.First == 0 {
+		return versions, nil
+	}
+	history, err := t.h.GetTaskHistory(ctx, t.task.ID, int(args.First), 0)
+	if errors.Is(err, models.ErrNotFound) {
+		return versions, nil
+	}
+	if err != nil {
+		return nil, gqlError(err)
+	}
+	for _, v := range history.Versions {
+		versions = append(versions, &versionResolver{version: v, h: t.h})
+	}
+	return versions, nil
+}
+
+// versionResolver resolves the fields of a past version of a task.
+type versionResolver struct {
+	version models.TaskVersion
+	h       *models.Handler
+}
+
+func (v *versionResolver) Revision() Revision      { return Revision(v.version.Revision) }
+func (v *versionResolver) Timestamp() graphql.Time { return graphql.Time{Time: v.version.Timestamp} }
+
+func (v *versionResolver) Task() *taskResolver {
+	return &taskResolver{task: v.version.Task, rev: v.version.Revision, h: v.h}
+}
+
+func (v *versionResolver) Changes() ([]*changeResolver, error) {
+	changes := []*changeResolver{}
+	for _, c := range v.version.Changes {
+		before, err := jsonValue(c.Before)
+		if err != nil {
+			return nil, gqlError(err)
+		}
+		after, err := jsonValue(c.After)
+		if err != nil {
+			return nil, gqlError(err)
+		}
+		changes = append(changes, &changeResolver{field: c.Field, before: before, after: after})
+	}
+	return changes, nil
+}
+
+// changeResolver resolves a field level change, with its values as JSON.
+type changeResolver struct {
+	field         string
+	before, after *string
+}
+
+func (c *changeResolver) Field() string   { return c.field }
+func (c *changeResolver) Before() *string { return c.before }
+func (c *changeResolver) After() *string  { return c.after }
+
+// connectionResolver resolves a page of tasks.
+type connectionResolver struct {
+	nodes     []*taskResolver
+	endCursor *string // nil on the last page
+}
+
+func (c *connectionResolver) Nodes() []*taskResolver { return c.nodes }
+func (c *connectionResolver) PageInfo() *pageInfoResolver {
+	return &pageInfoResolver{endCursor: c.endCursor}
+}
+
+type pageInfoResolver struct {
+	endCursor *string
+}
+
+func (p *pageInfoResolver) HasNextPage() bool  { return p.endCursor != nil }
+func (p *pageInfoResolver) EndCursor() *string { return p.endCursor }
+
+// eventResolver resolves a change to a watched task.
+type eventResolver struct {
+	typ  string
+	task *taskResolver
+	rev  int64
+}
+
+func (e *eventResolver) Type() string        { return e.typ }
+func (e *eventResolver) Task() *taskResolver { return e.task }
+func (e *eventResolver) Revision() Revision  { return Revision(e.rev) }
+
+// Revision is the Revision scalar, an etcd revision. It is a 64-bit integer,
+// which the built-in Int scalar cannot hold.
+type Revision int64
+
+// ImplementsGraphQLType maps Revision to the Revision scalar.
+func (Revision) ImplementsGraphQLType(name string) bool { return name == "Revision" }
+
+// UnmarshalGraphQL reads a revision given as an integer or a string of digits.
+func (r *Revision) UnmarshalGraphQL(input interface{}) error {
+	switch v := input.(type) {
+	case int32:
+		*r = Revision(v)
+	case int64:
+		*r = Revision(v)
+	case float64:
+		*r = Revision(v)
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid revision %q", v)
+		}
+		*r = Revision(n)
+	default:
+		return fmt.Errorf("wrong type for Revision: %T", input)
+	}
+	return nil
+}
+
+// matchExpr parses a filter expression into a match function, nil if it is empty.
+func matchExpr(expr string) (func(models.Task) bool, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	f, err := query.Parse(expr)
+	if err != nil {
+		var qErr *query.Error
+		if errors.As(err, &qErr) {
+			return nil, &Error{Message: qErr.Error(), Code: CodeBadInput, Extra: map[string]interface{}{"position": qErr.Pos, "token": qErr.Token}}
+		}
+		return nil, badInput(err.Error())
+	}
+	return f.Match, nil
+}
+
+// jsonValue renders a changed value as JSON, nil if it is unset.
+func jsonValue(v interface{}) (*string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	s := string(data)
+	return &s, nil
+}
+
+// optional returns a pointer to s, nil if it is empty.
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// stringOf returns the string pointed to by s, empty if it is nil.
+func stringOf(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// timeOf converts a GraphQL time to a time, nil if it is unset.
+func timeOf(t *graphql.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	return &t.Time
+}
+
+// gqlTime converts a time to a GraphQL time, nil if it is unset.
+func gqlTime(t *time.Time) *graphql.Time {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: *t}
+}
@@ -0,0 +1,244 @@
+// Package graphqlserver serves the tasks over GraphQL, with queries, mutations
+// and subscriptions backed by the etcd watch on the tasks prefix. It shares the
+// store and validation of the models package with the REST handlers.
+package graphqlserver
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"task-organizer/models"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+// Schema is the GraphQL schema served.
+//
+//go:embed schema.graphql
+var Schema string
+
+// Page sizes of the tasks query and the history of a task.
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+	DefaultHistory  = 10
+	MaxHistory      = 100
+)
+
+// requestTimeout bounds the execution of queries and mutations.
+const requestTimeout = 30 * time.Second
+
+// Codes of the errors, given in their code extension.
+const (
+	CodeBadInput  = "BAD_USER_INPUT"
+	CodeNotFound  = "NOT_FOUND"
+	CodeConflict  = "CONFLICT"
+	CodeTooLarge  = "QUERY_TOO_COMPLEX"
+	CodeInternal  = "INTERNAL_SERVER_ERROR"
+	CodeBadSyntax = "GRAPHQL_PARSE_FAILED"
+)
+
+// Error is an error of a resolver, reported with its code in the extensions
+// of the response.
+type Error struct {
+	Message string
+	Code    string
+	Extra   map[string]interface{} // Further extensions
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Extensions returns the extensions of the error in the response.
+func (e *Error) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.Code}
+	for k, v := range e.Extra {
+		ext[k] = v
+	}
+	return ext
+}
+
+// gqlError maps an error returned by the models package to a resolver error,
+// as errorStatus does to HTTP statuses for the REST handlers.
+func gqlError(err error) error {
+	var validationErr *models.ValidationError
+	switch {
+	case errors.As(err, &validationErr) && len(validationErr.Fields) > 0:
+		return &Error{Message: err.Error(), Code: CodeBadInput, Extra: map[string]interface{}{"fields": validationErr.Fields}}
+	case errors.As(err, &validationErr):
+		return &Error{Message: err.Error(), Code: CodeBadInput}
+	case errors.Is(err, models.ErrNotFound), errors.Is(err, models.ErrFilterNotFound):
+		return &Error{Message: err.Error(), Code: CodeNotFound}
+	case errors.Is(err, models.ErrExists), errors.Is(err, models.ErrConflict):
+		return &Error{Message: err.Error(), Code: CodeConflict}
+	default:
+		return &Error{Message: err.Error(), Code: CodeInternal}
+	}
+}
+
+func badInput(msg string) error {
+	return &Error{Message: msg, Code: CodeBadInput}
+}
+
+// Handler serves GraphQL requests over HTTP and subscriptions over WebSocket.
+type Handler struct {
+	schema *graphql.Schema
+}
+
+// New returns a handler serving the schema over the given etcd handlers. Like
+// the REST routes, it lists, creates and watches tasks through the etcd client
+// on port 2379 and reads, updates and deletes single tasks through the one on
+// port 2380.
+func New(handler1, handler2 *models.Handler) *Handler {
+	schema := graphql.MustParseSchema(Schema, &resolver{handler1: handler1, handler2: handler2},
+		graphql.UseStringDescriptions())
+	return &Handler{schema: schema}
+}
+
+// request is a GraphQL request, as sent in the body of a POST or the query of a GET.
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// ServeHTTP executes a GraphQL request given as JSON in the body of a POST, or
+// in the query, operationName and variables parameters of a GET. GET requests
+// may only run queries. Requests to upgrade to a WebSocket are served by
+// ServeWebSocket.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if isWebSocket(r) {
+		h.ServeWebSocket(w, r)
+		return
+	}
+
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeErrors(w, http.StatusBadRequest, &Error{Message: "Invalid variables", Code: CodeBadInput})
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				writeErrors(w, http.StatusRequestEntityTooLarge, &Error{Message: "Request body too large", Code: CodeBadInput})
+				return
+			}
+			writeErrors(w, http.StatusBadRequest, &Error{Message: "Invalid request body", Code: CodeBadInput})
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeErrors(w, http.StatusMethodNotAllowed, &Error{Message: "Method not allowed", Code: CodeBadInput})
+		return
+	}
+	if req.Query == "" {
+		writeErrors(w, http.StatusBadRequest, &Error{Message: "Query is required", Code: CodeBadInput})
+		return
+	}
+
+	op, errs := h.check(req)
+	if len(errs) > 0 {
+		writeJSON(w, http.StatusOK, &graphql.Response{Errors: errs})
+		return
+	}
+	switch {
+	case op == "subscription":
+		writeErrors(w, http.StatusBadRequest, &Error{Message: "Subscriptions are served over WebSocket", Code: CodeBadInput})
+		return
+	case op == "mutation" && r.Method == http.MethodGet:
+		writeErrors(w, http.StatusMethodNotAllowed, &Error{Message: "Mutations must be sent with POST", Code: CodeBadInput})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(withAuditMeta(r.Context(), w, r), requestTimeout)
+	defer cancel()
+	writeJSON(w, http.StatusOK, h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
+
+// check validates a request against the schema and the depth and complexity
+// limits, returning the type of its operation.
+func (h *Handler) check(req request) (string, []*gqlerrors.QueryError) {
+	if errs := h.schema.ValidateWithVariables(req.Query, req.Variables); len(errs) > 0 {
+		return "", errs
+	}
+
+	typ, depth, complexity, err := measure(req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		return "", []*gqlerrors.QueryError{queryError(&Error{Message: err.Error(), Code: CodeBadSyntax})}
+	}
+	if max := MaxDepth(); depth > max {
+		return "", []*gqlerrors.QueryError{queryError(&Error{
+			Message: fmt.Sprintf("Query has depth %d, which exceeds the maximum of %d", depth, max),
+			Code:    CodeTooLarge,
+			Extra:   map[string]interface{}{"depth": depth, "maxDepth": max},
+		})}
+	}
+	if max := MaxComplexity(); complexity > max {
+		return "", []*gqlerrors.QueryError{queryError(&Error{
+			Message: fmt.Sprintf("Query has complexity %d, which exceeds the maximum of %d", complexity, max),
+			Code:    CodeTooLarge,
+			Extra:   map[string]interface{}{"complexity": complexity, "maxComplexity": max},
+		})}
+	}
+	return typ, nil
+}
+
+// queryError wraps a resolver error reported outside of execution.
+func queryError(e *Error) *gqlerrors.QueryError {
+	return &gqlerrors.QueryError{Message: e.Message, Extensions: e.Extensions(), Err: e}
+}
+
+func writeErrors(w http.ResponseWriter, status int, e *Error) {
+	writeJSON(w, status, &graphql.Response{Errors: []*gqlerrors.QueryError{queryError(e)}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+type auditMetaKey struct{}
+
+// withAuditMeta attaches to ctx the actor and request behind the mutations of
+// an HTTP request, taken from its X-Actor and X-Request-ID headers. A request
+// ID is generated and sent back through w, if given, when the client did not
+// send one.
+func withAuditMeta(ctx context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+	meta := models.AuditMeta{Actor: r.Header.Get("X-Actor"), RequestID: r.Header.Get("X-Request-ID")}
+	if meta.Actor == "" {
+		meta.Actor = "anonymous"
+	}
+	if meta.RequestID == "" {
+		meta.RequestID = models.GenerateUniqueID()
+		if w != nil {
+			w.Header().Set("X-Request-ID", meta.RequestID)
+		}
+	}
+	return context.WithValue(ctx, auditMetaKey{}, meta)
+}
+
+// auditMetaFrom returns the audit metadata attached to ctx by withAuditMeta.
+func auditMetaFrom(ctx context.Context) models.AuditMeta {
+	if meta, ok := ctx.Value(auditMetaKey{}).(models.AuditMeta); ok {
+		return meta
+	}
+	return models.AuditMeta{Actor: "anonymous", RequestID: models.GenerateUniqueID()}
+}
+
+// isWebSocket reports whether a request asks to upgrade to a WebSocket.
+func isWebSocket(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
@@ -0,0 +1,227 @@
+package graphqlserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+// Subprotocol is the WebSocket subprotocol spoken by ServeWebSocket, the
+// GraphQL over WebSocket protocol of the graphql-ws library.
+const Subprotocol = "graphql-transport-ws"
+
+// initTimeout is how long a client has to initialise the connection.
+const initTimeout = 10 * time.Second
+
+// writeTimeout bounds the writing of a message to a client.
+const writeTimeout = 10 * time.Second
+
+// Types of the messages of the protocol.
+const (
+	msgConnectionInit = "connection_init"
+	msgConnectionAck  = "connection_ack"
+	msgPing           = "ping"
+	msgPong           = "pong"
+	msgSubscribe      = "subscribe"
+	msgNext           = "next"
+	msgError          = "error"
+	msgComplete       = "complete"
+)
+
+// Close codes of the protocol.
+const (
+	closeBadRequest   = 4400
+	closeUnauthorized = 4401
+	closeBadProtocol  = 4406
+	closeInitTimeout  = 4408
+	closeDuplicateID  = 4409
+	closeTooManyInits = 4429
+)
+
+// message is a message of the protocol, in either direction.
+type message struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+var upgrader = websocket.Upgrader{Subprotocols: []string{Subprotocol}}
+
+// ServeWebSocket serves GraphQL operations, subscriptions in particular, over
+// a WebSocket speaking the graphql-transport-ws protocol. Every operation
+// started is checked against the depth and complexity limits, and results are
+// sent as they are produced until the operation completes or the client stops it.
+func (h *Handler) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied with an error
+		return
+	}
+	ctx, cancel := context.WithCancel(withAuditMeta(r.Context(), nil, r))
+	s := &session{conn: conn, handler: h, ops: map[string]context.CancelFunc{}}
+	defer func() {
+		cancel()
+		s.wg.Wait()
+		conn.Close()
+	}()
+
+	if conn.Subprotocol() != Subprotocol {
+		s.close(closeBadProtocol, "Subprotocol not acceptable")
+		return
+	}
+
+	// The client must initialise the connection first, and only once
+	conn.SetReadDeadline(time.Now().Add(initTimeout))
+	initialised := false
+	for {
+		var msg message
+		if err := conn.ReadJSON(&msg); err != nil {
+			if !initialised {
+				if ne, ok := err.(interface{ Timeout() bool }); ok && ne.Timeout() {
+					s.close(closeInitTimeout, "Connection initialisation timeout")
+				}
+			}
+			return
+		}
+
+		switch msg.Type {
+		case msgConnectionInit:
+			if initialised {
+				s.close(closeTooManyInits, "Too many initialisation requests")
+				return
+			}
+			initialised = true
+			conn.SetReadDeadline(time.Time{})
+			s.send(message{Type: msgConnectionAck})
+		case msgPing:
+			s.send(message{Type: msgPong})
+		case msgPong:
+		case msgSubscribe:
+			if !initialised {
+				s.close(closeUnauthorized, "Unauthorized")
+				return
+			}
+			var req request
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil || req.Query == "" {
+				s.close(closeBadRequest, "Invalid subscribe message")
+				return
+			}
+			if !s.start(ctx, msg.ID, req) {
+				s.close(closeDuplicateID, "Subscriber for "+msg.ID+" already exists")
+				return
+			}
+		case msgComplete:
+			s.stop(msg.ID)
+		default:
+			s.close(closeBadRequest, "Invalid message type "+msg.Type)
+			return
+		}
+	}
+}
+
+// session is a WebSocket connection and the operations running on it.
+type session struct {
+	conn    *websocket.Conn
+	handler *Handler
+	writeMu sync.Mutex                    // Serialises writes to conn
+	mu      sync.Mutex                    // Guards ops
+	ops     map[string]context.CancelFunc // Stops the running operations, by ID
+	wg      sync.WaitGroup                // Running operations
+}
+
+// start runs an operation under an ID, sending its results until it is done.
+// It returns false if an operation with the same ID is already running.
+func (s *session) start(ctx context.Context, id string, req request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.ops[id]; ok {
+		return false
+	}
+
+	if _, errs := s.handler.check(req); len(errs) > 0 {
+		s.sendErrors(id, errs)
+		return true
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.ops[id] = cancel
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+
+		results, err := s.handler.schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+		if err != nil {
+			s.sendErrors(id, []*gqlerrors.QueryError{queryError(&Error{Message: err.Error(), Code: CodeInternal})})
+			s.finish(id)
+			return
+		}
+		for result := range results {
+			payload, err := json.Marshal(result.(*graphql.Response))
+			if err != nil {
+				continue
+			}
+			s.send(message{ID: id, Type: msgNext, Payload: payload})
+		}
+		// Operations stopped by the client are not completed again
+		if s.running(id) {
+			s.send(message{ID: id, Type: msgComplete})
+		}
+		s.finish(id)
+	}()
+	return true
+}
+
+// stop stops the operation running under an ID, if any.
+func (s *session) stop(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.ops[id]; ok {
+		cancel()
+		delete(s.ops, id)
+	}
+}
+
+// running reports whether an operation is running under an ID.
+func (s *session) running(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.ops[id]
+	return ok
+}
+
+// finish forgets the operation running under an ID once it is done.
+func (s *session) finish(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.ops, id)
+}
+
+// sendErrors reports the errors preventing an operation from running.
+func (s *session) sendErrors(id string, errs []*gqlerrors.QueryError) {
+	payload, err := json.Marshal(errs)
+	if err != nil {
+		return
+	}
+	s.send(message{ID: id, Type: msgError, Payload: payload})
+}
+
+func (s *session) send(msg message) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	s.conn.WriteJSON(msg)
+}
+
+// close closes the connection with a close code of the protocol.
+func (s *session) close(code int, reason string) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeTimeout))
+}
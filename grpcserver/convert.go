@@ -0,0 +1,70 @@
+package grpcserver
+
+import (
+	"task-organizer/models"
+	"task-organizer/taskpb"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// toProto converts a task stored at an etcd revision to its protobuf message.
+func toProto(t models.Task, rev int64) *taskpb.Task {
+	return &taskpb.Task{
+		Id:          t.ID,
+		Title:       t.Title,
+		Description: t.Description,
+		Completed:   t.Completed,
+		Priority:    t.Priority,
+		Tags:        t.Tags,
+		Due:         timestamp(t.Due),
+		Assignee:    t.Assignee,
+		Recurrence:  t.Recurrence,
+		CreatedAt:   timestamp(&t.CreatedAt),
+		UpdatedAt:   timestamp(&t.UpdatedAt),
+		ExpiresAt:   timestamp(t.ExpiresAt),
+		Ttl:         t.TTL,
+		Revision:    rev,
+	}
+}
+
+// fromProto converts a protobuf task message to a task.
+func fromProto(p *taskpb.Task) models.Task {
+	t := models.Task{
+		ID:          p.GetId(),
+		Title:       p.GetTitle(),
+		Description: p.GetDescription(),
+		Completed:   p.GetCompleted(),
+		Priority:    p.GetPriority(),
+		Tags:        p.GetTags(),
+		Due:         timeOf(p.GetDue()),
+		Assignee:    p.GetAssignee(),
+		Recurrence:  p.GetRecurrence(),
+		ExpiresAt:   timeOf(p.GetExpiresAt()),
+		TTL:         p.GetTtl(),
+	}
+	if created := timeOf(p.GetCreatedAt()); created != nil {
+		t.CreatedAt = *created
+	}
+	if updated := timeOf(p.GetUpdatedAt()); updated != nil {
+		t.UpdatedAt = *updated
+	}
+	return t
+}
+
+// timestamp converts a time to a protobuf timestamp, nil if it is unset.
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+// timeOf converts a protobuf timestamp to a time, nil if it is unset.
+func timeOf(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
@@ -0,0 +1,387 @@
+// Package grpcserver serves the task API over gRPC. It shares the store and
+// validation of the models package with the REST handlers.
+package grpcserver
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"task-organizer/models"
+	"task-organizer/query"
+	"task-organizer/taskpb"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// Page sizes of ListTasks.
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// DefaultAddr is the address the gRPC server listens on unless GRPC_ADDR is set.
+const DefaultAddr = ":9090"
+
+// Addr returns the address the gRPC server listens on, read from the
+// GRPC_ADDR environment variable.
+func Addr() string {
+	if v := os.Getenv("GRPC_ADDR"); v != "" {
+		return v
+	}
+	return DefaultAddr
+}
+
+// healthInterval is how often the health of etcd is checked.
+const healthInterval = 5 * time.Second
+
+// Server implements the TaskService. Like the REST routes, it lists, creates
+// and watches tasks through the etcd client on port 2379 and reads, updates
+// and deletes single tasks through the one on port 2380.
+type Server struct {
+	taskpb.UnimplementedTaskServiceServer
+	handler1 *models.Handler // etcd client on port 2379
+	handler2 *models.Handler // etcd client on port 2380
+}
+
+// New returns a TaskService using the given etcd handlers.
+func New(handler1, handler2 *models.Handler) *Server {
+	return &Server{handler1: handler1, handler2: handler2}
+}
+
+// ListenAndServe serves the TaskService on addr, together with gRPC health
+// checking and reflection. Both services report serving while etcd answers.
+func ListenAndServe(addr string, handler1, handler2 *models.Handler) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	s := grpc.NewServer()
+	taskpb.RegisterTaskServiceServer(s, New(handler1, handler2))
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(s, healthServer)
+	reflection.Register(s)
+
+	go func() {
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), healthInterval)
+			_, err := handler1.Client.Get(ctx, models.TaskPrefix, clientv3.WithPrefix(), clientv3.WithCountOnly())
+			cancel()
+			state := healthpb.HealthCheckResponse_SERVING
+			if err != nil {
+				state = healthpb.HealthCheckResponse_NOT_SERVING
+			}
+			healthServer.SetServingStatus("", state)
+			healthServer.SetServingStatus(taskpb.TaskService_ServiceDesc.ServiceName, state)
+			time.Sleep(healthInterval)
+		}
+	}()
+
+	return s.Serve(lis)
+}
+
+// GetTask returns a task by its ID.
+func (s *Server) GetTask(ctx context.Context, req *taskpb.GetTaskRequest) (*taskpb.Task, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "ID is required")
+	}
+	task, kv, err := s.handler2.GetTaskKV(ctx, req.GetId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	// Expose the remaining lifetime of expiring tasks
+	if kv.Lease != 0 {
+		ttl, err := s.handler2.Client.TimeToLive(ctx, clientv3.LeaseID(kv.Lease))
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		task.TTL = ttl.TTL
+	}
+	return toProto(*task, kv.ModRevision), nil
+}
+
+// pageToken is the position of a listing to continue from.
+type pageToken struct {
+	After    string `json:"after"` // ID of the last task examined
+	Revision int64  `json:"rev"`   // etcd revision the listing reads at
+}
+
+// ListTasks returns a page of tasks, ordered by ID.
+func (s *Server) ListTasks(ctx context.Context, req *taskpb.ListTasksRequest) (*taskpb.ListTasksResponse, error) {
+	h := s.handler1
+	size := int(req.GetPageSize())
+	switch {
+	case size < 0:
+		return nil, status.Error(codes.InvalidArgument, "Page size must not be negative")
+	case size == 0:
+		size = DefaultPageSize
+	case size > MaxPageSize:
+		size = MaxPageSize
+	}
+
+	var token pageToken
+	if req.GetPageToken() != "" {
+		data, err := base64.RawURLEncoding.DecodeString(req.GetPageToken())
+		if err != nil || json.Unmarshal(data, &token) != nil {
+			return nil, status.Error(codes.InvalidArgument, "Invalid page token")
+		}
+	}
+
+	filter := models.TaskFilter{
+		Completed: req.Completed,
+		Tag:       req.GetTag(),
+		Assignee:  req.GetAssignee(),
+		DueAfter:  timeOf(req.GetDueAfter()),
+		DueBefore: timeOf(req.GetDueBefore()),
+	}
+	expr := req.GetFilter()
+	if name := req.GetSavedFilter(); name != "" {
+		saved, err := h.GetSavedFilter(ctx, name)
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		expr = saved.Query
+	}
+	match, err := matchExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := h.ListPage(ctx, filter, match, token.After, size, token.Revision)
+	if errors.Is(err, rpctypes.ErrCompacted) {
+		return nil, status.Error(codes.FailedPrecondition, "Page token has expired; list again from the start")
+	}
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &taskpb.ListTasksResponse{}
+	for _, kv := range page.Kvs {
+		var task models.Task
+		if err := json.Unmarshal(kv.Value, &task); err != nil {
+			return nil, grpcError(err)
+		}
+		resp.Tasks = append(resp.Tasks, toProto(task, kv.ModRevision))
+	}
+	if page.Next != "" {
+		data, _ := json.Marshal(pageToken{After: page.Next, Revision: page.Revision})
+		resp.NextPageToken = base64.RawURLEncoding.EncodeToString(data)
+	}
+	return resp, nil
+}
+
+// CreateTask creates a task with a generated ID.
+func (s *Server) CreateTask(ctx context.Context, req *taskpb.CreateTaskRequest) (*taskpb.Task, error) {
+	h := s.handler1
+	task := fromProto(req.GetTask())
+	task.ID = ""
+	task.CreatedAt, task.UpdatedAt = time.Time{}, time.Time{}
+
+	// Validate the task, assign it a unique ID and prepare storing it with its audit entry
+	m, err := h.PrepareCreate(ctx, task, auditMeta(ctx))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	rev, err := h.Commit(ctx, m)
+	h.Release(ctx, err == nil, m)
+	if errors.Is(err, models.ErrConflict) {
+		err = models.ErrExists
+	}
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	created := *m.After
+	if created.ExpiresAt != nil {
+		created.TTL = int64(time.Until(*created.ExpiresAt).Round(time.Second) / time.Second)
+	}
+	return toProto(created, rev), nil
+}
+
+// UpdateTask changes the fields of a task named in the update mask.
+func (s *Server) UpdateTask(ctx context.Context, req *taskpb.UpdateTaskRequest) (*taskpb.Task, error) {
+	h := s.handler2
+	id := req.GetTask().GetId()
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "Task ID is required")
+	}
+	current, kv, err := h.GetTaskKV(ctx, id)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if req.GetRevision() != 0 && req.GetRevision() != kv.ModRevision {
+		return nil, status.Error(codes.FailedPrecondition, "Task was modified since the given revision")
+	}
+
+	update, err := updateReq(current, fromProto(req.GetTask()), req.GetUpdateMask().GetPaths())
+	if err != nil {
+		return nil, err
+	}
+	m, err := h.PrepareUpdate(ctx, id, update, auditMeta(ctx))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	// The fields left out of the mask were taken from the task as read above
+	if m.Prev().ModRevision != kv.ModRevision {
+		h.Release(ctx, false, m)
+		return nil, grpcError(models.ErrConflict)
+	}
+	rev, err := h.Commit(ctx, m)
+	h.Release(ctx, err == nil, m)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return toProto(*m.After, rev), nil
+}
+
+// updatableFields lists the fields of Task that UpdateTask changes when the
+// update mask is empty.
+var updatableFields = []string{"title", "description", "completed", "priority", "tags", "due", "assignee", "recurrence"}
+
+// updateReq builds the update request setting the masked fields of the current
+// task to those of the given one.
+func updateReq(current *models.Task, t models.Task, paths []string) (models.UpdateReq, error) {
+	req := models.UpdateReq{Title: current.Title}
+	if len(paths) == 0 {
+		paths = updatableFields
+	}
+	for _, path := range paths {
+		switch path {
+		case "title":
+			req.Title = t.Title
+		case "description":
+			req.Description = &t.Description
+		case "completed":
+			req.Completed = &t.Completed
+		case "priority":
+			req.Priority = &t.Priority
+		case "tags":
+			tags := t.Tags
+			if tags == nil {
+				tags = []string{}
+			}
+			req.Tags = &tags
+		case "due":
+			req.Due = t.Due
+			req.ClearDue = t.Due == nil
+		case "assignee":
+			req.Assignee = &t.Assignee
+		case "recurrence":
+			req.Recurrence = &t.Recurrence
+		case "ttl":
+			req.TTL = &t.TTL
+		case "expires_at":
+			req.ExpiresAt = t.ExpiresAt
+		default:
+			return req, status.Errorf(codes.InvalidArgument, "Unknown field %q in update mask", path)
+		}
+	}
+	return req, nil
+}
+
+// DeleteTask moves a task to the trash.
+func (s *Server) DeleteTask(ctx context.Context, req *taskpb.DeleteTaskRequest) (*taskpb.DeleteTaskResponse, error) {
+	h := s.handler2
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "ID is required")
+	}
+
+	// The trash lease purges the task once the retention period is over
+	lease, err := h.GrantTrashLease(ctx)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	m, err := h.PrepareDelete(ctx, req.GetId(), lease, auditMeta(ctx))
+	if err == nil && req.GetRevision() != 0 && m.Prev().ModRevision != req.GetRevision() {
+		h.Client.Revoke(ctx, lease)
+		return nil, status.Error(codes.FailedPrecondition, "Task was modified since the given revision")
+	}
+	if err == nil {
+		err = h.Apply(ctx, m)
+	}
+	if err != nil {
+		h.Client.Revoke(ctx, lease)
+		return nil, grpcError(err)
+	}
+	return &taskpb.DeleteTaskResponse{}, nil
+}
+
+// matchExpr parses a filter expression into a match function, nil if it is empty.
+func matchExpr(expr string) (func(models.Task) bool, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	f, err := query.Parse(expr)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return f.Match, nil
+}
+
+// auditMeta identifies the actor and request behind a mutation for the audit log.
+// The actor is taken from the x-actor metadata and the request ID from
+// x-request-id; a request ID is generated and sent back when the client did
+// not send one.
+func auditMeta(ctx context.Context) models.AuditMeta {
+	meta := models.AuditMeta{Actor: "anonymous"}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("x-actor"); len(v) > 0 && v[0] != "" {
+			meta.Actor = v[0]
+		}
+		if v := md.Get("x-request-id"); len(v) > 0 {
+			meta.RequestID = v[0]
+		}
+	}
+	if meta.RequestID == "" {
+		meta.RequestID = models.GenerateUniqueID()
+		grpc.SetHeader(ctx, metadata.Pairs("x-request-id", meta.RequestID))
+	}
+	return meta
+}
+
+// grpcError maps an error returned by the models package to a gRPC status,
+// as errorStatus does to HTTP statuses for the REST handlers.
+func grpcError(err error) error {
+	var validationErr *models.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		st := status.New(codes.InvalidArgument, err.Error())
+		if len(validationErr.Fields) > 0 {
+			details := &errdetails.BadRequest{}
+			for _, f := range validationErr.Fields {
+				details.FieldViolations = append(details.FieldViolations,
+					&errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Message})
+			}
+			if withDetails, err := st.WithDetails(details); err == nil {
+				st = withDetails
+			}
+		}
+		return st.Err()
+	case errors.Is(err, models.ErrNotFound), errors.Is(err, models.ErrFilterNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, models.ErrExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, models.ErrConflict):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
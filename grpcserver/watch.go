@@ -0,0 +1,59 @@
+package grpcserver
+
+import (
+	"errors"
+	"task-organizer/models"
+	"task-organizer/taskpb"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// eventTypes maps the kinds of task changes to event types.
+var eventTypes = map[string]taskpb.TaskEvent_Type{
+	models.ChangeCreated: taskpb.TaskEvent_TYPE_CREATED,
+	models.ChangeUpdated: taskpb.TaskEvent_TYPE_UPDATED,
+	models.ChangeDeleted: taskpb.TaskEvent_TYPE_DELETED,
+}
+
+// errSend marks a failure to send an event, as opposed to one of the watch.
+type errSend struct{ err error }
+
+func (e errSend) Error() string { return e.err.Error() }
+
+// WatchTasks streams changes to the tasks matching the request as they
+// happen. A task that starts or stops matching is reported as created or
+// deleted, so that the stream mirrors the matching set.
+func (s *Server) WatchTasks(req *taskpb.WatchTasksRequest, stream taskpb.TaskService_WatchTasksServer) error {
+	filter := models.TaskFilter{Completed: req.Completed, Tag: req.GetTag(), Assignee: req.GetAssignee()}
+	expr, err := matchExpr(req.GetFilter())
+	if err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	err = s.handler1.WatchTasks(ctx, filter, expr, req.GetStartRevision(), func(change models.TaskChange) error {
+		out := &taskpb.TaskEvent{
+			Type:     eventTypes[change.Type],
+			Task:     toProto(change.Task, change.TaskRevision),
+			Revision: change.Revision,
+		}
+		if err := stream.Send(out); err != nil {
+			return errSend{err}
+		}
+		return nil
+	})
+
+	var sendErr errSend
+	switch {
+	case errors.As(err, &sendErr):
+		return sendErr.err
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	case errors.Is(err, rpctypes.ErrCompacted):
+		return status.Error(codes.OutOfRange, "Start revision has been compacted")
+	default:
+		return status.Error(codes.Unavailable, err.Error())
+	}
+}
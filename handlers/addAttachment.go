@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"task-organizer/blob"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead is the room left in upload bodies for the multipart framing around the file.
+const multipartOverhead = 64 << 10
+
+// AddAttachment godoc
+// @Summary Attach a file to a task
+// @Description Uploads a file as the "file" field of a multipart form and attaches it to the task with the specified ID.
+// @Description Files are limited in size and media type; identical contents are stored once
+// @Tags Attachments
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param file formData file true "File to attach"
+// @Param X-Actor header string false "Uploader of the file"
+// @Success 201 {object} models.Attachment
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 413 {object} nil
+// @Failure 500 {object} nil
+// @Router /tasks/{id}/attachments [post]
+func AddAttachment(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	blobs, ok := c.Get("blobs")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blob store"})
+		return
+	}
+
+	store, ok := blobs.(blob.Store)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid blob store type"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, models.MaxAttachmentSize()+multipartOverhead)
+	form, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Request must be a multipart form"})
+		return
+	}
+
+	// Stream the file part to the blob store without buffering the form
+	for {
+		part, err := form.NextPart()
+		if errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "File is required", "fields": []models.FieldError{{Field: "file", Message: "File is required"}}})
+			return
+		}
+		if err != nil {
+			c.JSON(errorStatus(err), bodyError(err))
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+
+		name := part.FileName()
+		if name == "" {
+			name = "attachment"
+		}
+		taskID := c.Param("id")
+		attachment, err := h.AddAttachment(ctx, store, taskID, name, part.Header.Get("Content-Type"), auditMeta(c).Actor, part)
+		if err != nil {
+			c.JSON(errorStatus(err), bodyError(err))
+			return
+		}
+
+		c.Header("Location", "/tasks/"+taskID+"/attachments/"+attachment.ID)
+		c.JSON(http.StatusCreated, attachment)
+		return
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AddComment godoc
+// @Summary Comment on a task
+// @Description Posts a comment on the task with the specified ID. The author is taken from the X-Actor header and @name mentions are collected from the body
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param comment body models.CommentReq true "Comment to post"
+// @Param X-Actor header string false "Author of the comment"
+// @Success 201 {object} models.Comment
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 413 {object} nil
+// @Failure 500 {object} nil
+// @Router /tasks/{id}/comments [post]
+func AddComment(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	var commentReq models.CommentReq
+	if err := bindJSON(c, &commentReq); err != nil {
+		c.JSON(errorStatus(err), bodyError(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	taskID := c.Param("id")
+	comment, err := h.AddComment(ctx, taskID, auditMeta(c).Actor, commentReq)
+	if err != nil {
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	c.Header("Location", "/tasks/"+taskID+"/comments/"+comment.ID)
+	c.JSON(http.StatusCreated, comment)
+}
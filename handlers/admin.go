@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminToken returns the token admin endpoints require, read from the
+// ADMIN_TOKEN environment variable. Admin endpoints are disabled when it is unset.
+func AdminToken() string {
+	return os.Getenv("ADMIN_TOKEN")
+}
+
+// RequireAdmin returns a middleware letting through only the requests that
+// carry token as a bearer token in their Authorization header. With an empty
+// token, every request is refused.
+func RequireAdmin(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin endpoints are disabled; set ADMIN_TOKEN to enable them"})
+			return
+		}
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin token"})
+			return
+		}
+		c.Next()
+	}
+}
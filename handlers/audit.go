@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"task-organizer/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditMeta identifies the actor and request behind a mutation for the audit log.
+// The actor is taken from the X-Actor header and the request ID from X-Request-ID;
+// a request ID is generated and echoed back when the client did not send one.
+func auditMeta(c *gin.Context) models.AuditMeta {
+	actor := c.GetHeader("X-Actor")
+	if actor == "" {
+		actor = "anonymous"
+	}
+
+	requestID := c.GetHeader("X-Request-ID")
+	if requestID == "" {
+		requestID = c.Writer.Header().Get("X-Request-ID")
+	}
+	if requestID == "" {
+		requestID = models.GenerateUniqueID()
+		c.Header("X-Request-ID", requestID)
+	}
+
+	return models.AuditMeta{Actor: actor, RequestID: requestID}
+}
+
+// newAuditEntry builds an audit entry for a task mutation performed by the current request.
+func newAuditEntry(c *gin.Context, action string, before, after *models.Task) (*models.AuditEntry, error) {
+	meta := auditMeta(c)
+	return models.NewAuditEntry(action, meta.Actor, meta.RequestID, before, after)
+}
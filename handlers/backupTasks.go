@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"task-organizer/backup"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BackupTasks godoc
+// @Summary Back up the task keyspace
+// @Description Takes a consistent snapshot of the tasks, trash, audit log and saved filters at a single etcd revision
+// @Description and returns it as a versioned, gzip-compressed tar archive with a SHA-256 checksum.
+// @Tags Admin
+// @Produce octet-stream
+// @Param Authorization header string true "Bearer admin token"
+// @Success 200 {file} file
+// @Failure 401 {object} nil
+// @Failure 403 {object} nil
+// @Failure 500 {object} nil
+// @Router /admin/backup [get]
+func BackupTasks(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var archive bytes.Buffer
+	manifest, err := backup.Write(ctx, h, &archive)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to back up tasks: " + err.Error()})
+		return
+	}
+
+	rev := strconv.FormatInt(manifest.Revision, 10)
+	c.Header("Content-Disposition", `attachment; filename="tasks-backup-`+rev+`.tar.gz"`)
+	c.Header("X-Backup-Revision", rev)
+	c.Header("X-Backup-Checksum", manifest.Checksum)
+	c.Data(http.StatusOK, "application/gzip", archive.Bytes())
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// BatchTasks godoc
+// @Summary Create, update and delete tasks in bulk
+// @Description Applies up to 100 mixed create/update/delete operations.
+// @Description With atomic set, they are applied in a single etcd transaction, either all or none, and batches not fitting in one are rejected with 400;
+// @Description otherwise they are split in transactions respecting the transaction size limit and each operation succeeds or fails on its own.
+// @Tags Tasks
+// @Accept json
+// @Produce json
+// @Param batch body models.BatchReq true "Operations to apply"
+// @Param X-Actor header string false "Actor recorded in the audit log"
+// @Success 200 {object} models.BatchResp
+// @Failure 400 {object} nil
+// @Failure 413 {object} nil
+// @Failure 409 {object} models.BatchResp
+// @Failure 500 {object} nil
+// @Router /tasks:batch [post]
+func BatchTasks(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	var batchReq models.BatchReq
+	if err := bindJSON(c, &batchReq); err != nil {
+		c.JSON(errorStatus(err), bodyError(err))
+		return
+	}
+	if len(batchReq.Operations) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Operations cannot be empty"})
+		return
+	}
+	if len(batchReq.Operations) > models.MaxBatchOperations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A batch cannot have more than %d operations", models.MaxBatchOperations)})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	meta := auditMeta(c)
+	results := make([]models.BatchResult, len(batchReq.Operations))
+	muts := make([]*models.Mutation, len(batchReq.Operations))
+	seen := map[string]bool{}
+
+	// Deleted tasks share one trash lease, granted on first use
+	var trashLease clientv3.LeaseID
+
+	// Created tasks without a position are appended in the order of the operations
+	var unpositioned []*models.Task
+	for _, op := range batchReq.Operations {
+		if op.Op == models.BatchCreate && op.Task != nil && op.Task.Position == "" {
+			unpositioned = append(unpositioned, op.Task)
+		}
+	}
+	positions, err := h.AppendPositions(ctx, len(unpositioned))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task positions"})
+		return
+	}
+	for i, task := range unpositioned {
+		task.Position = positions[i]
+	}
+
+	// Validate and prepare every operation before committing any of them
+	failed := false
+	for i, op := range batchReq.Operations {
+		results[i] = models.BatchResult{Index: i, Op: op.Op, ID: op.ID}
+
+		var m *models.Mutation
+		var err error
+		switch op.Op {
+		case models.BatchCreate:
+			if op.Task == nil {
+				err = &models.ValidationError{Msg: "Task is required for create"}
+				break
+			}
+			m, err = h.PrepareCreate(ctx, *op.Task, meta)
+		case models.BatchUpdate:
+			switch {
+			case op.Update == nil:
+				err = &models.ValidationError{Msg: "Update is required for update"}
+			case seen[op.ID]:
+				err = &models.ValidationError{Msg: "Task appears more than once in the batch"}
+			default:
+				m, err = h.PrepareUpdate(ctx, op.ID, *op.Update, meta)
+			}
+		case models.BatchDelete:
+			if seen[op.ID] {
+				err = &models.ValidationError{Msg: "Task appears more than once in the batch"}
+				break
+			}
+			if trashLease == 0 {
+				if trashLease, err = h.GrantTrashLease(ctx); err != nil {
+					break
+				}
+			}
+			m, err = h.PrepareDelete(ctx, op.ID, trashLease, meta)
+		default:
+			err = &models.ValidationError{Msg: "Unknown operation " + op.Op}
+		}
+		if op.ID != "" {
+			seen[op.ID] = true
+		}
+
+		if err != nil {
+			failed = true
+			results[i].Status = errorStatus(err)
+			results[i].Error = err.Error()
+			results[i].Fields = models.ErrorFields(err)
+			continue
+		}
+		muts[i] = m
+	}
+
+	// In atomic mode a single invalid operation cancels the whole batch
+	var prepared []*models.Mutation
+	var preparedIdx []int
+	for i, m := range muts {
+		if m == nil {
+			continue
+		}
+		if batchReq.Atomic && failed {
+			h.Release(ctx, false, m)
+			results[i].Status = errorStatus(models.ErrNotExecuted)
+			results[i].Error = models.ErrNotExecuted.Error()
+			continue
+		}
+		prepared = append(prepared, m)
+		preparedIdx = append(preparedIdx, i)
+	}
+
+	// An atomic batch is committed in a single transaction, which it must fit in
+	if batchReq.Atomic {
+		if err := models.CheckAtomic(prepared); err != nil {
+			h.Release(ctx, false, prepared...)
+			if trashLease != 0 {
+				h.Client.Revoke(ctx, trashLease)
+			}
+			c.JSON(errorStatus(err), errorBody(err))
+			return
+		}
+	}
+
+	// Commit the prepared operations and collect their outcome
+	deleted := false
+	errs := h.ApplyBatch(ctx, prepared, batchReq.Atomic)
+	for n, err := range errs {
+		i := preparedIdx[n]
+		m := prepared[n]
+		if err != nil {
+			results[i].Status = errorStatus(err)
+			results[i].Error = err.Error()
+			continue
+		}
+
+		results[i].Status = http.StatusOK
+		if m.Before == nil {
+			results[i].Status = http.StatusCreated
+		}
+		if m.After != nil {
+			results[i].ID = m.After.ID
+			results[i].Task = m.After
+		} else {
+			deleted = true
+		}
+	}
+	if !deleted && trashLease != 0 {
+		h.Client.Revoke(ctx, trashLease)
+	}
+
+	resp := models.BatchResp{Results: results}
+	status := http.StatusOK
+	for _, result := range results {
+		if result.Error != "" {
+			resp.Failed++
+			if batchReq.Atomic && status == http.StatusOK && result.Status != http.StatusFailedDependency {
+				status = result.Status
+			}
+			continue
+		}
+		resp.Succeeded++
+	}
+
+	c.JSON(status, resp)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"task-organizer/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Request body limits used when MAX_BODY_SIZE and MAX_IMPORT_SIZE are not set, in bytes.
+const (
+	DefaultMaxBodySize   = 1 << 20
+	DefaultMaxImportSize = 32 << 20
+)
+
+// MaxBodySize returns the maximum size of a request body, in bytes. It is read
+// from the MAX_BODY_SIZE environment variable, falling back to
+// DefaultMaxBodySize when unset or invalid.
+func MaxBodySize() int64 {
+	return sizeFromEnv("MAX_BODY_SIZE", DefaultMaxBodySize)
+}
+
+// MaxImportSize returns the maximum size of the body of imports and backup
+// restores, in bytes. It is read from the MAX_IMPORT_SIZE environment
+// variable, falling back to DefaultMaxImportSize when unset or invalid.
+func MaxImportSize() int64 {
+	return sizeFromEnv("MAX_IMPORT_SIZE", DefaultMaxImportSize)
+}
+
+func sizeFromEnv(name string, def int64) int64 {
+	if v := os.Getenv(name); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return def
+}
+
+// readBody reads a request body of at most n bytes. Larger bodies fail with
+// an *http.MaxBytesError, reported by errorStatus as 413.
+func readBody(c *gin.Context, n int64) ([]byte, error) {
+	return io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, n))
+}
+
+// bindJSON strictly decodes a JSON request body of at most MaxBodySize bytes
+// into v, rejecting unknown fields and duplicate keys.
+func bindJSON(c *gin.Context, v interface{}) error {
+	data, err := readBody(c, MaxBodySize())
+	if err != nil {
+		return err
+	}
+	return models.DecodeStrict(data, v)
+}
+
+// bodyError describes a request body that could not be read or decoded.
+func bodyError(err error) gin.H {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return gin.H{"error": "Request body too large", "limit": maxErr.Limit}
+	}
+	return errorBody(err)
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"net/http"
+	"task-organizer/caldav"
+
+	"github.com/gin-gonic/gin"
+)
+
+// writeMultistatus sends a 207 Multi-Status response.
+func writeMultistatus(c *gin.Context, ms caldav.Multistatus) {
+	data, err := ms.Marshal()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusMultiStatus, "application/xml; charset=utf-8", data)
+}
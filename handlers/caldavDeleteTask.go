@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/caldav"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CalDAVDeleteTask godoc
+// @Summary Delete a task through CalDAV
+// @Description Moves the task with the specified ID to the trash. If-Match is honored.
+// @Tags CalDAV
+// @Param file path string true "Task ID followed by .ics"
+// @Param If-Match header string false "Only delete the task if its ETag matches"
+// @Param X-Actor header string false "Actor recorded in the audit log"
+// @Success 204 {object} nil
+// @Failure 404 {object} nil
+// @Failure 412 {object} nil
+// @Failure 500 {object} nil
+// @Router /caldav/tasks/{file} [delete]
+func CalDAVDeleteTask(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	id, ok := caldav.TaskID(c.Request.URL.EscapedPath())
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, kv, err := h.GetTaskKV(ctx, id)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if !precondition(c, kv) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Precondition failed"})
+		return
+	}
+
+	// The trash lease purges the task once the retention period is over
+	lease, err := h.GrantTrashLease(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	m, err := models.NewDeleteMutation(kv, lease, auditMeta(c))
+	if err == nil {
+		err = h.Apply(ctx, m)
+	}
+	if err != nil {
+		h.Client.Revoke(ctx, lease)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
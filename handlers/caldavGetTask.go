@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/caldav"
+	"task-organizer/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CalDAVGetTask godoc
+// @Summary Get a task as an iCalendar object
+// @Description Retrieves the task with the specified ID as an iCalendar object holding a single VTODO, with its ETag
+// @Tags CalDAV
+// @Produce plain
+// @Param file path string true "Task ID followed by .ics"
+// @Success 200 {string} string
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Router /caldav/tasks/{file} [get]
+func CalDAVGetTask(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	id, ok := caldav.TaskID(c.Request.URL.EscapedPath())
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+
+	task, kv, err := h.GetTaskKV(context.Background(), id)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := caldav.CalendarData(*task)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", models.ETag(kv.ModRevision))
+	c.Data(http.StatusOK, caldav.ContentType, []byte(data))
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CalDAVOptions godoc
+// @Summary Describe the CalDAV capabilities
+// @Description Advertises the WebDAV and CalDAV features and the methods supported on the CalDAV resources
+// @Tags CalDAV
+// @Success 200 {object} nil
+// @Router /caldav/tasks/ [options]
+func CalDAVOptions(c *gin.Context) {
+	c.Header("DAV", "1, 3, calendar-access")
+	c.Header("Allow", "OPTIONS, GET, PUT, DELETE, PROPFIND, REPORT")
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"task-organizer/caldav"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// CalDAVPropfind lists the properties of the CalDAV root, the calendar
+// collection or a task resource (PROPFIND, RFC 4918). With a Depth other than
+// 0 the members of a collection are listed too. The requested properties are
+// not inspected; all known ones are returned.
+func CalDAVPropfind(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+	members := c.GetHeader("Depth") != "0"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	path := c.Request.URL.Path
+	switch path {
+	case caldav.RootPath, caldav.CalendarPath:
+		// The store revision serves as ctag: it changes whenever a task does
+		opts := []clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithCountOnly()}
+		if path == caldav.CalendarPath && members {
+			opts = []clientv3.OpOption{clientv3.WithPrefix()}
+		}
+		resp, err := h.Client.Get(ctx, models.TaskPrefix, opts...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+			return
+		}
+		calendar := caldav.OK(caldav.CalendarPath, caldav.Calendar(resp.Header.Revision))
+
+		if path == caldav.RootPath {
+			ms := caldav.NewMultistatus(caldav.OK(caldav.RootPath, caldav.Principal()))
+			if members {
+				ms.Responses = append(ms.Responses, calendar)
+			}
+			writeMultistatus(c, ms)
+			return
+		}
+
+		ms := caldav.NewMultistatus(calendar)
+		for _, kv := range resp.Kvs {
+			ms.Responses = append(ms.Responses, taskResponse(kv, ""))
+		}
+		writeMultistatus(c, ms)
+	default:
+		id, ok := caldav.TaskID(c.Request.URL.EscapedPath())
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+			return
+		}
+		_, kv, err := h.GetTaskKV(ctx, id)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		writeMultistatus(c, caldav.NewMultistatus(taskResponse(kv, "")))
+	}
+}
+
+// taskResponse describes the resource of a stored task, with its calendar data if given.
+func taskResponse(kv *mvccpb.KeyValue, data string) caldav.Response {
+	id := strings.TrimPrefix(string(kv.Key), models.TaskPrefix)
+	return caldav.OK(caldav.TaskHref(id), caldav.TaskProp(kv.ModRevision, data))
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"task-organizer/caldav"
+	"task-organizer/models"
+	"task-organizer/transfer"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CalDAVPutTask godoc
+// @Summary Create or replace a task from an iCalendar object
+// @Description Stores the single VTODO of an iCalendar object as the task with the specified ID. Fields that iCalendar
+// @Description does not carry, such as the assignee, are kept when a task is replaced. If-Match and If-None-Match are honored.
+// @Tags CalDAV
+// @Accept plain
+// @Param file path string true "Task ID followed by .ics"
+// @Param task body string true "iCalendar object with a single VTODO"
+// @Param If-Match header string false "Only replace the task if its ETag matches"
+// @Param If-None-Match header string false "Use * to only create the task if it does not exist"
+// @Param X-Actor header string false "Actor recorded in the audit log"
+// @Success 201 {object} nil
+// @Success 204 {object} nil
+// @Failure 400 {object} nil
+// @Failure 413 {object} nil
+// @Failure 412 {object} nil
+// @Failure 500 {object} nil
+// @Router /caldav/tasks/{file} [put]
+func CalDAVPutTask(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	id, ok := caldav.TaskID(c.Request.URL.EscapedPath())
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+		return
+	}
+
+	// The body must hold exactly one VTODO, identified by the resource name
+	format, _ := transfer.Lookup("ics")
+	data, err := readBody(c, MaxBodySize())
+	if err != nil {
+		c.JSON(errorStatus(err), bodyError(err))
+		return
+	}
+	rows, err := format.Decode(bytes.NewReader(data))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid iCalendar object: " + err.Error()})
+		return
+	}
+	if len(rows) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "iCalendar object must hold exactly one VTODO"})
+		return
+	}
+	if rows[0].Err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": rows[0].Err.Error()})
+		return
+	}
+	todo := rows[0].Task
+	if todo.ID != "" && todo.ID != id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "UID must match the resource name"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	existing, kv, err := h.GetTaskKV(ctx, id)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !precondition(c, kv) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Precondition failed"})
+		return
+	}
+
+	// Replace the fields iCalendar carries and keep the others of a stored task
+	task := todo
+	task.ID = id
+	if existing != nil {
+		task = *existing
+		task.Title = todo.Title
+		task.Description = todo.Description
+		task.Completed = todo.Completed
+		task.Priority = todo.Priority
+		task.Tags = todo.Tags
+		task.Due = todo.Due
+		task.Recurrence = todo.Recurrence
+	}
+	task.UpdatedAt = time.Now().UTC()
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = task.UpdatedAt
+	}
+
+	m, err := h.PrepareImport(ctx, task, kv, auditMeta(c))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	rev, err := h.Commit(ctx, m)
+	h.Release(ctx, err == nil, m)
+	if errors.Is(err, models.ErrConflict) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Task was modified concurrently"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", models.ETag(rev))
+	if existing == nil {
+		c.Status(http.StatusCreated)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
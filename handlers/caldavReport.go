@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"task-organizer/caldav"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// CalDAVReport answers calendar-query and calendar-multiget reports on the
+// calendar collection (REPORT, RFC 4791) with the calendar data of the tasks.
+// A calendar-query returns every task; its filters are not applied.
+func CalDAVReport(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	data, err := readBody(c, MaxBodySize())
+	if err != nil {
+		c.JSON(errorStatus(err), bodyError(err))
+		return
+	}
+	var report caldav.ReportReq
+	if err := xml.Unmarshal(data, &report); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var kvs []*mvccpb.KeyValue
+	var missing []string
+	switch report.XMLName.Local {
+	case caldav.ReportCalendarQuery:
+		resp, err := h.Client.Get(ctx, models.TaskPrefix, clientv3.WithPrefix())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+			return
+		}
+		kvs = resp.Kvs
+	case caldav.ReportCalendarMultiget:
+		// Fetch the requested tasks and report the ones that do not exist
+		var ids []string
+		for _, href := range report.Hrefs {
+			id, ok := caldav.TaskID(strings.TrimSpace(href))
+			if !ok {
+				missing = append(missing, href)
+				continue
+			}
+			ids = append(ids, id)
+		}
+		var err error
+		if kvs, err = h.GetTaskKVs(ctx, ids); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+			return
+		}
+		found := map[string]bool{}
+		for _, kv := range kvs {
+			found[strings.TrimPrefix(string(kv.Key), models.TaskPrefix)] = true
+		}
+		for _, id := range ids {
+			if !found[id] {
+				missing = append(missing, caldav.TaskHref(id))
+			}
+		}
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unsupported report " + report.XMLName.Local})
+		return
+	}
+
+	ms := caldav.NewMultistatus()
+	for _, kv := range kvs {
+		var task models.Task
+		if err := json.Unmarshal(kv.Value, &task); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse tasks"})
+			return
+		}
+		data, err := caldav.CalendarData(task)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ms.Responses = append(ms.Responses, taskResponse(kv, data))
+	}
+	for _, href := range missing {
+		ms.Responses = append(ms.Responses, caldav.Response{Href: href, Status: caldav.StatusNotFound})
+	}
+
+	writeMultistatus(c, ms)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateLabel godoc
+// @Summary Create a label
+// @Description Creates a label tasks can reference by its ID. Names are unique regardless of case
+// @Tags Labels
+// @Accept json
+// @Produce json
+// @Param label body models.LabelReq true "Label to create"
+// @Success 201 {object} models.Label
+// @Failure 400 {object} nil
+// @Failure 409 {object} nil
+// @Failure 413 {object} nil
+// @Failure 500 {object} nil
+// @Router /labels [post]
+func CreateLabel(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	var labelReq models.LabelReq
+	if err := bindJSON(c, &labelReq); err != nil {
+		c.JSON(errorStatus(err), bodyError(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	label, err := h.CreateLabel(ctx, labelReq)
+	if err != nil {
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	c.Header("Location", "/labels/"+label.ID)
+	c.JSON(http.StatusCreated, label)
+}
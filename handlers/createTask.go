@@ -12,14 +12,21 @@ import (
 
 // CreateTask godoc
 // @Summary Create a new task
-// @Description Creates a new task with a unique ID if not provided
+// @Description Creates a new task with a unique ID if not provided. A ttl (seconds) or expires_at makes the task expire automatically
+// @Description Unknown fields, duplicate keys and text fields over their length limits are rejected, with the invalid fields listed in the error
+// @Description Retries sent by the same client, told apart by API key or else IP address, with the same Idempotency-Key and body get the stored response of the first request; reusing the key with a different body fails with 422
 // @Tags Tasks
 // @Accept json
 // @Produce json
 // @Param task body models.Task true "Task object to be created"
+// @Param X-Actor header string false "Actor recorded in the audit log"
+// @Param Idempotency-Key header string false "Key making retries of the request return the first response instead of creating another task"
 // @Success 201 {object} models.Task
 // @Failure 500 {object} nil
 // @Failure 400 {object} nil
+// @Failure 413 {object} nil
+// @Failure 409 {object} nil
+// @Failure 422 {object} nil
 // @Router /tasks [post]
 func CreateTask(c *gin.Context) {
 	client, ok := c.Get("handler")
@@ -34,40 +41,85 @@ func CreateTask(c *gin.Context) {
 		return
 	}
 
-	var task models.Task
-	if err := c.BindJSON(&task); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	data, err := readBody(c, MaxBodySize())
+	if err != nil {
+		c.JSON(errorStatus(err), bodyError(err))
 		return
 	}
-
-	// Check if the request contains an ID
-	if task.ID != "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Manual ID entry is not allowed"}) // Reject request with manual ID entry
-		return
+	key := c.GetHeader("Idempotency-Key")
+	if key != "" {
+		if err := models.ValidateIdempotencyKey(key); err != nil {
+			c.JSON(errorStatus(err), errorBody(err))
+			return
+		}
 	}
+	fingerprint := models.Fingerprint(data)
 
-	// Generate a unique ID using the GenerateUniqueID function
-	task.ID = models.GenerateUniqueID()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// A retried request from the same client gets the response to the first one
+	scope := idempotencyScope(c, createTaskScope)
+	if key != "" {
+		stored, err := h.GetIdempotentResponse(ctx, scope, key)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch idempotent response"})
+			return
+		}
+		if stored != nil {
+			replay(c, stored, fingerprint)
+			return
+		}
+	}
 
-	// Check if the Title is empty
-	if task.Title == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Title cannot be empty"}) // Reject request with empty title
+	var task models.Task
+	if err := models.DecodeStrict(data, &task); err != nil {
+		c.JSON(errorStatus(err), errorBody(err))
 		return
 	}
 
-	data, err := json.Marshal(task)
+	// Validate the task, assign it a unique ID and prepare storing it with its audit entry
+	m, err := h.PrepareCreate(ctx, task, auditMeta(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), errorBody(err))
 		return
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
 
-	// Store the task in the database with the generated ID
-	_, err = h.Client.Put(ctx, "tasks/"+task.ID, string(data))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	task = *m.After
+	if task.ExpiresAt != nil {
+		task.TTL = int64(time.Until(*task.ExpiresAt).Round(time.Second) / time.Second)
+	}
+
+	// Store the response together with the task, so that retries return it
+	if key != "" {
+		body, err := json.Marshal(task)
+		if err == nil {
+			err = h.Remember(ctx, m, scope, key, models.IdempotentResponse{
+				Fingerprint: fingerprint,
+				Status:      http.StatusCreated,
+				Body:        body,
+				CreatedAt:   task.CreatedAt,
+			})
+		}
+		if err != nil {
+			h.Release(ctx, false, m)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store idempotent response"})
+			return
+		}
+	}
+
+	// Store the task and its audit entry in the database in a single transaction
+	if err := h.Apply(ctx, m); err != nil {
+		// A concurrent request with the same Idempotency-Key may have been served first
+		if key != "" {
+			if stored, _ := h.GetIdempotentResponse(ctx, scope, key); stored != nil {
+				replay(c, stored, fingerprint)
+				return
+			}
+		}
+		c.JSON(errorStatus(err), errorBody(err))
 		return
 	}
+
 	c.JSON(http.StatusCreated, task)
 }
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"task-organizer/models"
 
@@ -11,11 +12,13 @@ import (
 
 // DeleteAllTasks godoc
 // @Summary Deletes All Tasks
-// @Description Deletes All Data
+// @Description Moves all tasks to the trash with their comments and attachments
 // @Tags Tasks
 // @Accept json
 // @Produce json
+// @Param X-Actor header string false "Actor recorded in the audit log"
 // @Success 201 {object} models.Task
+// @Failure 409 {object} nil
 // @Failure 500 {object} nil
 // @Router /tasks/ [delete]
 func DeleteAllTasks(c *gin.Context) {
@@ -32,27 +35,55 @@ func DeleteAllTasks(c *gin.Context) {
 		return
 	}
 
-	// Get all keys with the "tasks/" prefix from the database
-	resp, err := h.Client.Get(context.Background(), "tasks/", clientv3.WithPrefix())
+	// Get all tasks
+	ctx := context.Background()
+	resp, err := h.Client.Get(ctx, models.TaskPrefix, clientv3.WithPrefix())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
 		return
 	}
+	kvs := resp.Kvs
 
 	// Check if there are any tasks to delete
-	if len(resp.Kvs) == 0 {
+	if len(kvs) == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"message": "No tasks to delete"})
 		return
 	}
 
-	// Delete all tasks one by one
-	for _, kv := range resp.Kvs {
-		_, err := h.Client.Delete(context.Background(), string(kv.Key))
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tasks"})
-			return
+	// All trashed tasks share one lease which purges them once the retention period is over
+	lease, err := h.GrantTrashLease(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tasks"})
+		return
+	}
+
+	// Move all tasks to the trash one by one, each in a transaction with its own audit entry
+	meta := auditMeta(c)
+	moved := 0
+	for _, kv := range kvs {
+		var m *models.Mutation
+		if m, err = models.NewDeleteMutation(kv, lease, meta); err != nil {
+			break
 		}
+		if err = h.Apply(ctx, m); err != nil {
+			break
+		}
+		moved++
+	}
+
+	// The lease is only kept if a task was moved under it
+	if moved == 0 {
+		h.Client.Revoke(ctx, lease)
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "All tasks deleted"})
+	switch {
+	case errors.Is(err, models.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "Tasks were modified concurrently"})
+	case errors.Is(err, models.ErrInTrash):
+		c.JSON(http.StatusConflict, errorBody(err))
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tasks"})
+	default:
+		c.JSON(http.StatusCreated, gin.H{"message": "All tasks moved to trash"})
+	}
 }
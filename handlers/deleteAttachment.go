@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/blob"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeleteAttachment godoc
+// @Summary Delete an attachment of a task
+// @Description Deletes a file attached to a task. Its content is deleted too unless another attachment has the same
+// @Tags Attachments
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param attachmentId path string true "Attachment ID"
+// @Success 200 {object} nil
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Router /tasks/{id}/attachments/{attachmentId} [delete]
+func DeleteAttachment(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	blobs, ok := c.Get("blobs")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blob store"})
+		return
+	}
+
+	store, ok := blobs.(blob.Store)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid blob store type"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := h.DeleteAttachment(ctx, store, c.Param("id"), c.Param("attachmentId")); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted"})
+}
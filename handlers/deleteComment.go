@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeleteComment godoc
+// @Summary Delete a comment of a task
+// @Description Deletes a comment posted on a task
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param commentId path string true "Comment ID"
+// @Success 200 {object} nil
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Router /tasks/{id}/comments/{commentId} [delete]
+func DeleteComment(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.DeleteComment(ctx, c.Param("id"), c.Param("commentId")); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeleteFilter godoc
+// @Summary Delete a saved filter by name
+// @Description Deletes the saved filter with the specified name
+// @Tags Filters
+// @Accept json
+// @Produce json
+// @Param name path string true "Filter name"
+// @Success 200 {object} nil
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Router /filters/{name} [delete]
+func DeleteFilter(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := h.Client.Delete(ctx, models.FilterPrefix+c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if resp.Deleted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": models.ErrFilterNotFound.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Filter deleted"})
+}
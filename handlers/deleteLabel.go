@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeleteLabel godoc
+// @Summary Delete a label
+// @Description Deletes the label with the specified ID and removes it from all tasks having it, recording the changes in the audit log
+// @Description The change is applied atomically in a single transaction. A label on more tasks than the etcd operation limit
+// @Description allows to relabel at once is refused with 400; remove it from some of the tasks first.
+// @Tags Labels
+// @Accept json
+// @Produce json
+// @Param id path string true "Label ID"
+// @Param X-Actor header string false "Actor recorded in the audit log"
+// @Success 200 {object} nil
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 409 {object} nil
+// @Failure 500 {object} nil
+// @Router /labels/{id} [delete]
+func DeleteLabel(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tasks, err := h.DeleteLabel(ctx, c.Param("id"), auditMeta(c))
+	if err != nil {
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Label deleted", "tasks": tasks})
+}
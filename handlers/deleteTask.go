@@ -11,14 +11,19 @@ import (
 
 // DeleteTask godoc
 // @Summary Delete a task by ID
-// @Description Deletes a task with the specified ID
+// @Description Moves the task with the specified ID to the trash, from where it can be restored until the retention period expires.
+// @Description Its comments and attachments stay with it in the trash
 // @Tags Tasks
 // @Accept json
 // @Produce json
 // @Param id path string true "Task ID" Format(int64)
+// @Param X-Actor header string false "Actor recorded in the audit log"
+// @Param If-Match header string false "Only delete the task if its ETag matches"
 // @Success 200 {object} models.Task
 // @Failure 400 {object} nil
 // @Failure 404 {object} nil
+// @Failure 409 {object} nil
+// @Failure 412 {object} nil
 // @Failure 500 {object} nil
 // @Router /tasks/{id} [delete]
 func DeleteTask(c *gin.Context) {
@@ -35,28 +40,33 @@ func DeleteTask(c *gin.Context) {
 	}
 	taskID := c.Param("id")
 
-	// Check if the task exists before attempting to delete it
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	resp, err := h.Client.Get(ctx, "tasks/"+taskID)
-	cancel()
+	defer cancel()
+
+	// The trash lease purges the task once the retention period is over
+	lease, err := h.GrantTrashLease(ctx)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if len(resp.Kvs) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	// Check if the task exists and prepare moving it to the trash with its audit entry
+	m, err := h.PrepareDelete(ctx, taskID, lease, auditMeta(c))
+	if err == nil && !precondition(c, m.Prev()) {
+		h.Client.Revoke(ctx, lease)
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Precondition failed"})
 		return
 	}
-
-	// Perform the delete operation
-	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
-	_, err = h.Client.Delete(ctx, "tasks/"+taskID)
-	cancel()
+	if err == nil {
+		// Perform the delete operation together with the audit entry,
+		// provided the task was not modified since it was read
+		err = h.Apply(ctx, m)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.Client.Revoke(ctx, lease)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Task deleted"})
+	c.JSON(http.StatusOK, gin.H{"message": "Task moved to trash"})
 }
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeleteWorkflow godoc
+// @Summary Delete a workflow by name
+// @Description Deletes the workflow with the specified name if no task follows it, counting the ones in the trash. Deleting the default workflow brings back the built-in one.
+// @Tags Workflows
+// @Accept json
+// @Produce json
+// @Param name path string true "Workflow name"
+// @Success 200 {object} nil
+// @Failure 404 {object} nil
+// @Failure 409 {object} nil
+// @Failure 500 {object} nil
+// @Router /workflows/{name} [delete]
+func DeleteWorkflow(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.DeleteWorkflow(ctx, c.Param("name")); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Workflow deleted"})
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"task-organizer/backup"
+	"task-organizer/models"
+	"task-organizer/query"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorStatus maps an error returned by the models package to an HTTP status code.
+func errorStatus(err error) int {
+	var validationErr *models.ValidationError
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &validationErr), errors.Is(err, backup.ErrInvalidArchive):
+		return http.StatusBadRequest
+	case errors.As(err, &maxErr):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, models.ErrNotFound), errors.Is(err, models.ErrFilterNotFound), errors.Is(err, models.ErrWorkflowNotFound),
+		errors.Is(err, models.ErrCommentNotFound), errors.Is(err, models.ErrAttachmentNotFound), errors.Is(err, models.ErrLabelNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrExists), errors.Is(err, models.ErrConflict), errors.Is(err, backup.ErrNotEmpty),
+		errors.Is(err, models.ErrWorkflowInUse), errors.Is(err, models.ErrLabelExists), errors.Is(err, models.ErrLabelGone),
+		errors.Is(err, models.ErrWorkflowChanged), errors.Is(err, models.ErrInTrash):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrNotExecuted):
+		return http.StatusFailedDependency
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// queryError describes an invalid filter expression, pointing at the offending token.
+func queryError(err error) gin.H {
+	var qErr *query.Error
+	if errors.As(err, &qErr) {
+		return gin.H{"error": qErr.Error(), "position": qErr.Pos, "token": qErr.Token}
+	}
+	return gin.H{"error": err.Error()}
+}
+
+// errorBody describes an error, listing the invalid fields of a validation error.
+func errorBody(err error) gin.H {
+	if fields := models.ErrorFields(err); len(fields) > 0 {
+		return gin.H{"error": err.Error(), "fields": fields}
+	}
+	return gin.H{"error": err.Error()}
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"task-organizer/models"
+	"task-organizer/transfer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportCalendar godoc
+// @Summary Export all tasks as a calendar
+// @Description Streams all tasks as the VTODO components of an RFC 5545 iCalendar file, for subscription by calendar clients
+// @Tags Tasks
+// @Produce plain
+// @Success 200 {string} string
+// @Failure 500 {object} nil
+// @Router /tasks.ics [get]
+func ExportCalendar(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	format, _ := transfer.Lookup("ics")
+	exportTasks(c, h, format)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"task-organizer/models"
+	"task-organizer/transfer"
+
+	"github.com/gin-gonic/gin"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// exportPageSize is the number of tasks read from etcd at a time while exporting.
+const exportPageSize = 500
+
+// ExportTasks godoc
+// @Summary Export all tasks
+// @Description Streams all tasks as a JSON array, newline-delimited JSON, CSV, a Markdown checklist, an iCalendar file or todo.txt
+// @Tags Tasks
+// @Produce json
+// @Produce plain
+// @Param format query string false "Export format (json, ndjson, csv, md, ics, todotxt)" default(json)
+// @Success 200 {array} models.Task
+// @Failure 400 {object} nil
+// @Failure 500 {object} nil
+// @Router /tasks/export [get]
+func ExportTasks(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	format, ok := transfer.Lookup(c.DefaultQuery("format", "json"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be one of " + strings.Join(transfer.Names(), ", ")})
+		return
+	}
+
+	exportTasks(c, h, format)
+}
+
+// exportTasks streams all tasks to the response in the given format.
+func exportTasks(c *gin.Context, h *models.Handler, format *transfer.Format) {
+	ctx := context.Background()
+
+	// Read the tasks page by page, all at the revision of the first page
+	key := models.TaskPrefix
+	end := clientv3.GetPrefixRangeEnd(models.TaskPrefix)
+	resp, err := h.Client.Get(ctx, key, clientv3.WithRange(end), clientv3.WithLimit(exportPageSize))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+		return
+	}
+	rev := resp.Header.Revision
+
+	c.Header("Content-Type", format.ContentType)
+	c.Header("Content-Disposition", `attachment; filename="tasks.`+format.Extension+`"`)
+	c.Status(http.StatusOK)
+
+	// Once streaming has started the status can no longer change, so failures end the response
+	enc := format.NewEncoder(c.Writer)
+	for {
+		for _, kv := range resp.Kvs {
+			var task models.Task
+			if err := json.Unmarshal(kv.Value, &task); err != nil {
+				log.Println("Failed to export task", string(kv.Key)+":", err)
+				return
+			}
+			if err := enc.Encode(task); err != nil {
+				log.Println("Failed to export tasks:", err)
+				return
+			}
+		}
+		c.Writer.Flush()
+
+		if !resp.More {
+			break
+		}
+		key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+		resp, err = h.Client.Get(ctx, key, clientv3.WithRange(end), clientv3.WithLimit(exportPageSize), clientv3.WithRev(rev))
+		if err != nil {
+			log.Println("Failed to export tasks:", err)
+			return
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		log.Println("Failed to export tasks:", err)
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"task-organizer/models"
+	"task-organizer/query"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -12,11 +15,23 @@ import (
 
 // GetAllTasks godoc
 // @Summary Get a list of all tasks
-// @Description Returns the data of all the tasks
+// @Description Returns the data of all the tasks, optionally filtered through the secondary indexes, in their manual order unless sorted by ID
 // @Tags Tasks
 // @Accept json
 // @Produce json
+// @Param completed query bool false "Only tasks with this completion status"
+// @Param tag query string false "Only tasks with this tag"
+// @Param assignee query string false "Only tasks assigned to this person"
+// @Param status query string false "Only tasks in this workflow state"
+// @Param label query string false "Only tasks with the label of this ID"
+// @Param due_after query string false "Only tasks due at or after this time (RFC 3339)"
+// @Param due_before query string false "Only tasks due before this time (RFC 3339)"
+// @Param filter query string false "Filter expression, e.g. completed = false AND priority >= high AND tag:backend"
+// @Param saved query string false "Name of a saved filter to apply"
+// @Param sort query string false "Order of the tasks: position, the manual order, or id" default(position)
 // @Success 200 {array} models.Task
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
 // @Failure 500 {object} nil
 // @Router /tasks [get]
 func GetAllTasks(c *gin.Context) {
@@ -34,22 +49,105 @@ func GetAllTasks(c *gin.Context) {
 		return
 	}
 
-	// Use the etcd client to get all tasks
-	resp, err := h.Client.Get(context.Background(), "tasks/", clientv3.WithPrefix())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+	// Parse the optional filters on indexed fields
+	var filter models.TaskFilter
+	if v := c.Query("completed"); v != "" {
+		completed, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid completed filter"})
+			return
+		}
+		filter.Completed = &completed
+	}
+	filter.Tag = c.Query("tag")
+	filter.Assignee = c.Query("assignee")
+	filter.Status = c.Query("status")
+	filter.Label = c.Query("label")
+	for param, target := range map[string]**time.Time{"due_after": &filter.DueAfter, "due_before": &filter.DueBefore} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + " filter"})
+				return
+			}
+			*target = &t
+		}
+	}
+
+	sortBy := c.DefaultQuery("sort", "position")
+	if sortBy != "position" && sortBy != "id" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sort must be one of position, id"})
 		return
 	}
 
-	// Unmarshal the tasks from etcd key-value pairs to models.Task slice
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Parse the optional filter expression, given inline or by the name of a saved filter
+	expr := c.Query("filter")
+	if name := c.Query("saved"); name != "" {
+		saved, err := h.GetSavedFilter(ctx, name)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		expr = saved.Query
+	}
+	var exprFilter *query.Filter
+	if expr != "" {
+		var err error
+		if exprFilter, err = query.Parse(expr); err != nil {
+			c.JSON(http.StatusBadRequest, queryError(err))
+			return
+		}
+	}
+
 	var tasks []models.Task
-	for _, kv := range resp.Kvs {
-		var task models.Task
-		if err := json.Unmarshal(kv.Value, &task); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse tasks"})
+	if !filter.IsEmpty() {
+		// Filtered lists are looked up through the secondary indexes instead of a full scan
+		ids, err := h.FilterTaskIDs(ctx, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+			return
+		}
+		tasks, err = h.GetTasksByID(ctx, ids)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
 			return
 		}
-		tasks = append(tasks, task)
+	} else {
+		// Use the etcd client to get all tasks
+		resp, err := h.Client.Get(ctx, models.TaskPrefix, clientv3.WithPrefix())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+			return
+		}
+
+		// Unmarshal the tasks from etcd key-value pairs to models.Task slice
+		for _, kv := range resp.Kvs {
+			var task models.Task
+			if err := json.Unmarshal(kv.Value, &task); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse tasks"})
+				return
+			}
+			tasks = append(tasks, task)
+		}
+	}
+
+	// Keep the tasks matching the filter expression
+	if exprFilter != nil {
+		matched := []models.Task{}
+		for _, task := range tasks {
+			if exprFilter.Match(task) {
+				matched = append(matched, task)
+			}
+		}
+		tasks = matched
+	}
+
+	// Tasks are read in ID order
+	if sortBy == "position" {
+		models.SortByPosition(tasks)
 	}
 
 	// Return the list of tasks as JSON response
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"task-organizer/blob"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAttachment godoc
+// @Summary Download an attachment of a task
+// @Description Returns the content of a file attached to a task, with its media type and file name
+// @Tags Attachments
+// @Produce octet-stream
+// @Param id path string true "Task ID"
+// @Param attachmentId path string true "Attachment ID"
+// @Param If-None-Match header string false "Answer 304 if the ETag of the content matches"
+// @Success 200 {file} file
+// @Success 304 {object} nil
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Router /tasks/{id}/attachments/{attachmentId} [get]
+func GetAttachment(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	blobs, ok := c.Get("blobs")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blob store"})
+		return
+	}
+
+	store, ok := blobs.(blob.Store)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid blob store type"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	attachment, err := h.GetAttachment(ctx, c.Param("id"), c.Param("attachmentId"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// Contents never change, so their hash identifies them
+	etag := `"` + attachment.SHA256 + `"`
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	content, err := store.Open(ctx, attachment.SHA256)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer content.Close()
+
+	c.Header("Content-Type", attachment.ContentType)
+	c.Header("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, content); err != nil {
+		log.Println("Failed to send attachment "+attachment.ID+":", err)
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAttachments godoc
+// @Summary Get the attachments of a task
+// @Description Returns the metadata of the files attached to the task with the specified ID, oldest first
+// @Tags Attachments
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Success 200 {array} models.Attachment
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Router /tasks/{id}/attachments [get]
+func GetAttachments(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	attachments, err := h.GetAttachments(context.Background(), c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, attachments)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditLog godoc
+// @Summary Get the audit log
+// @Description Returns audit entries of task mutations, oldest first, optionally filtered by task, actor and time range.
+// @Description Entries are read a page of limit entries at a time; pass the returned next key as after to get the following ones.
+// @Description Entries filtered out by task or actor count towards the limit, so a page may hold fewer entries while next is set.
+// @Description The actor of an entry is taken from the X-Actor header of the request that made the mutation, which is not verified.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer admin token"
+// @Param task query string false "Only entries for this task ID"
+// @Param actor query string false "Only entries made by this actor"
+// @Param from query string false "Only entries at or after this time (RFC 3339)"
+// @Param to query string false "Only entries before this time (RFC 3339)"
+// @Param limit query int false "Number of entries to read, 100 by default and at most 1000"
+// @Param after query string false "Next key returned by the previous page"
+// @Success 200 {object} models.AuditLog
+// @Failure 400 {object} nil
+// @Failure 401 {object} nil
+// @Failure 403 {object} nil
+// @Failure 500 {object} nil
+// @Router /admin/audit [get]
+func GetAuditLog(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	q := models.AuditQuery{Task: c.Query("task"), Actor: c.Query("actor"), After: c.Query("after")}
+	var fields []models.FieldError
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			fields = append(fields, models.FieldError{Field: "from", Message: "Invalid time, expected RFC 3339"})
+		}
+		q.From = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			fields = append(fields, models.FieldError{Field: "to", Message: "Invalid time, expected RFC 3339"})
+		}
+		q.To = t
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > models.MaxAuditLimit {
+			fields = append(fields, models.FieldError{Field: "limit", Message: "Limit must be between 1 and " + strconv.Itoa(models.MaxAuditLimit)})
+		}
+		q.Limit = n
+	}
+	if len(fields) > 0 {
+		err := &models.ValidationError{Fields: fields}
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	log, err := h.GetAuditLog(ctx, q)
+	if err != nil {
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, log)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetComment godoc
+// @Summary Get a comment of a task
+// @Description Retrieves a comment posted on a task, with its edit history
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param commentId path string true "Comment ID"
+// @Success 200 {object} models.Comment
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Router /tasks/{id}/comments/{commentId} [get]
+func GetComment(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	comment, _, err := h.GetComment(context.Background(), c.Param("id"), c.Param("commentId"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetFilter godoc
+// @Summary Get a saved filter by name
+// @Description Retrieves the saved filter with the specified name
+// @Tags Filters
+// @Accept json
+// @Produce json
+// @Param name path string true "Filter name"
+// @Success 200 {object} models.SavedFilter
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Router /filters/{name} [get]
+func GetFilter(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	filter, err := h.GetSavedFilter(context.Background(), c.Param("name"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, filter)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"task-organizer/models"
+
+	"github.com/gin-gonic/gin"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// GetFilters godoc
+// @Summary Get a list of saved filters
+// @Description Returns all saved filter expressions
+// @Tags Filters
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.SavedFilter
+// @Failure 500 {object} nil
+// @Router /filters [get]
+func GetFilters(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	resp, err := h.Client.Get(context.Background(), models.FilterPrefix, clientv3.WithPrefix())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch filters"})
+		return
+	}
+
+	filters := []models.SavedFilter{}
+	for _, kv := range resp.Kvs {
+		var filter models.SavedFilter
+		if err := json.Unmarshal(kv.Value, &filter); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse filters"})
+			return
+		}
+		filters = append(filters, filter)
+	}
+
+	c.JSON(http.StatusOK, filters)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetLabel godoc
+// @Summary Get a label by ID
+// @Description Returns the label with the specified ID and the number of tasks having it
+// @Tags Labels
+// @Accept json
+// @Produce json
+// @Param id path string true "Label ID"
+// @Success 200 {object} models.LabelInfo
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Router /labels/{id} [get]
+func GetLabel(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	label, err := h.GetLabelInfo(ctx, c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, label)
+}
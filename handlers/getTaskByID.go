@@ -3,27 +3,34 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 	"task-organizer/models"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/swaggo/gin-swagger"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
 // GetTask godoc
 // @Summary Get a task by ID
-// @Description Retrieves a task with the specified ID
+// @Description Retrieves a task with the specified ID, with its ETag unless a revision is given
 // @Tags Tasks
 // @Accept json
 // @Produce json
 // @Param id path string true "Task ID" Format(int64)
+// @Param revision query int false "Return the task as of this etcd revision"
+// @Param If-None-Match header string false "Respond with 304 if the ETag of the task matches"
 // @Success 200 {object} models.Task
+// @Success 304 {object} nil
+// @Failure 400 {object} nil
 // @Failure 404 {object} nil
+// @Failure 410 {object} nil
 // @Failure 500 {object} nil
 // @Router /tasks/{id} [get]
 func GetTask(c *gin.Context) {
-	// Fetch the task ID from the URL path parameter
 	var task models.Task
 	client, ok := c.Get("handler")
 	if !ok {
@@ -37,9 +44,44 @@ func GetTask(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.Client.Get(context.Background(), "tasks/", clientv3.WithPrefix())
+	// Return the task as of an earlier etcd revision if one was requested
+	if rev := c.Query("revision"); rev != "" {
+		revision, err := strconv.ParseInt(rev, 10, 64)
+		if err != nil || revision <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision"})
+			return
+		}
+
+		resp, err := h.Client.Get(context.Background(), models.TaskPrefix+c.Param("id"), clientv3.WithRev(revision))
+		if errors.Is(err, rpctypes.ErrCompacted) {
+			c.JSON(http.StatusGone, gin.H{"error": "Revision has been compacted"})
+			return
+		}
+		if errors.Is(err, rpctypes.ErrFutureRev) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Revision is in the future"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task"})
+			return
+		}
+		if len(resp.Kvs) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found at this revision"})
+			return
+		}
+
+		if err := json.Unmarshal(resp.Kvs[0].Value, &task); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse task"})
+			return
+		}
+		c.IndentedJSON(http.StatusOK, task)
+		return
+	}
+
+	// Look up the task under its own key
+	resp, err := h.Client.Get(context.Background(), models.TaskPrefix+c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task"})
 		return
 	}
 	// Check if the key exists in the response
@@ -48,10 +90,28 @@ func GetTask(c *gin.Context) {
 		return
 	}
 
+	// The ETag changes with every write, so unchanged tasks need not be sent again
+	etag := models.ETag(resp.Kvs[0].ModRevision)
+	c.Header("ETag", etag)
+	if match := c.GetHeader("If-None-Match"); match == etag || match == "*" {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
 	if err := json.Unmarshal(resp.Kvs[0].Value, &task); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse task"})
 		return
 	}
 
+	// Expose the remaining lifetime of expiring tasks
+	if lease := resp.Kvs[0].Lease; lease != 0 {
+		ttl, err := h.Client.TimeToLive(context.Background(), clientv3.LeaseID(lease))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task lease"})
+			return
+		}
+		task.TTL = ttl.TTL
+	}
+
 	c.IndentedJSON(http.StatusOK, task)
 }
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"task-organizer/models"
+
+	"github.com/gin-gonic/gin"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// GetTrash godoc
+// @Summary Get a list of deleted tasks
+// @Description Returns the tasks in the trash together with their deletion and purge times
+// @Tags Trash
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.TrashedTask
+// @Failure 500 {object} nil
+// @Router /trash [get]
+func GetTrash(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	resp, err := h.Client.Get(context.Background(), models.TrashPrefix, clientv3.WithPrefix())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trash"})
+		return
+	}
+
+	trashed := []models.TrashedTask{}
+	for _, kv := range resp.Kvs {
+		var task models.TrashedTask
+		if err := json.Unmarshal(kv.Value, &task); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse trash"})
+			return
+		}
+		trashed = append(trashed, task)
+	}
+
+	c.JSON(http.StatusOK, trashed)
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetWorkflow godoc
+// @Summary Get a workflow by name
+// @Description Retrieves the workflow with the specified name, with the number of tasks in each of its states
+// @Tags Workflows
+// @Accept json
+// @Produce json
+// @Param name path string true "Workflow name"
+// @Success 200 {object} models.WorkflowInfo
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Router /workflows/{name} [get]
+func GetWorkflow(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	ctx := context.Background()
+	workflow, err := h.GetWorkflow(ctx, c.Param("name"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	usage, err := h.WorkflowUsage(ctx, workflow.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.WorkflowInfo{Workflow: *workflow, Usage: usage})
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"task-organizer/models"
+
+	"github.com/gin-gonic/gin"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// GetWorkflows godoc
+// @Summary Get a list of workflows
+// @Description Returns all workflows, including the built-in default one unless it was replaced
+// @Tags Workflows
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.Workflow
+// @Failure 500 {object} nil
+// @Router /workflows [get]
+func GetWorkflows(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	resp, err := h.Client.Get(context.Background(), models.WorkflowPrefix, clientv3.WithPrefix())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workflows"})
+		return
+	}
+
+	workflows := []models.Workflow{}
+	stored := false
+	for _, kv := range resp.Kvs {
+		var workflow models.Workflow
+		if err := json.Unmarshal(kv.Value, &workflow); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse workflows"})
+			return
+		}
+		stored = stored || workflow.Name == models.DefaultWorkflow
+		workflows = append(workflows, workflow)
+	}
+	if !stored {
+		workflows = append([]models.Workflow{*models.BuiltinWorkflow()}, workflows...)
+	}
+
+	c.JSON(http.StatusOK, workflows)
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"task-organizer/graphqlserver"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GraphQL godoc
+// @Summary Run a GraphQL operation
+// @Description Executes a GraphQL query or mutation over tasks, given as JSON with query, operationName and variables. GET requests take them as query parameters and may only run queries. Subscriptions are served over a WebSocket speaking the graphql-transport-ws protocol on the same path. Queries exceeding the depth or complexity limits are rejected.
+// @Tags GraphQL
+// @Accept json
+// @Produce json
+// @Param X-Actor header string false "Who performs the mutations, recorded in the audit log"
+// @Success 200 {object} nil
+// @Failure 400 {object} nil
+// @Failure 405 {object} nil
+// @Failure 413 {object} nil
+// @Failure 500 {object} nil
+// @Router /graphql [post]
+func GraphQL(c *gin.Context) {
+	server, ok := c.Get("graphql")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch GraphQL handler"})
+		return
+	}
+
+	gql, ok := server.(*graphqlserver.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid GraphQL handler type"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxBodySize())
+	gql.ServeHTTP(c.Writer, c.Request)
+}
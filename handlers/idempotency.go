@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"task-organizer/models"
+	"task-organizer/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Scopes of the Idempotency-Keys of the operations supporting them.
+const createTaskScope = "POST /tasks"
+
+// idempotencyScope scopes the Idempotency-Keys of an operation to the client
+// of a request, identified by a hash of its API key or else by its IP
+// address, so that clients choosing the same key do not get each other's
+// responses.
+func idempotencyScope(c *gin.Context, operation string) string {
+	if key := ratelimit.APIKey(c); key != "" {
+		return operation + " key/" + ratelimit.HashKey(key)
+	}
+	return operation + " ip/" + c.ClientIP()
+}
+
+// replay answers a retried request with the response stored for its
+// Idempotency-Key, or with 422 if the key was used for a different request.
+func replay(c *gin.Context, stored *models.IdempotentResponse, fingerprint string) {
+	if stored.Fingerprint != fingerprint {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": models.ErrIdempotencyMismatch.Error()})
+		return
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(stored.Status, "application/json; charset=utf-8", stored.Body)
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"task-organizer/models"
+	"task-organizer/transfer"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+// ImportTasks godoc
+// @Summary Import tasks
+// @Description Imports tasks from a JSON array, newline-delimited JSON, CSV, a Markdown checklist, an iCalendar file or todo.txt and reports the outcome of each row.
+// @Description Rows without an ID get a new one. The conflict strategy decides what happens to rows whose ID is taken:
+// @Description skip them, overwrite the stored task or import them under a new ID. With dry_run set nothing is stored.
+// @Description Markdown and iCalendar files leave out fields of the tasks, so they cannot overwrite stored tasks.
+// @Tags Tasks
+// @Accept json
+// @Accept plain
+// @Produce json
+// @Param format query string false "Import format (json, ndjson, csv, md, ics, todotxt)" default(json)
+// @Param conflict query string false "Strategy for taken IDs (skip, overwrite, regenerate)" default(skip)
+// @Param dry_run query bool false "Only validate the import"
+// @Param tasks body string true "Tasks to import"
+// @Param X-Actor header string false "Actor recorded in the audit log"
+// @Success 200 {object} models.ImportResp
+// @Failure 400 {object} nil
+// @Failure 413 {object} nil
+// @Failure 500 {object} nil
+// @Router /tasks/import [post]
+func ImportTasks(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	format, ok := transfer.Lookup(c.DefaultQuery("format", "json"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be one of " + strings.Join(transfer.Names(), ", ")})
+		return
+	}
+
+	importTasks(c, h, format)
+}
+
+// importTasks imports the tasks of the request body in the given format.
+func importTasks(c *gin.Context, h *models.Handler, format *transfer.Format) {
+	conflict := c.DefaultQuery("conflict", models.ConflictSkip)
+	switch conflict {
+	case models.ConflictSkip, models.ConflictOverwrite, models.ConflictRegenerate:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Conflict must be one of skip, overwrite, regenerate"})
+		return
+	}
+	if conflict == models.ConflictOverwrite && format.Lossy {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format " + format.Name + " does not carry every field of a task and cannot overwrite stored ones"})
+		return
+	}
+	dryRun := false
+	if v := c.Query("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dry_run"})
+			return
+		}
+	}
+
+	data, err := readBody(c, MaxImportSize())
+	if err != nil {
+		c.JSON(errorStatus(err), bodyError(err))
+		return
+	}
+	rows, err := format.Decode(bytes.NewReader(data))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + format.Name + " input: " + err.Error()})
+		return
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No tasks to import"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// Look up which of the imported IDs are taken
+	var ids []string
+	for _, row := range rows {
+		if row.Err == nil && row.Task.ID != "" {
+			ids = append(ids, row.Task.ID)
+		}
+	}
+	kvs, err := h.GetTaskKVs(ctx, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+		return
+	}
+	stored := map[string]*mvccpb.KeyValue{}
+	for _, kv := range kvs {
+		stored[strings.TrimPrefix(string(kv.Key), models.TaskPrefix)] = kv
+	}
+
+	meta := auditMeta(c)
+	resp := models.ImportResp{DryRun: dryRun, Results: make([]models.ImportResult, len(rows))}
+	var prepared []*models.Mutation
+	var preparedIdx []int
+	seen := map[string]bool{}
+
+	// New tasks without a position are appended in the order of the rows
+	unpositioned := 0
+	for _, row := range rows {
+		if row.Err == nil && row.Task.Position == "" {
+			unpositioned++
+		}
+	}
+	positions, err := h.AppendPositions(ctx, unpositioned)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task positions"})
+		return
+	}
+
+	// Validate and prepare every row before storing any of them
+	for i, row := range rows {
+		result := &resp.Results[i]
+		*result = models.ImportResult{Row: row.Line, ID: row.Task.ID}
+		if row.Err != nil {
+			result.Result = models.ImportFailed
+			result.Error = row.Err.Error()
+			result.Fields = models.ErrorFields(row.Err)
+			continue
+		}
+
+		task := row.Task
+		kv := stored[task.ID]
+		regenerate := false
+		switch {
+		case task.ID == "":
+			regenerate = true
+		case seen[task.ID] && conflict != models.ConflictRegenerate:
+			result.Result = models.ImportFailed
+			result.Error = "Task appears more than once in the import"
+			continue
+		case seen[task.ID] || (kv != nil && conflict == models.ConflictRegenerate):
+			regenerate = true
+			kv = nil
+		case kv != nil && conflict == models.ConflictSkip:
+			seen[task.ID] = true
+			result.Result = models.ImportSkipped
+			continue
+		}
+		seen[row.Task.ID] = true
+		if task.Position == "" && kv == nil {
+			task.Position, positions = positions[0], positions[1:]
+		}
+
+		var err error
+		if regenerate {
+			task.ID, err = h.NewTaskID(ctx)
+		}
+		var m *models.Mutation
+		if err == nil {
+			m, err = h.PrepareImport(ctx, task, kv, meta)
+		}
+		if err != nil {
+			result.Result = models.ImportFailed
+			result.Error = err.Error()
+			result.Fields = models.ErrorFields(err)
+			continue
+		}
+		result.ID = task.ID
+		prepared = append(prepared, m)
+		preparedIdx = append(preparedIdx, i)
+	}
+
+	// Store the prepared rows, or only report what would happen on a dry run
+	var errs []error
+	if dryRun {
+		h.Release(ctx, false, prepared...)
+		errs = make([]error, len(prepared))
+	} else {
+		errs = h.ApplyBatch(ctx, prepared, false)
+	}
+	for n, err := range errs {
+		result := &resp.Results[preparedIdx[n]]
+		switch {
+		case err != nil:
+			result.Result = models.ImportFailed
+			result.Error = err.Error()
+		case prepared[n].Before != nil:
+			result.Result = models.ImportOverwritten
+		default:
+			result.Result = models.ImportCreated
+		}
+	}
+
+	for _, result := range resp.Results {
+		switch result.Result {
+		case models.ImportCreated:
+			resp.Created++
+		case models.ImportOverwritten:
+			resp.Overwritten++
+		case models.ImportSkipped:
+			resp.Skipped++
+		default:
+			resp.Failed++
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
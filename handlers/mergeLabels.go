@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MergeLabels godoc
+// @Summary Merge labels into a label
+// @Description Gives the label with the specified ID to all tasks having any of the merged labels, then deletes them, recording the changes in the audit log
+// @Description The change is applied atomically in a single transaction. A label on more tasks than the etcd operation limit
+// @Description allows to relabel at once is refused with 400; remove it from some of the tasks first.
+// @Tags Labels
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the label to merge into"
+// @Param merge body models.MergeReq true "Labels to merge"
+// @Param X-Actor header string false "Actor recorded in the audit log"
+// @Success 200 {object} models.MergeResult
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 409 {object} nil
+// @Failure 413 {object} nil
+// @Failure 500 {object} nil
+// @Router /labels/{id}/merge [post]
+func MergeLabels(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	var mergeReq models.MergeReq
+	if err := bindJSON(c, &mergeReq); err != nil {
+		c.JSON(errorStatus(err), bodyError(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	id := c.Param("id")
+	tasks, err := h.MergeLabels(ctx, id, mergeReq, auditMeta(c))
+	if err != nil {
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+	label, err := h.GetLabelInfo(ctx, id)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MergeResult{Label: *label, Tasks: tasks})
+}
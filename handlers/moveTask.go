@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MoveTask godoc
+// @Summary Move a task in the list
+// @Description Places a task right after the task given as after, right before the task given as before, or between both.
+// @Description Only the moved task is rewritten; tasks are listed in this order by default
+// @Tags Tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param move body models.MoveReq true "Tasks to place the task next to"
+// @Param X-Actor header string false "Actor recorded in the audit log"
+// @Param If-Match header string false "Only move the task if its ETag matches"
+// @Success 200 {object} models.Task
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 409 {object} nil
+// @Failure 412 {object} nil
+// @Failure 413 {object} nil
+// @Failure 500 {object} nil
+// @Router /tasks/{id}/move [post]
+func MoveTask(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	var moveReq models.MoveReq
+	if err := bindJSON(c, &moveReq); err != nil {
+		c.JSON(errorStatus(err), bodyError(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Work out the new position of the task and prepare saving it with its audit entry
+	m, err := h.PrepareMove(ctx, c.Param("id"), moveReq, auditMeta(c))
+	if err != nil {
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	if !precondition(c, m.Prev()) {
+		h.Release(ctx, false, m)
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Precondition failed"})
+		return
+	}
+
+	rev, err := h.Commit(ctx, m)
+	h.Release(ctx, err == nil, m)
+	if err != nil {
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	c.Header("ETag", models.ETag(rev))
+	c.JSON(http.StatusOK, m.After)
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"task-organizer/models"
+
+	"github.com/gin-gonic/gin"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+// precondition reports whether the If-Match and If-None-Match headers of
+// a request hold for the stored task kv, which is nil if there is none.
+func precondition(c *gin.Context, kv *mvccpb.KeyValue) bool {
+	if match := c.GetHeader("If-Match"); match != "" {
+		if kv == nil || (match != "*" && match != models.ETag(kv.ModRevision)) {
+			return false
+		}
+	}
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if kv != nil && (match == "*" || match == models.ETag(kv.ModRevision)) {
+			return false
+		}
+	}
+	return true
+}
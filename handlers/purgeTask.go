@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"task-organizer/blob"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// PurgeTask godoc
+// @Summary Permanently delete a task from the trash
+// @Description Removes a deleted task, its comments and its attachments for good; it can no longer be restored
+// @Tags Trash
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param X-Actor header string false "Actor recorded in the audit log"
+// @Success 200 {object} nil
+// @Failure 404 {object} nil
+// @Failure 409 {object} nil
+// @Failure 500 {object} nil
+// @Router /trash/{id} [delete]
+func PurgeTask(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+	blobs, ok := c.Get("blobs")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blob store"})
+		return
+	}
+
+	store, ok := blobs.(blob.Store)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid blob store type"})
+		return
+	}
+	id := c.Param("id")
+	trashKey := models.TrashPrefix + id
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Get the trashed task with its attachments; none can be added while it is in the trash
+	resp, err := h.Client.Txn(ctx).Then(
+		clientv3.OpGet(trashKey),
+		clientv3.OpGet(models.AttachmentKeyPrefix(id), clientv3.WithPrefix()),
+	).Commit()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	kvs := resp.Responses[0].GetResponseRange().Kvs
+	if len(kvs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found in trash"})
+		return
+	}
+
+	var trashed models.TrashedTask
+	if err := json.Unmarshal(kvs[0].Value, &trashed); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	hashes, err := models.HashesByTask(resp.Responses[1].GetResponseRange().Kvs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Record the purge in the audit log
+	entry, err := newAuditEntry(c, models.AuditPurge, &trashed.Task, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	auditOp, err := entry.Op()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	txn, err := h.Client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(trashKey), "=", kvs[0].ModRevision), entry.Cmp()).
+		Then(
+			clientv3.OpDelete(trashKey),
+			clientv3.OpDelete(models.CommentKeyPrefix(id), clientv3.WithPrefix()),
+			clientv3.OpDelete(models.AttachmentKeyPrefix(id), clientv3.WithPrefix()),
+			auditOp,
+		).
+		Commit()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !txn.Succeeded {
+		c.JSON(http.StatusConflict, gin.H{"error": "Trash entry was modified concurrently"})
+		return
+	}
+
+	// Revoke the lease that would have purged the task, and delete the
+	// contents no other attachment has
+	if err := h.ReleaseTrashLease(ctx, clientv3.LeaseID(kvs[0].Lease)); err != nil {
+		log.Println("Failed to revoke the trash lease of task "+id+":", err)
+	}
+	if err := h.ReleaseBlobs(ctx, store, hashes[id]); err != nil {
+		log.Println("Failed to delete the attachments of task "+id+":", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task purged"})
+}
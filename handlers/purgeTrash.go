@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"task-organizer/blob"
+	"task-organizer/models"
+
+	"github.com/gin-gonic/gin"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// PurgeTrash godoc
+// @Summary Empty the trash
+// @Description Permanently deletes every task in the trash with its comments and attachments
+// @Tags Trash
+// @Accept json
+// @Produce json
+// @Param X-Actor header string false "Actor recorded in the audit log"
+// @Success 200 {object} nil
+// @Failure 409 {object} nil
+// @Failure 500 {object} nil
+// @Router /trash [delete]
+func PurgeTrash(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	blobs, ok := c.Get("blobs")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blob store"})
+		return
+	}
+
+	store, ok := blobs.(blob.Store)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid blob store type"})
+		return
+	}
+
+	// Get the trashed tasks and the attachments at a single revision
+	ctx := context.Background()
+	resp, err := h.Client.Txn(ctx).Then(
+		clientv3.OpGet(models.TrashPrefix, clientv3.WithPrefix()),
+		clientv3.OpGet(models.AttachmentPrefix, clientv3.WithPrefix()),
+	).Commit()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trash"})
+		return
+	}
+	hashes, err := models.HashesByTask(resp.Responses[1].GetResponseRange().Kvs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attachments"})
+		return
+	}
+
+	// Purge the trashed tasks one by one, each in a transaction with its own audit entry,
+	// noting the attachment contents of the ones purged
+	var released []string
+	defer func() {
+		if err := h.ReleaseBlobs(ctx, store, released); err != nil {
+			log.Println("Failed to delete the attachments of the trash:", err)
+		}
+	}()
+	for _, kv := range resp.Responses[0].GetResponseRange().Kvs {
+		var trashed models.TrashedTask
+		if err := json.Unmarshal(kv.Value, &trashed); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse trash"})
+			return
+		}
+
+		entry, err := newAuditEntry(c, models.AuditPurge, &trashed.Task, nil)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		auditOp, err := entry.Op()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		key := string(kv.Key)
+		txn, err := h.Client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(key), "=", kv.ModRevision), entry.Cmp()).
+			Then(
+				clientv3.OpDelete(key),
+				clientv3.OpDelete(models.CommentKeyPrefix(trashed.ID), clientv3.WithPrefix()),
+				clientv3.OpDelete(models.AttachmentKeyPrefix(trashed.ID), clientv3.WithPrefix()),
+				auditOp,
+			).
+			Commit()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge trash"})
+			return
+		}
+		if !txn.Succeeded {
+			c.JSON(http.StatusConflict, gin.H{"error": "Trash was modified concurrently"})
+			return
+		}
+		released = append(released, hashes[trashed.ID]...)
+
+		// Revoke the lease that would have purged the task once no other entry uses it
+		if err := h.ReleaseTrashLease(ctx, clientv3.LeaseID(kv.Lease)); err != nil {
+			log.Println("Failed to revoke the trash lease of task "+trashed.ID+":", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Trash emptied"})
+}
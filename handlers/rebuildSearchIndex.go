@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/models"
+	"task-organizer/search"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RebuildSearchIndex godoc
+// @Summary Rebuild the search index
+// @Description Reloads the full-text search index from the tasks stored in etcd
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer admin token"
+// @Success 200 {object} nil
+// @Failure 401 {object} nil
+// @Failure 403 {object} nil
+// @Failure 500 {object} nil
+// @Router /admin/search/rebuild [post]
+func RebuildSearchIndex(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	index, ok := c.Get("index")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch search index"})
+		return
+	}
+
+	ix, ok := index.(*search.Index)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid search index type"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := ix.Load(ctx, h.Client); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Search index rebuilt"})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"task-organizer/backup"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RestoreBackup godoc
+// @Summary Restore the task keyspace from a backup
+// @Description Validates a backup archive and loads it in transactions, rolling back if loading fails.
+// @Description The keyspace must be empty unless force is set, in which case it is replaced. Secondary indexes are rebuilt.
+// @Description A restore is not transactional as a whole: clients see the keyspace emptied and partly loaded while it runs,
+// @Description and errors rolling back a failed restore are reported along with the cause.
+// @Tags Admin
+// @Accept octet-stream
+// @Produce json
+// @Param archive body string true "Backup archive"
+// @Param force query bool false "Replace the tasks currently stored"
+// @Param dry_run query bool false "Only validate the archive"
+// @Param Authorization header string true "Bearer admin token"
+// @Success 200 {object} backup.RestoreResult
+// @Failure 400 {object} nil
+// @Failure 413 {object} nil
+// @Failure 409 {object} nil
+// @Failure 401 {object} nil
+// @Failure 403 {object} nil
+// @Failure 500 {object} nil
+// @Router /admin/restore [post]
+func RestoreBackup(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	var force, dryRun bool
+	for param, target := range map[string]*bool{"force": &force, "dry_run": &dryRun} {
+		if v := c.Query(param); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param})
+				return
+			}
+			*target = b
+		}
+	}
+
+	data, err := readBody(c, MaxImportSize())
+	if err != nil {
+		c.JSON(errorStatus(err), bodyError(err))
+		return
+	}
+	manifest, entries, err := backup.Read(bytes.NewReader(data))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, backup.RestoreResult{Manifest: manifest, DryRun: true})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	result, err := backup.Restore(ctx, h, manifest, entries, force)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RestoreTask godoc
+// @Summary Restore a deleted task
+// @Description Moves a task from the trash back to the task list together with its comments and attachments
+// @Tags Trash
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param X-Actor header string false "Actor recorded in the audit log"
+// @Success 200 {object} models.Task
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 409 {object} nil
+// @Failure 500 {object} nil
+// @Router /trash/{id}/restore [post]
+func RestoreTask(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+	trashKey := models.TrashPrefix + c.Param("id")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := h.Client.Get(ctx, trashKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(resp.Kvs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found in trash"})
+		return
+	}
+
+	// Prepare moving the task back together with its index entries and audit entry
+	m, err := h.PrepareRestore(ctx, resp.Kvs[0], auditMeta(c))
+	if err != nil {
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	// Restore the task, provided the trash entry is unchanged and no task took its ID
+	if err := h.Apply(ctx, m); err != nil {
+		if errors.Is(err, models.ErrExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Task already exists or trash entry was modified concurrently"})
+			return
+		}
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, m.After)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// RevertTask godoc
+// @Summary Revert a task to a previous version
+// @Description Restores the task to the version stored at the given etcd revision
+// @Tags Tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param revert body models.RevertReq true "Revision to restore"
+// @Param X-Actor header string false "Actor recorded in the audit log"
+// @Success 200 {object} models.Task
+// @Failure 400 {object} nil
+// @Failure 413 {object} nil
+// @Failure 404 {object} nil
+// @Failure 409 {object} nil
+// @Failure 410 {object} nil
+// @Failure 500 {object} nil
+// @Router /tasks/{id}/revert [post]
+func RevertTask(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+	taskID := c.Param("id")
+	key := models.TaskPrefix + taskID
+
+	var revertReq models.RevertReq
+	if err := bindJSON(c, &revertReq); err != nil {
+		c.JSON(errorStatus(err), bodyError(err))
+		return
+	}
+	if revertReq.Revision <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Revision must be positive"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Fetch the current version of the task
+	resp, err := h.Client.Get(ctx, key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(resp.Kvs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	// Fetch the version to restore
+	old, err := h.Client.Get(ctx, key, clientv3.WithRev(revertReq.Revision))
+	if errors.Is(err, rpctypes.ErrCompacted) {
+		c.JSON(http.StatusGone, gin.H{"error": "Revision has been compacted"})
+		return
+	}
+	if errors.Is(err, rpctypes.ErrFutureRev) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Revision is in the future"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(old.Kvs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task did not exist at this revision"})
+		return
+	}
+	var revertedTask models.Task
+	if err := json.Unmarshal(old.Kvs[0].Value, &revertedTask); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Prepare writing the old version back together with its index entries and audit entry
+	m, err := h.PrepareRevert(ctx, revertedTask, resp.Kvs[0], auditMeta(c))
+	if err != nil {
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	// Revert the task, provided it was not modified since it was read
+	if err := h.Apply(ctx, m); err != nil {
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, m.After)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"task-organizer/models"
+	"task-organizer/query"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SaveFilter godoc
+// @Summary Save a named filter
+// @Description Creates or replaces the filter expression stored under the specified name
+// @Tags Filters
+// @Accept json
+// @Produce json
+// @Param name path string true "Filter name"
+// @Param filter body models.FilterReq true "Filter expression"
+// @Success 200 {object} models.SavedFilter
+// @Failure 400 {object} nil
+// @Failure 413 {object} nil
+// @Failure 500 {object} nil
+// @Router /filters/{name} [put]
+func SaveFilter(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+	name := c.Param("name")
+
+	var filterReq models.FilterReq
+	if err := bindJSON(c, &filterReq); err != nil {
+		c.JSON(errorStatus(err), bodyError(err))
+		return
+	}
+
+	// Only valid expressions can be saved
+	if filterReq.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query cannot be empty"})
+		return
+	}
+	if _, err := query.Parse(filterReq.Query); err != nil {
+		c.JSON(http.StatusBadRequest, queryError(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Keep the creation time of a filter being replaced
+	now := time.Now().UTC()
+	filter := models.SavedFilter{Name: name, Query: filterReq.Query, CreatedAt: now, UpdatedAt: now}
+	existing, err := h.GetSavedFilter(ctx, name)
+	if err != nil && !errors.Is(err, models.ErrFilterNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if existing != nil {
+		filter.CreatedAt = existing.CreatedAt
+	}
+
+	data, err := json.Marshal(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := h.Client.Put(ctx, models.FilterPrefix+name, string(data)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, filter)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SaveWorkflow godoc
+// @Summary Save a workflow
+// @Description Creates or replaces the workflow stored under the specified name. States still holding tasks, counting the ones in the trash, cannot be removed.
+// @Tags Workflows
+// @Accept json
+// @Produce json
+// @Param name path string true "Workflow name"
+// @Param workflow body models.WorkflowReq true "States and transitions"
+// @Success 200 {object} models.Workflow
+// @Failure 400 {object} nil
+// @Failure 409 {object} nil
+// @Failure 413 {object} nil
+// @Failure 500 {object} nil
+// @Router /workflows/{name} [put]
+func SaveWorkflow(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	var workflowReq models.WorkflowReq
+	if err := bindJSON(c, &workflowReq); err != nil {
+		c.JSON(errorStatus(err), bodyError(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	workflow, err := h.SaveWorkflow(ctx, c.Param("name"), workflowReq)
+	if err != nil {
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, workflow)
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"task-organizer/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchTasks godoc
+// @Summary Search tasks
+// @Description Finds tasks whose title or description contain every word of the query, matching words by prefix, ranked by relevance
+// @Tags Tasks
+// @Accept json
+// @Produce json
+// @Param q query string true "Words to search for"
+// @Param limit query int false "Maximum number of results (default 20)"
+// @Success 200 {array} search.Result
+// @Failure 400 {object} nil
+// @Failure 500 {object} nil
+// @Router /tasks/search [get]
+func SearchTasks(c *gin.Context) {
+	index, ok := c.Get("index")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch search index"})
+		return
+	}
+
+	ix, ok := index.(*search.Index)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid search index type"})
+		return
+	}
+
+	query := c.Query("q")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query cannot be empty"})
+		return
+	}
+
+	limit := 20
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	c.JSON(http.StatusOK, ix.Search(query, limit))
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"task-organizer/models"
+	"task-organizer/transfer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportTodoTxt godoc
+// @Summary Export all tasks as todo.txt
+// @Description Streams all tasks as todo.txt lines with priority, completion marker, dates, +project and @context tags.
+// @Description Fields todo.txt has no syntax for are written as key:value extensions, so the file can be imported back without loss.
+// @Tags Tasks
+// @Produce plain
+// @Success 200 {string} string
+// @Failure 500 {object} nil
+// @Router /tasks/todo.txt [get]
+func ExportTodoTxt(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	format, _ := transfer.Lookup("todotxt")
+	exportTasks(c, h, format)
+}
+
+// ImportTodoTxt godoc
+// @Summary Import tasks from todo.txt
+// @Description Imports one task per todo.txt line and reports the outcome of each line, like POST /tasks/import with format todotxt.
+// @Tags Tasks
+// @Accept plain
+// @Produce json
+// @Param conflict query string false "Strategy for taken IDs (skip, overwrite, regenerate)" default(skip)
+// @Param dry_run query bool false "Only validate the import"
+// @Param tasks body string true "todo.txt lines"
+// @Param X-Actor header string false "Actor recorded in the audit log"
+// @Success 200 {object} models.ImportResp
+// @Failure 400 {object} nil
+// @Failure 413 {object} nil
+// @Failure 500 {object} nil
+// @Router /tasks/todo.txt [post]
+func ImportTodoTxt(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	format, _ := transfer.Lookup("todotxt")
+	importTasks(c, h, format)
+}
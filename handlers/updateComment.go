@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"task-organizer/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateComment godoc
+// @Summary Edit a comment of a task
+// @Description Replaces the body of a comment, keeping the previous one in its edit history
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param commentId path string true "Comment ID"
+// @Param comment body models.CommentReq true "New body of the comment"
+// @Param X-Actor header string false "Editor recorded in the edit history"
+// @Success 200 {object} models.Comment
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 409 {object} nil
+// @Failure 413 {object} nil
+// @Failure 500 {object} nil
+// @Router /tasks/{id}/comments/{commentId} [put]
+func UpdateComment(c *gin.Context) {
+	client, ok := c.Get("handler")
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handler"})
+		return
+	}
+
+	h, ok := client.(*models.Handler)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid handler type"})
+		return
+	}
+
+	var commentReq models.CommentReq
+	if err := bindJSON(c, &commentReq); err != nil {
+		c.JSON(errorStatus(err), bodyError(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	comment, err := h.EditComment(ctx, c.Param("id"), c.Param("commentId"), auditMeta(c).Actor, commentReq)
+	if err != nil {
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
@@ -2,24 +2,35 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 	"task-organizer/models"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // UpdateTask godoc
 // @Summary Update a task by ID
-// @Description Updates a task with the specified ID
+// @Description Updates a task with the specified ID. Expiring tasks have their lease renewed unless a new ttl or expires_at is given
+// @Description With upsert set, a task that does not exist is created under the ID chosen by the client; If-None-Match: * makes the request create-only
+// @Description Unknown fields, duplicate keys and text fields over their length limits are rejected, with the invalid fields listed in the error
 // @Tags Tasks
 // @Accept json
 // @Produce json
 // @Param id path string true "Task ID" Format(int64)
 // @Param task body models.UpdateReq true "Task object with fields to be updated"
+// @Param upsert query bool false "Create the task under the ID if it does not exist"
+// @Param X-Actor header string false "Actor recorded in the audit log"
+// @Param If-Match header string false "Only update the task if its ETag matches"
 // @Success 200 {object} models.UpdateReq
+// @Success 201 {object} models.Task
 // @Failure 400 {object} nil
+// @Failure 413 {object} nil
 // @Failure 404 {object} nil
+// @Failure 409 {object} nil
+// @Failure 412 {object} nil
 // @Failure 500 {object} nil
 // @Router /tasks/{id} [put]
 func UpdateTask(c *gin.Context) {
@@ -37,55 +48,56 @@ func UpdateTask(c *gin.Context) {
 	// Get the task ID from the URL path
 	taskID := c.Param("id")
 
-	// Fetch the existing task from the database
-	resp, err := h.Client.Get(context.Background(), "tasks/"+taskID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
-		return
+	upsert := false
+	if v := c.Query("upsert"); v != "" {
+		var err error
+		if upsert, err = strconv.ParseBool(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upsert"})
+			return
+		}
 	}
 
-	if len(resp.Kvs) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	// Bind the JSON request body to the update request
+	var updateReq models.UpdateReq
+	if err := bindJSON(c, &updateReq); err != nil {
+		c.JSON(errorStatus(err), bodyError(err))
 		return
 	}
 
-	// Unmarshal the existing task data into a temporary task variable
-	var existingTask models.Task
-	err = json.Unmarshal(resp.Kvs[0].Value, &existingTask)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	// Bind the JSON request body to the update request
-	var updateReq models.UpdateReq
-	if err := c.ShouldBindJSON(&updateReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// Apply the update to the existing task and prepare saving it with its audit entry,
+	// or prepare creating the task under the ID if it does not exist and upsert is set
+	m, err := h.PrepareUpdate(ctx, taskID, updateReq, auditMeta(c))
+	if errors.Is(err, models.ErrNotFound) && upsert {
+		m, err = h.PrepareInsert(ctx, updateReq.Task(taskID), auditMeta(c))
+	}
+	if err != nil {
+		c.JSON(errorStatus(err), errorBody(err))
 		return
 	}
 
-	// Validate if the idea field is empty
-	if updateReq.Title == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Title cannot be empty"})
+	if !precondition(c, m.Prev()) {
+		h.Release(ctx, false, m)
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Precondition failed"})
 		return
 	}
 
-	// Update the existing task with the new title
-	existingTask.Title = updateReq.Title
-
-	// Marshal the updated task into JSON
-	taskJSON, err := json.Marshal(existingTask)
+	// Save the updated task back to the database together with the audit entry,
+	// provided the task was not modified since it was read
+	rev, err := h.Commit(ctx, m)
+	h.Release(ctx, err == nil, m)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), errorBody(err))
 		return
 	}
 
-	// Save the updated task back to the database
-	_, err = h.Client.Put(context.Background(), "tasks/"+taskID, string(taskJSON))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	c.Header("ETag", models.ETag(rev))
+	if m.Before == nil {
+		c.Header("Location", "/tasks/"+taskID)
+		c.JSON(http.StatusCreated, m.After)
 		return
 	}
-
 	c.JSON(http.StatusOK, updateReq)
 }
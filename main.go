@@ -20,6 +20,14 @@ import (
 // @BasePath /tasks
 
 func main() {
+	// Run a subcommand such as backup or restore instead of the server if one is given.
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Create a new Gin router with default middleware (logger, recovery).
 	r := gin.Default()
 
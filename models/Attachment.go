@@ -0,0 +1,428 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"task-organizer/blob"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// AttachmentPrefix is the etcd key prefix under which the metadata of
+// attachments is stored, under attachments/<task ID>/ like comments, while the
+// task is in the task list or in the trash. It is deleted when the task is
+// purged, or by CollectBlobs once it expired. The contents are kept in a blob
+// store under their SHA-256, so that identical files are stored once.
+const AttachmentPrefix = "attachments/"
+
+// Defaults of the attachment settings read from the environment.
+const (
+	DefaultMaxAttachmentSize = 10 << 20
+	DefaultAttachmentTypes   = "image/png,image/jpeg,image/gif,image/webp,text/plain,text/csv,application/pdf,application/json,application/zip,application/gzip"
+	DefaultBlobGracePeriod   = time.Hour
+)
+
+// MaxAttachmentNameLength is the maximum length of the file name of an attachment, in characters.
+const MaxAttachmentNameLength = 255
+
+// ErrAttachmentNotFound is returned when an attachment does not exist.
+var ErrAttachmentNotFound = errors.New("Attachment not found")
+
+// Attachment describes a file attached to a task.
+type Attachment struct {
+	ID          string    `json:"id"`           // ID of the attachment, ordered by upload time
+	TaskID      string    `json:"task_id"`      // ID of the task the file is attached to
+	Name        string    `json:"name"`         // File name
+	ContentType string    `json:"content_type"` // Media type of the content
+	Size        int64     `json:"size"`         // Size of the content in bytes
+	SHA256      string    `json:"sha256"`       // Hex SHA-256 of the content, its key in the blob store
+	UploadedBy  string    `json:"uploaded_by"`  // Who attached the file
+	CreatedAt   time.Time `json:"created_at"`   // When the file was attached
+}
+
+// AttachmentKeyPrefix returns the prefix of the keys of the attachments of a task.
+func AttachmentKeyPrefix(taskID string) string {
+	return AttachmentPrefix + taskID + "/"
+}
+
+// AttachmentKey returns the key of an attachment of a task.
+func AttachmentKey(taskID, id string) string {
+	return AttachmentKeyPrefix(taskID) + id
+}
+
+// MaxAttachmentSize returns the maximum size of an attachment in bytes. It is
+// read from the MAX_ATTACHMENT_SIZE environment variable, falling back to
+// DefaultMaxAttachmentSize when unset or invalid.
+func MaxAttachmentSize() int64 {
+	if v := os.Getenv("MAX_ATTACHMENT_SIZE"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return DefaultMaxAttachmentSize
+}
+
+// AttachmentTypes returns the media types files may be attached as. They are
+// read as a comma-separated list from the ATTACHMENT_TYPES environment
+// variable, falling back to DefaultAttachmentTypes. A type like image/*
+// allows all subtypes and * allows any type.
+func AttachmentTypes() []string {
+	v := os.Getenv("ATTACHMENT_TYPES")
+	if strings.TrimSpace(v) == "" {
+		v = DefaultAttachmentTypes
+	}
+	var types []string
+	for _, t := range strings.Split(v, ",") {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// BlobGracePeriod returns how long a blob is kept after it was last written
+// before it may be deleted for not being referenced, which leaves uploads in
+// progress the time to reference it. It is read from the BLOB_GRACE_PERIOD
+// environment variable (e.g. "30m"), falling back to DefaultBlobGracePeriod.
+func BlobGracePeriod() time.Duration {
+	if v := os.Getenv("BLOB_GRACE_PERIOD"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= time.Second {
+			return d
+		}
+	}
+	return DefaultBlobGracePeriod
+}
+
+// allowedType reports whether a media type is one of the allowed ones.
+func allowedType(mediaType string, allowed []string) bool {
+	for _, t := range allowed {
+		switch {
+		case t == "*", t == mediaType:
+			return true
+		case strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*")):
+			return true
+		}
+	}
+	return false
+}
+
+// attachmentType works out the media type of a file from the one declared by
+// the client, or from its first bytes if it declared none or a generic one.
+func attachmentType(declared string, head []byte) (string, error) {
+	if declared == "" || strings.HasPrefix(declared, "application/octet-stream") {
+		declared = http.DetectContentType(head)
+	}
+	mediaType, params, err := mime.ParseMediaType(declared)
+	if err != nil {
+		return "", &ValidationError{Fields: []FieldError{{"content_type", "Content type " + declared + " is invalid"}}}
+	}
+	if !allowedType(mediaType, AttachmentTypes()) {
+		return "", &ValidationError{Fields: []FieldError{{"content_type", "Files of type " + mediaType + " cannot be attached"}}}
+	}
+	if charset, ok := params["charset"]; ok {
+		return mime.FormatMediaType(mediaType, map[string]string{"charset": charset}), nil
+	}
+	return mediaType, nil
+}
+
+// checkAttachmentName checks the file name of an attachment.
+func checkAttachmentName(name string) error {
+	fields := checkText(nil, "name", "Name", name, MaxAttachmentNameLength, false)
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		fields = append(fields, FieldError{"name", "Name cannot be a path"})
+	}
+	return fieldErrors(fields)
+}
+
+// GetAttachments fetches the attachments of a task, oldest first. It fails
+// with ErrNotFound if the task does not exist.
+func (h *Handler) GetAttachments(ctx context.Context, taskID string) ([]Attachment, error) {
+	txn, err := h.Client.Txn(ctx).Then(
+		clientv3.OpGet(TaskPrefix+taskID, clientv3.WithCountOnly()),
+		clientv3.OpGet(AttachmentKeyPrefix(taskID), clientv3.WithPrefix()),
+	).Commit()
+	if err != nil {
+		return nil, err
+	}
+	if txn.Responses[0].GetResponseRange().Count == 0 {
+		return nil, ErrNotFound
+	}
+
+	attachments := []Attachment{}
+	for _, kv := range txn.Responses[1].GetResponseRange().Kvs {
+		var attachment Attachment
+		if err := json.Unmarshal(kv.Value, &attachment); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+// GetAttachment fetches an attachment of a task.
+func (h *Handler) GetAttachment(ctx context.Context, taskID, id string) (*Attachment, error) {
+	resp, err := h.Client.Get(ctx, AttachmentKey(taskID, id))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, ErrAttachmentNotFound
+	}
+
+	var attachment Attachment
+	if err := json.Unmarshal(resp.Kvs[0].Value, &attachment); err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// AddAttachment attaches the file read from r to a task. The content is
+// stored in the blob store under its SHA-256 before the metadata is saved in
+// etcd, provided the task still exists. Files over MaxAttachmentSize fail
+// with an *http.MaxBytesError and files of other types than AttachmentTypes
+// with a ValidationError.
+func (h *Handler) AddAttachment(ctx context.Context, store blob.Store, taskID, name, contentType, uploader string, r io.Reader) (*Attachment, error) {
+	if err := checkAttachmentName(name); err != nil {
+		return nil, err
+	}
+
+	// Spool the file to work out its hash before storing it under it
+	tmp, err := os.CreateTemp("", "attachment-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	limit := MaxAttachmentSize()
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if size > limit {
+		return nil, &http.MaxBytesError{Limit: limit}
+	}
+
+	head := make([]byte, 512)
+	n, err := tmp.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if contentType, err = attachmentType(contentType, head[:n]); err != nil {
+		return nil, err
+	}
+
+	attachment := Attachment{
+		ID:          NewULID(),
+		TaskID:      taskID,
+		Name:        name,
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		UploadedBy:  uploader,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	// Content already stored is written again all the same, which renews it
+	// so that it is not collected before the metadata references it
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := store.Put(ctx, attachment.SHA256, tmp, size); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(attachment)
+	if err != nil {
+		return nil, err
+	}
+	txn, err := h.Client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(TaskPrefix+taskID), ">", 0)).
+		Then(clientv3.OpPut(AttachmentKey(taskID, attachment.ID), string(data))).
+		Commit()
+	if err != nil {
+		return nil, err
+	}
+	if !txn.Succeeded {
+		return nil, ErrNotFound
+	}
+	return &attachment, nil
+}
+
+// DeleteAttachment deletes an attachment of a task, and its content if no
+// other attachment has the same. Contents that cannot be deleted right away
+// are left to CollectBlobs.
+func (h *Handler) DeleteAttachment(ctx context.Context, store blob.Store, taskID, id string) error {
+	resp, err := h.Client.Delete(ctx, AttachmentKey(taskID, id), clientv3.WithPrevKV())
+	if err != nil {
+		return err
+	}
+	if resp.Deleted == 0 {
+		return ErrAttachmentNotFound
+	}
+
+	var attachment Attachment
+	if err := json.Unmarshal(resp.PrevKvs[0].Value, &attachment); err != nil {
+		return err
+	}
+	if err := h.ReleaseBlobs(ctx, store, []string{attachment.SHA256}); err != nil {
+		log.Println("Failed to delete the content of attachment "+id+":", err)
+	}
+	return nil
+}
+
+// AttachmentHashes returns the hashes of the contents of the attachments of
+// a task, or of all tasks if taskID is empty.
+func (h *Handler) AttachmentHashes(ctx context.Context, taskID string) ([]string, error) {
+	prefix := AttachmentPrefix
+	if taskID != "" {
+		prefix = AttachmentKeyPrefix(taskID)
+	}
+	resp, err := h.Client.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	var hashes []string
+	for _, kv := range resp.Kvs {
+		var attachment Attachment
+		if err := json.Unmarshal(kv.Value, &attachment); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, attachment.SHA256)
+	}
+	return hashes, nil
+}
+
+// HashesByTask returns the hashes of the contents of the stored attachments
+// kvs, by the ID of their task.
+func HashesByTask(kvs []*mvccpb.KeyValue) (map[string][]string, error) {
+	hashes := map[string][]string{}
+	for _, kv := range kvs {
+		var attachment Attachment
+		if err := json.Unmarshal(kv.Value, &attachment); err != nil {
+			return nil, err
+		}
+		hashes[attachment.TaskID] = append(hashes[attachment.TaskID], attachment.SHA256)
+	}
+	return hashes, nil
+}
+
+// ReleaseBlobs deletes the given contents from the blob store unless an
+// attachment still has them. Contents written within BlobGracePeriod may be
+// about to be referenced and are left for CollectBlobs.
+func (h *Handler) ReleaseBlobs(ctx context.Context, store blob.Store, hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	used, err := h.AttachmentHashes(ctx, "")
+	if err != nil {
+		return err
+	}
+	referenced := map[string]bool{}
+	for _, hash := range used {
+		referenced[hash] = true
+	}
+
+	cutoff := time.Now().Add(-BlobGracePeriod())
+	for _, hash := range hashes {
+		if referenced[hash] {
+			continue
+		}
+		info, err := store.Stat(ctx, hash)
+		if errors.Is(err, blob.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if info.ModTime.Before(cutoff) {
+			if err := store.Delete(ctx, hash); err != nil {
+				return err
+			}
+		}
+		referenced[hash] = true
+	}
+	return nil
+}
+
+// CollectBlobs cleans up after tasks deleted without their attachments being
+// released, such as expired ones and the ones whose trash entry expired: it
+// deletes the attachments of tasks that are neither stored nor in the trash,
+// then the contents no attachment references that were written before
+// BlobGracePeriod. It returns the number of contents deleted.
+func (h *Handler) CollectBlobs(ctx context.Context, store blob.Store) (int, error) {
+	resp, err := h.Client.Get(ctx, AttachmentPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return 0, err
+	}
+
+	referenced := map[string]bool{}
+	for _, kv := range resp.Kvs {
+		var attachment Attachment
+		if err := json.Unmarshal(kv.Value, &attachment); err != nil {
+			return 0, err
+		}
+		txn, err := h.Client.Txn(ctx).
+			If(
+				clientv3.Compare(clientv3.CreateRevision(TaskPrefix+attachment.TaskID), "=", 0),
+				clientv3.Compare(clientv3.CreateRevision(TrashPrefix+attachment.TaskID), "=", 0),
+			).
+			Then(clientv3.OpDelete(string(kv.Key))).
+			Commit()
+		if err != nil {
+			return 0, err
+		}
+		if !txn.Succeeded {
+			referenced[attachment.SHA256] = true
+		}
+	}
+
+	// Attachments added while listing the blobs are recent enough to be spared
+	cutoff := time.Now().Add(-BlobGracePeriod())
+	deleted := 0
+	err = store.List(ctx, func(info blob.Info) error {
+		if referenced[info.Key] || !info.ModTime.Before(cutoff) {
+			return nil
+		}
+		if err := store.Delete(ctx, info.Key); err != nil {
+			return err
+		}
+		deleted++
+		return nil
+	})
+	return deleted, err
+}
+
+// CollectBlobsEvery runs CollectBlobs at the given interval until ctx is done.
+func (h *Handler) CollectBlobsEvery(ctx context.Context, store blob.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := h.CollectBlobs(ctx, store)
+		if err != nil && ctx.Err() == nil {
+			log.Println("Failed to collect unused attachment contents:", err)
+		} else if n > 0 {
+			log.Printf("Deleted %d unused attachment contents", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"task-organizer/blob"
+	"task-organizer/blob/blobtest"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// testRun tells apart the contents attached by different runs of the tests.
+var testRun = time.Now().UnixNano()
+
+// testHandler returns a handler of the local etcd, skipping the test if it is not running.
+func testHandler(t *testing.T) *Handler {
+	t.Helper()
+	conn, err := net.DialTimeout("tcp", "localhost:2379", time.Second)
+	if err != nil {
+		t.Skip("etcd is not reachable at localhost:2379")
+	}
+	conn.Close()
+
+	h1, h2, err := Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		h1.Client.Close()
+		h2.Client.Close()
+	})
+	return h1
+}
+
+// testStores returns an empty file store and an empty S3 store.
+func testStores(t *testing.T) map[string]blob.Store {
+	files, err := blob.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := blobtest.NewS3Server("blobs")
+	t.Cleanup(server.Close)
+	return map[string]blob.Store{"file": files, "s3": server.Store()}
+}
+
+// createTestTask creates a task expiring in an hour, which is deleted when the test ends.
+func createTestTask(t *testing.T, h *Handler) Task {
+	t.Helper()
+	ctx := context.Background()
+	m, err := h.PrepareCreate(ctx, Task{Title: t.Name(), TTL: 3600}, AuditMeta{Actor: "test"})
+	if err != nil {
+		t.Fatalf("PrepareCreate: %v", err)
+	}
+	if err := h.Apply(ctx, m); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	t.Cleanup(func() {
+		expireTestTask(t, h, m.After.ID)
+		h.Client.Delete(context.Background(), AttachmentKeyPrefix(m.After.ID), clientv3.WithPrefix())
+	})
+	return *m.After
+}
+
+// expireTestTask deletes a task created by createTestTask as if it expired,
+// leaving its attachments behind.
+func expireTestTask(t *testing.T, h *Handler, id string) {
+	t.Helper()
+	ctx := context.Background()
+	resp, err := h.Client.Get(ctx, TaskPrefix+id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) == 0 {
+		return
+	}
+	if _, err := h.Client.Revoke(ctx, clientv3.LeaseID(resp.Kvs[0].Lease)); err != nil {
+		t.Fatalf("expiring task %s: %v", id, err)
+	}
+}
+
+// attach attaches content to a task. The content is made unique to the test
+// run, so that attachments left by other runs do not reference it.
+func attach(t *testing.T, h *Handler, store blob.Store, taskID, content string) *Attachment {
+	t.Helper()
+	content = fmt.Sprintf("%s %s %d", t.Name(), content, testRun)
+	a, err := h.AddAttachment(context.Background(), store, taskID, "notes.txt", "text/plain", "test", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("AddAttachment: %v", err)
+	}
+	return a
+}
+
+// blobExists reports whether a content is in the store.
+func blobExists(t *testing.T, store blob.Store, hash string) bool {
+	t.Helper()
+	_, err := store.Stat(context.Background(), hash)
+	if errors.Is(err, blob.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		t.Fatalf("Stat(%s): %v", hash, err)
+	}
+	return true
+}
+
+// passGracePeriod shortens BlobGracePeriod and waits until the contents
+// written so far are older than it.
+func passGracePeriod(t *testing.T) {
+	t.Setenv("BLOB_GRACE_PERIOD", "1s")
+	// Stores may round modification times down to the second
+	time.Sleep(1100 * time.Millisecond)
+}
+
+func TestAttachmentDedup(t *testing.T) {
+	h := testHandler(t)
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			first := attach(t, h, store, createTestTask(t, h).ID, "same content "+name)
+			second := attach(t, h, store, createTestTask(t, h).ID, "same content "+name)
+			other := attach(t, h, store, first.TaskID, "other content "+name)
+
+			if first.SHA256 != second.SHA256 || first.SHA256 == other.SHA256 {
+				t.Fatalf("hashes %s, %s and %s; want the first two equal", first.SHA256, second.SHA256, other.SHA256)
+			}
+			var keys []string
+			err := store.List(context.Background(), func(info blob.Info) error {
+				keys = append(keys, info.Key)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if len(keys) != 2 {
+				t.Errorf("stored blobs %v, want one per distinct content", keys)
+			}
+
+			r, err := store.Open(context.Background(), second.SHA256)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer r.Close()
+			data, err := io.ReadAll(r)
+			if err != nil || !strings.HasSuffix(string(data), " same content "+name+fmt.Sprintf(" %d", testRun)) {
+				t.Errorf("content = %q, %v", data, err)
+			}
+		})
+	}
+}
+
+func TestAttachmentTaskGone(t *testing.T) {
+	h := testHandler(t)
+	store, err := blob.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := createTestTask(t, h)
+	expireTestTask(t, h, task.ID)
+
+	_, err = h.AddAttachment(context.Background(), store, task.ID, "notes.txt", "text/plain", "test", strings.NewReader("late"))
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("AddAttachment to a missing task: %v, want ErrNotFound", err)
+	}
+}
+
+func TestReleaseBlobs(t *testing.T) {
+	h := testHandler(t)
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			first := attach(t, h, store, createTestTask(t, h).ID, "shared "+name)
+			second := attach(t, h, store, createTestTask(t, h).ID, "shared "+name)
+
+			// A content written within the grace period is kept even if unreferenced
+			if err := h.DeleteAttachment(ctx, store, first.TaskID, first.ID); err != nil {
+				t.Fatalf("DeleteAttachment: %v", err)
+			}
+			if err := h.DeleteAttachment(ctx, store, second.TaskID, second.ID); err != nil {
+				t.Fatalf("DeleteAttachment: %v", err)
+			}
+			if !blobExists(t, store, first.SHA256) {
+				t.Fatal("content within the grace period was deleted")
+			}
+
+			first = attach(t, h, store, first.TaskID, "shared "+name)
+			second = attach(t, h, store, second.TaskID, "shared "+name)
+			passGracePeriod(t)
+
+			// A content still referenced by another attachment is kept
+			if err := h.DeleteAttachment(ctx, store, first.TaskID, first.ID); err != nil {
+				t.Fatalf("DeleteAttachment: %v", err)
+			}
+			if !blobExists(t, store, first.SHA256) {
+				t.Fatal("content still attached to a task was deleted")
+			}
+
+			// The last attachment releases it
+			if err := h.DeleteAttachment(ctx, store, second.TaskID, second.ID); err != nil {
+				t.Fatalf("DeleteAttachment: %v", err)
+			}
+			if blobExists(t, store, first.SHA256) {
+				t.Error("content of no attachment was kept")
+			}
+			if err := h.DeleteAttachment(ctx, store, second.TaskID, second.ID); !errors.Is(err, ErrAttachmentNotFound) {
+				t.Errorf("second DeleteAttachment: %v, want ErrAttachmentNotFound", err)
+			}
+		})
+	}
+}
+
+func TestCollectBlobs(t *testing.T) {
+	h := testHandler(t)
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			kept := attach(t, h, store, createTestTask(t, h).ID, "kept "+name)
+			expired := attach(t, h, store, createTestTask(t, h).ID, "expired "+name)
+			orphan := "0000orphan"
+			if err := store.Put(ctx, orphan, strings.NewReader("orphan"), 6); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			expireTestTask(t, h, expired.TaskID)
+			passGracePeriod(t)
+
+			// A content written after the grace period started is spared
+			recent := "0000recent"
+			if err := store.Put(ctx, recent, strings.NewReader("recent"), 6); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			n, err := h.CollectBlobs(ctx, store)
+			if err != nil {
+				t.Fatalf("CollectBlobs: %v", err)
+			}
+			if n != 2 {
+				t.Errorf("CollectBlobs deleted %d contents, want 2", n)
+			}
+			for hash, want := range map[string]bool{kept.SHA256: true, expired.SHA256: false, orphan: false, recent: true} {
+				if got := blobExists(t, store, hash); got != want {
+					t.Errorf("content %s exists = %v, want %v", hash, got, want)
+				}
+			}
+			if _, err := h.GetAttachment(ctx, expired.TaskID, expired.ID); !errors.Is(err, ErrAttachmentNotFound) {
+				t.Errorf("attachment of the expired task: %v, want ErrAttachmentNotFound", err)
+			}
+			if _, err := h.GetAttachment(ctx, kept.TaskID, kept.ID); err != nil {
+				t.Errorf("attachment of the live task: %v", err)
+			}
+		})
+	}
+}
+
+func TestAttachmentsSurviveTrash(t *testing.T) {
+	h := testHandler(t)
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			task := createTestTask(t, h)
+			attached := attach(t, h, store, task.ID, "trashed "+name)
+
+			lease, err := h.Client.Grant(ctx, 600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { h.Client.Revoke(context.Background(), lease.ID) })
+			m, err := h.PrepareDelete(ctx, task.ID, lease.ID, AuditMeta{Actor: "test"})
+			if err != nil {
+				t.Fatalf("PrepareDelete: %v", err)
+			}
+			if err := h.Apply(ctx, m); err != nil {
+				t.Fatalf("Apply delete: %v", err)
+			}
+
+			// Collecting leaves the attachments of tasks in the trash alone
+			passGracePeriod(t)
+			if _, err := h.CollectBlobs(ctx, store); err != nil {
+				t.Fatalf("CollectBlobs: %v", err)
+			}
+			if !blobExists(t, store, attached.SHA256) {
+				t.Fatal("content of a trashed task was collected")
+			}
+
+			resp, err := h.Client.Get(ctx, TrashPrefix+task.ID)
+			if err != nil || len(resp.Kvs) == 0 {
+				t.Fatalf("trash entry: %v", err)
+			}
+			m, err = h.PrepareRestore(ctx, resp.Kvs[0], AuditMeta{Actor: "test"})
+			if err != nil {
+				t.Fatalf("PrepareRestore: %v", err)
+			}
+			if err := h.Apply(ctx, m); err != nil {
+				t.Fatalf("Apply restore: %v", err)
+			}
+
+			attachments, err := h.GetAttachments(ctx, task.ID)
+			if err != nil {
+				t.Fatalf("GetAttachments: %v", err)
+			}
+			if len(attachments) != 1 || attachments[0].ID != attached.ID || attachments[0].SHA256 != attached.SHA256 {
+				t.Errorf("attachments after restore = %+v, want %+v", attachments, attached)
+			}
+			if !blobExists(t, store, attached.SHA256) {
+				t.Error("content of the restored attachment is gone")
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// AuditPrefix is the etcd key prefix under which audit entries are stored.
+const AuditPrefix = "audit/"
+
+// Audit actions recorded for task mutations.
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRevert  = "revert"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+// Change describes a single field that differs between two versions of a task.
+type Change struct {
+	Field  string      `json:"field"`            // Name of the changed field (JSON name)
+	Before interface{} `json:"before,omitempty"` // Value before the mutation
+	After  interface{} `json:"after,omitempty"`  // Value after the mutation
+}
+
+// AuditEntry represents an immutable record of a single task mutation.
+type AuditEntry struct {
+	ID        string    `json:"id"`               // ID of the audit entry
+	Action    string    `json:"action"`           // Kind of mutation (create, update, delete, revert, restore, purge)
+	TaskID    string    `json:"task_id"`          // ID of the mutated task
+	Actor     string    `json:"actor"`            // Who performed the mutation, as claimed by the unverified X-Actor header
+	RequestID string    `json:"request_id"`       // ID of the HTTP request that caused the mutation
+	Timestamp time.Time `json:"timestamp"`        // When the mutation happened
+	Before    *Task     `json:"before,omitempty"` // Task before the mutation (nil on create)
+	After     *Task     `json:"after,omitempty"`  // Task after the mutation (nil on delete)
+	Changes   []Change  `json:"changes"`          // Field level diff between Before and After
+}
+
+// NewAuditEntry creates an audit entry for a mutation turning before into after.
+// Either before or after may be nil for creations and deletions respectively.
+func NewAuditEntry(action, actor, requestID string, before, after *Task) (*AuditEntry, error) {
+	changes, err := Diff(before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &AuditEntry{
+		ID:        GenerateUniqueID(),
+		Action:    action,
+		Actor:     actor,
+		RequestID: requestID,
+		Timestamp: time.Now().UTC(),
+		Before:    before,
+		After:     after,
+		Changes:   changes,
+	}
+	if after != nil {
+		entry.TaskID = after.ID
+	} else if before != nil {
+		entry.TaskID = before.ID
+	}
+	return entry, nil
+}
+
+// Limits on the number of audit entries read at once.
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 1000
+)
+
+// AuditQuery selects a page of audit entries.
+type AuditQuery struct {
+	Task  string    // Only entries for this task ID, if set
+	Actor string    // Only entries made by this actor, if set
+	From  time.Time // Only entries at or after this time, if set
+	To    time.Time // Only entries before this time, if set
+	After string    // Continuation key returned by the previous page
+	Limit int       // Number of entries to read
+}
+
+// AuditLog is a page of audit entries, oldest first.
+type AuditLog struct {
+	Entries []AuditEntry `json:"entries"`        // Entries of the page matching the filters
+	Next    string       `json:"next,omitempty"` // Key to pass as after to read the next page; empty at the end of the log
+}
+
+// GetAuditLog reads the page of the audit log following q.After, or the
+// first one, filtering the entries by task and actor. The page covers up to
+// q.Limit entries of the time range, which defaults to DefaultAuditLimit and
+// is capped at MaxAuditLimit; filtered out entries count towards it, so a
+// page may hold fewer entries, or none, while Next is set.
+func (h *Handler) GetAuditLog(ctx context.Context, q AuditQuery) (*AuditLog, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultAuditLimit
+	}
+	if q.Limit > MaxAuditLimit {
+		q.Limit = MaxAuditLimit
+	}
+
+	// Translate the time range into a key range; audit keys sort by time
+	start := AuditPrefix
+	end := clientv3.GetPrefixRangeEnd(AuditPrefix)
+	if !q.From.IsZero() {
+		start = AuditTimeKey(q.From)
+	}
+	if !q.To.IsZero() {
+		end = AuditTimeKey(q.To)
+	}
+	if q.After != "" {
+		if !strings.HasPrefix(q.After, AuditPrefix) {
+			return nil, fieldErrors([]FieldError{{"after", "Not a key returned as next"}})
+		}
+		if after := q.After + "\x00"; after > start {
+			start = after
+		}
+	}
+
+	log := &AuditLog{Entries: []AuditEntry{}}
+	if start >= end {
+		return log, nil
+	}
+	resp, err := h.Client.Get(ctx, start, clientv3.WithRange(end), clientv3.WithLimit(int64(q.Limit)))
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range resp.Kvs {
+		var entry AuditEntry
+		if err := json.Unmarshal(kv.Value, &entry); err != nil {
+			return nil, err
+		}
+		if q.Task != "" && entry.TaskID != q.Task {
+			continue
+		}
+		if q.Actor != "" && entry.Actor != q.Actor {
+			continue
+		}
+		log.Entries = append(log.Entries, entry)
+	}
+	if resp.More {
+		log.Next = string(resp.Kvs[len(resp.Kvs)-1].Key)
+	}
+	return log, nil
+}
+
+// AuditKey returns the etcd key for an audit entry written at ts.
+// Keys sort by time so that a time range maps onto a key range.
+func AuditKey(ts time.Time, id string) string {
+	return fmt.Sprintf("%s%020d/%s", AuditPrefix, ts.UnixNano(), id)
+}
+
+// AuditTimeKey returns the smallest audit key for entries written at or after ts.
+func AuditTimeKey(ts time.Time) string {
+	return fmt.Sprintf("%s%020d", AuditPrefix, ts.UnixNano())
+}
+
+// Key returns the etcd key of the audit entry.
+func (e *AuditEntry) Key() string {
+	return AuditKey(e.Timestamp, e.ID)
+}
+
+// Cmp returns the transaction guard ensuring the entry is never overwritten.
+func (e *AuditEntry) Cmp() clientv3.Cmp {
+	return clientv3.Compare(clientv3.CreateRevision(e.Key()), "=", 0)
+}
+
+// Op returns the etcd operation that stores the audit entry.
+func (e *AuditEntry) Op() (clientv3.Op, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return clientv3.Op{}, err
+	}
+	return clientv3.OpPut(e.Key(), string(data)), nil
+}
+
+// Diff compares two values field by field using their JSON representation
+// and returns the changed fields sorted by name. Nil values are treated as empty.
+func Diff(before, after interface{}) ([]Change, error) {
+	b, err := toFields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := toFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []Change{}
+	for field, bv := range b {
+		if av, ok := a[field]; !ok || !reflect.DeepEqual(av, bv) {
+			changes = append(changes, Change{Field: field, Before: bv, After: a[field]})
+		}
+	}
+	for field, av := range a {
+		if _, ok := b[field]; !ok {
+			changes = append(changes, Change{Field: field, After: av})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// toFields converts a value into a map of its JSON fields.
+func toFields(v interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
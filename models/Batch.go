@@ -0,0 +1,177 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Batch operation kinds.
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// MaxBatchOperations is the maximum number of operations of a batch request.
+const MaxBatchOperations = 100
+
+// ErrNotExecuted is reported for batch operations that were not applied because another one failed.
+var ErrNotExecuted = errors.New("Operation not executed because another operation failed")
+
+// BatchOp represents a single create, update or delete operation of a batch request.
+type BatchOp struct {
+	Op     string     `json:"op"`               // Kind of operation (create, update, delete)
+	ID     string     `json:"id,omitempty"`     // ID of the task to update or delete
+	Task   *Task      `json:"task,omitempty"`   // Task to create
+	Update *UpdateReq `json:"update,omitempty"` // Fields of the task to update
+}
+
+// BatchReq represents a request applying several operations at once.
+type BatchReq struct {
+	Atomic     bool      `json:"atomic"`     // Apply either all operations or none of them
+	Operations []BatchOp `json:"operations"` // Operations to apply, in order
+}
+
+// BatchResult reports the outcome of a single batch operation.
+type BatchResult struct {
+	Index  int          `json:"index"`            // Position of the operation in the request
+	Op     string       `json:"op"`               // Kind of operation
+	ID     string       `json:"id,omitempty"`     // ID of the affected task
+	Status int          `json:"status"`           // HTTP status of the operation
+	Task   *Task        `json:"task,omitempty"`   // Resulting task for creates and updates
+	Error  string       `json:"error,omitempty"`  // Why the operation failed
+	Fields []FieldError `json:"fields,omitempty"` // Invalid fields of the operation
+}
+
+// BatchResp represents the outcome of a batch request.
+type BatchResp struct {
+	Succeeded int           `json:"succeeded"` // Number of applied operations
+	Failed    int           `json:"failed"`    // Number of operations not applied
+	Results   []BatchResult `json:"results"`   // Outcome of each operation, in request order
+}
+
+// ApplyBatch commits prepared mutations and returns the error of each
+// mutation (nil if applied).
+//
+// In atomic mode the mutations are committed in a single transaction, all or
+// nothing; mutations not fitting in one fail with the error of CheckAtomic
+// without being tried. Otherwise they are split in as few transactions as the
+// etcd operation limit allows, and a failing transaction is retried one
+// mutation at a time, so only the failing ones are lost.
+func (h *Handler) ApplyBatch(ctx context.Context, muts []*Mutation, atomic bool) []error {
+	errs := make([]error, len(muts))
+	if atomic {
+		err := CheckAtomic(muts)
+		if err == nil {
+			_, err = h.Commit(ctx, muts...)
+		}
+		h.Release(ctx, err == nil, muts...)
+		if err != nil {
+			for i, m := range muts {
+				errs[i] = h.mutationError(ctx, m, err)
+			}
+		}
+		return errs
+	}
+
+	for _, chunk := range chunkMutations(muts, MaxTxnOps()) {
+		batch := make([]*Mutation, len(chunk))
+		for i, idx := range chunk {
+			batch[i] = muts[idx]
+		}
+
+		_, err := h.Commit(ctx, batch...)
+		if err == nil {
+			h.Release(ctx, true, batch...)
+			continue
+		}
+
+		// Find out which of the mutations failed by applying them one by one
+		if len(batch) == 1 {
+			h.Release(ctx, false, batch...)
+			errs[chunk[0]] = h.mutationError(ctx, batch[0], err)
+			continue
+		}
+		for i, m := range batch {
+			errs[chunk[i]] = h.Apply(ctx, m)
+		}
+	}
+	return errs
+}
+
+// CheckAtomic fails with a ValidationError if the mutations do not fit in a
+// single etcd transaction, which applying them atomically requires.
+func CheckAtomic(muts []*Mutation) error {
+	size := 0
+	for _, m := range muts {
+		size += m.Size()
+	}
+	if size > MaxTxnOps() {
+		return &ValidationError{Msg: fmt.Sprintf("Batch needs %d of the %d operations of a transaction to apply atomically; split it or send it without atomic", size, MaxTxnOps())}
+	}
+	return nil
+}
+
+// chunkMutations splits mutations, by index, into groups fitting in one transaction.
+func chunkMutations(muts []*Mutation, limit int) [][]int {
+	var chunks [][]int
+	var chunk []int
+	size := 0
+	for i, m := range muts {
+		if len(chunk) > 0 && size+m.Size() > limit {
+			chunks = append(chunks, chunk)
+			chunk, size = nil, 0
+		}
+		chunk = append(chunk, i)
+		size += m.Size()
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// mutationError tells apart why a mutation did not apply: a label it adds was
+// deleted in the meantime, the workflow it was checked against changed, on a
+// deletion a task with the same ID is in the trash, or else on a creation the
+// task already exists.
+func (h *Handler) mutationError(ctx context.Context, m *Mutation, err error) error {
+	if !errors.Is(err, ErrConflict) {
+		return err
+	}
+	if len(m.labels) > 0 {
+		exist, lerr := h.labelsExist(ctx, m.labels)
+		if lerr != nil {
+			return err
+		}
+		var gone []string
+		for i, id := range m.labels {
+			if !exist[i] {
+				gone = append(gone, id)
+			}
+		}
+		if len(gone) > 0 {
+			return fmt.Errorf("%w: %s", ErrLabelGone, strings.Join(gone, ", "))
+		}
+	}
+	if m.workflow != nil {
+		txn, werr := h.Client.Txn(ctx).If(*m.workflow).Commit()
+		if werr == nil && !txn.Succeeded {
+			return ErrWorkflowChanged
+		}
+	}
+	if m.After == nil {
+		resp, terr := h.Client.Get(ctx, TrashPrefix+m.Before.ID, clientv3.WithCountOnly())
+		if terr == nil && resp.Count > 0 {
+			return ErrInTrash
+		}
+	}
+	if m.Before == nil {
+		return ErrExists
+	}
+	return err
+}
@@ -0,0 +1,293 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// CommentPrefix is the etcd key prefix under which comments are stored. The
+// comments of a task are kept under comments/<task ID>/, one key per comment,
+// while the task is in the task list or in the trash. They are deleted when
+// it is purged, or by CollectComments once it expired.
+const CommentPrefix = "comments/"
+
+// Limits on comments.
+const (
+	MaxCommentLength = 10000 // Characters of a comment body
+	MaxCommentEdits  = 50    // Previous versions kept in the edit history; older ones are dropped
+	MaxMentionLength = 100   // Characters of a mentioned name
+)
+
+// ErrCommentNotFound is returned when a comment does not exist.
+var ErrCommentNotFound = errors.New("Comment not found")
+
+// Comment is a message posted on a task.
+type Comment struct {
+	ID        string        `json:"id"`                 // ID of the comment, ordered by creation time
+	TaskID    string        `json:"task_id"`            // ID of the task commented on
+	Author    string        `json:"author"`             // Who posted the comment
+	Body      string        `json:"body"`               // Text of the comment
+	Mentions  []string      `json:"mentions,omitempty"` // Names mentioned in the body as @name, in order of appearance
+	CreatedAt time.Time     `json:"created_at"`         // When the comment was posted
+	UpdatedAt time.Time     `json:"updated_at"`         // When the comment was last edited
+	Edits     []CommentEdit `json:"edits,omitempty"`    // Previous versions of the body, oldest first
+}
+
+// CommentEdit is a previous version of the body of a comment.
+type CommentEdit struct {
+	Body     string    `json:"body"`      // Body before the edit
+	EditedBy string    `json:"edited_by"` // Who edited the comment
+	EditedAt time.Time `json:"edited_at"` // When the body was replaced
+}
+
+// CommentReq represents a request to post or edit a comment.
+type CommentReq struct {
+	Body string `json:"body"` // Text of the comment; @name mentions a person
+}
+
+// CommentKeyPrefix returns the prefix of the keys of the comments of a task.
+func CommentKeyPrefix(taskID string) string {
+	return CommentPrefix + taskID + "/"
+}
+
+// CommentKey returns the key of a comment of a task.
+func CommentKey(taskID, id string) string {
+	return CommentKeyPrefix(taskID) + id
+}
+
+// ValidateComment checks the body of a comment.
+func ValidateComment(req CommentReq) error {
+	var fields []FieldError
+	if strings.TrimSpace(req.Body) == "" {
+		fields = append(fields, FieldError{"body", "Body cannot be empty"})
+	}
+	fields = checkText(fields, "body", "Body", req.Body, MaxCommentLength, true)
+	return fieldErrors(fields)
+}
+
+// ParseMentions returns the names mentioned in a text as @name, once each in
+// order of appearance. Names are made of letters, digits, _, - and inner dots;
+// an @ following a letter or digit, as in an email address, is not a mention.
+func ParseMentions(text string) []string {
+	isName := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+	}
+
+	var mentions []string
+	seen := map[string]bool{}
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '@' || (i > 0 && isName(runes[i-1])) {
+			continue
+		}
+		j := i + 1
+		for j < len(runes) && isName(runes[j]) {
+			j++
+		}
+		name := strings.TrimRight(string(runes[i+1:j]), ".")
+		if name != "" && len([]rune(name)) <= MaxMentionLength && !seen[name] {
+			seen[name] = true
+			mentions = append(mentions, name)
+		}
+		i = j - 1
+	}
+	return mentions
+}
+
+// GetComments fetches the comments of a task, oldest first. It fails with
+// ErrNotFound if the task does not exist.
+func (h *Handler) GetComments(ctx context.Context, taskID string) ([]Comment, error) {
+	txn, err := h.Client.Txn(ctx).Then(
+		clientv3.OpGet(TaskPrefix+taskID, clientv3.WithCountOnly()),
+		clientv3.OpGet(CommentKeyPrefix(taskID), clientv3.WithPrefix()),
+	).Commit()
+	if err != nil {
+		return nil, err
+	}
+	if txn.Responses[0].GetResponseRange().Count == 0 {
+		return nil, ErrNotFound
+	}
+
+	comments := []Comment{}
+	for _, kv := range txn.Responses[1].GetResponseRange().Kvs {
+		var comment Comment
+		if err := json.Unmarshal(kv.Value, &comment); err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, nil
+}
+
+// GetComment fetches a comment of a task and the etcd revision it was last modified at.
+func (h *Handler) GetComment(ctx context.Context, taskID, id string) (*Comment, int64, error) {
+	resp, err := h.Client.Get(ctx, CommentKey(taskID, id))
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, 0, ErrCommentNotFound
+	}
+
+	var comment Comment
+	if err := json.Unmarshal(resp.Kvs[0].Value, &comment); err != nil {
+		return nil, 0, err
+	}
+	return &comment, resp.Kvs[0].ModRevision, nil
+}
+
+// AddComment posts a comment on a task. It fails with ErrNotFound if the
+// task does not exist, including when it is deleted concurrently.
+func (h *Handler) AddComment(ctx context.Context, taskID, author string, req CommentReq) (*Comment, error) {
+	if err := ValidateComment(req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	comment := Comment{
+		ID:        NewULID(),
+		TaskID:    taskID,
+		Author:    author,
+		Body:      req.Body,
+		Mentions:  ParseMentions(req.Body),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	data, err := json.Marshal(comment)
+	if err != nil {
+		return nil, err
+	}
+
+	// The comment is only stored while the task exists, so that it is not left
+	// behind by a delete removing the comments of the task
+	txn, err := h.Client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(TaskPrefix+taskID), ">", 0)).
+		Then(clientv3.OpPut(CommentKey(taskID, comment.ID), string(data))).
+		Commit()
+	if err != nil {
+		return nil, err
+	}
+	if !txn.Succeeded {
+		return nil, ErrNotFound
+	}
+	return &comment, nil
+}
+
+// EditComment replaces the body of a comment, keeping the previous one in its
+// edit history. It fails with ErrConflict if the comment changes concurrently.
+func (h *Handler) EditComment(ctx context.Context, taskID, id, editor string, req CommentReq) (*Comment, error) {
+	if err := ValidateComment(req); err != nil {
+		return nil, err
+	}
+
+	comment, rev, err := h.GetComment(ctx, taskID, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Body == comment.Body {
+		return comment, nil
+	}
+
+	now := time.Now().UTC()
+	comment.Edits = append(comment.Edits, CommentEdit{Body: comment.Body, EditedBy: editor, EditedAt: now})
+	if n := len(comment.Edits); n > MaxCommentEdits {
+		comment.Edits = comment.Edits[n-MaxCommentEdits:]
+	}
+	comment.Body = req.Body
+	comment.Mentions = ParseMentions(req.Body)
+	comment.UpdatedAt = now
+
+	data, err := json.Marshal(comment)
+	if err != nil {
+		return nil, err
+	}
+	key := CommentKey(taskID, id)
+	txn, err := h.Client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", rev)).
+		Then(clientv3.OpPut(key, string(data))).
+		Commit()
+	if err != nil {
+		return nil, err
+	}
+	if !txn.Succeeded {
+		return nil, ErrConflict
+	}
+	return comment, nil
+}
+
+// DeleteComment deletes a comment of a task.
+func (h *Handler) DeleteComment(ctx context.Context, taskID, id string) error {
+	resp, err := h.Client.Delete(ctx, CommentKey(taskID, id))
+	if err != nil {
+		return err
+	}
+	if resp.Deleted == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+// CollectComments deletes the comments of tasks that are neither in the task
+// list nor in the trash, as left behind by expiring tasks and by the trash
+// lease purging deleted ones. It returns the number of tasks whose comments
+// were deleted.
+func (h *Handler) CollectComments(ctx context.Context) (int, error) {
+	resp, err := h.Client.Get(ctx, CommentPrefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	seen := map[string]bool{}
+	for _, kv := range resp.Kvs {
+		taskID, _, ok := strings.Cut(strings.TrimPrefix(string(kv.Key), CommentPrefix), "/")
+		if !ok || seen[taskID] {
+			continue
+		}
+		seen[taskID] = true
+
+		// Comments are only added while their task exists, so none can be
+		// added once both guards hold
+		txn, err := h.Client.Txn(ctx).
+			If(
+				clientv3.Compare(clientv3.CreateRevision(TaskPrefix+taskID), "=", 0),
+				clientv3.Compare(clientv3.CreateRevision(TrashPrefix+taskID), "=", 0),
+			).
+			Then(clientv3.OpDelete(CommentKeyPrefix(taskID), clientv3.WithPrefix())).
+			Commit()
+		if err != nil {
+			return deleted, err
+		}
+		if txn.Succeeded {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// CollectCommentsEvery runs CollectComments at the given interval until ctx is done.
+func (h *Handler) CollectCommentsEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := h.CollectComments(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Println("Failed to collect the comments of deleted tasks:", err)
+		} else if n > 0 {
+			log.Printf("Deleted the comments of %d tasks", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// FilterPrefix is the etcd key prefix under which saved filters are stored.
+const FilterPrefix = "filters/"
+
+// ErrFilterNotFound is returned when a saved filter does not exist.
+var ErrFilterNotFound = errors.New("Filter not found")
+
+// SavedFilter represents a named filter expression for listing tasks.
+type SavedFilter struct {
+	Name      string    `json:"name"`       // Name of the filter
+	Query     string    `json:"query"`      // Filter expression
+	CreatedAt time.Time `json:"created_at"` // When the filter was first saved
+	UpdatedAt time.Time `json:"updated_at"` // When the filter was last saved
+}
+
+// FilterReq represents a request to save a filter expression under a name.
+type FilterReq struct {
+	Query string `json:"query"` // Filter expression
+}
+
+// GetSavedFilter fetches a saved filter by name.
+func (h *Handler) GetSavedFilter(ctx context.Context, name string) (*SavedFilter, error) {
+	resp, err := h.Client.Get(ctx, FilterPrefix+name)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, ErrFilterNotFound
+	}
+
+	var filter SavedFilter
+	if err := json.Unmarshal(resp.Kvs[0].Value, &filter); err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// TaskVersion represents a past version of a task as stored at an etcd revision.
+type TaskVersion struct {
+	Revision  int64     `json:"revision"`  // etcd revision at which this version was written
+	Timestamp time.Time `json:"timestamp"` // When this version was written
+	Task      Task      `json:"task"`      // The task as of this revision
+	Changes   []Change  `json:"changes"`   // Diff against the previous version
+}
+
+// Limits on the number of versions of a task returned at once.
+const (
+	DefaultHistoryLimit = 20
+	MaxHistoryLimit     = 100
+)
+
+// TaskHistory lists the versions of a task, newest first.
+type TaskHistory struct {
+	TaskID    string        `json:"task_id"`        // ID of the task
+	Versions  []TaskVersion `json:"versions"`       // Known versions, newest first
+	Compacted bool          `json:"compacted"`      // True if older versions were removed by etcd compaction
+	Next      int64         `json:"next,omitempty"` // Revision to pass as before to list older versions; 0 if there are none
+}
+
+// GetTaskHistory walks back through the revisions of a task kept by etcd,
+// collecting up to limit versions written before revision before, or the
+// latest ones if before is 0, each diffed against the one preceding it. The
+// limit defaults to DefaultHistoryLimit and is capped at MaxHistoryLimit.
+func (h *Handler) GetTaskHistory(ctx context.Context, id string, limit int, before int64) (*TaskHistory, error) {
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+	if limit > MaxHistoryLimit {
+		limit = MaxHistoryLimit
+	}
+
+	key := TaskPrefix + id
+	resp, err := h.Client.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	history := &TaskHistory{TaskID: id, Versions: []TaskVersion{}}
+
+	// Start from the version current just before the requested revision
+	kv := resp.Kvs[0]
+	if before > 0 && before <= kv.ModRevision {
+		prev, err := h.Client.Get(ctx, key, clientv3.WithRev(before-1))
+		if errors.Is(err, rpctypes.ErrCompacted) {
+			history.Compacted = true
+			return history, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(prev.Kvs) == 0 {
+			return history, nil
+		}
+		kv = prev.Kvs[0]
+	}
+
+	// Walk back through the revisions of the key until its creation,
+	// or until etcd has compacted the older revisions away. One version
+	// beyond the limit is read to diff the oldest returned one against.
+	for {
+		var task Task
+		if err := json.Unmarshal(kv.Value, &task); err != nil {
+			return nil, err
+		}
+		history.Versions = append(history.Versions, TaskVersion{
+			Revision:  kv.ModRevision,
+			Timestamp: task.UpdatedAt,
+			Task:      task,
+		})
+
+		// Version 1 is the revision that created the key
+		if kv.Version <= 1 || len(history.Versions) > limit {
+			break
+		}
+
+		prev, err := h.Client.Get(ctx, key, clientv3.WithRev(kv.ModRevision-1))
+		if errors.Is(err, rpctypes.ErrCompacted) {
+			history.Compacted = true
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(prev.Kvs) == 0 {
+			break
+		}
+		kv = prev.Kvs[0]
+	}
+
+	// Diff every version against the one preceding it
+	for i := range history.Versions {
+		var before *Task
+		if i+1 < len(history.Versions) {
+			before = &history.Versions[i+1].Task
+		}
+		changes, err := Diff(before, &history.Versions[i].Task)
+		if err != nil {
+			return nil, err
+		}
+		history.Versions[i].Changes = changes
+	}
+	if len(history.Versions) > limit {
+		history.Versions = history.Versions[:limit]
+		history.Next = history.Versions[limit-1].Revision
+	}
+	return history, nil
+}
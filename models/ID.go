@@ -0,0 +1,223 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Strategies for generating the IDs of new tasks.
+const (
+	IDStrategyUUIDv4   = "uuidv4"   // Random UUIDs
+	IDStrategyUUIDv7   = "uuidv7"   // Time-ordered UUIDs, listed in creation order
+	IDStrategyULID     = "ulid"     // Time-ordered ULIDs, listed in creation order
+	IDStrategySequence = "sequence" // Short IDs like TASK-123 numbered by an etcd counter
+)
+
+// IDStrategies lists the valid ID strategies.
+var IDStrategies = []string{IDStrategyUUIDv4, IDStrategyUUIDv7, IDStrategyULID, IDStrategySequence}
+
+// DefaultIDPrefix prefixes sequence IDs when ID_PREFIX is not set.
+const DefaultIDPrefix = "TASK"
+
+// CounterPrefix is the etcd prefix of the counters numbering sequence IDs.
+const CounterPrefix = "counters/"
+
+// taskCounterKey holds the last number given to a task by the sequence strategy.
+const taskCounterKey = CounterPrefix + "tasks"
+
+// counterAttempts bounds the retries of a counter increment racing with others.
+const counterAttempts = 10
+
+// IDStrategy returns the strategy used to generate the IDs of new tasks. It is
+// read from the ID_STRATEGY environment variable, falling back to
+// IDStrategyUUIDv4 when unset or invalid.
+func IDStrategy() string {
+	v := strings.ToLower(os.Getenv("ID_STRATEGY"))
+	for _, s := range IDStrategies {
+		if v == s {
+			return s
+		}
+	}
+	return IDStrategyUUIDv4
+}
+
+// IDPrefix returns the prefix of sequence IDs, read from the ID_PREFIX
+// environment variable and falling back to DefaultIDPrefix.
+func IDPrefix() string {
+	if v := os.Getenv("ID_PREFIX"); v != "" && !strings.Contains(v, "/") {
+		return v
+	}
+	return DefaultIDPrefix
+}
+
+// NewTaskID generates the ID of a new task with the configured strategy.
+func (h *Handler) NewTaskID(ctx context.Context) (string, error) {
+	switch IDStrategy() {
+	case IDStrategyUUIDv7:
+		return NewUUIDv7(), nil
+	case IDStrategyULID:
+		return NewULID(), nil
+	case IDStrategySequence:
+		// Numbers already taken by imported or upserted tasks are skipped
+		for attempt := 0; attempt < counterAttempts; attempt++ {
+			n, err := h.nextSequence(ctx)
+			if err != nil {
+				return "", err
+			}
+			id := IDPrefix() + "-" + strconv.FormatInt(n, 10)
+			resp, err := h.Client.Get(ctx, TaskPrefix+id, clientv3.WithCountOnly())
+			if err != nil {
+				return "", err
+			}
+			if resp.Count == 0 {
+				return id, nil
+			}
+		}
+		return "", errors.New("Too many task IDs in sequence are taken")
+	default:
+		return GenerateUniqueID(), nil
+	}
+}
+
+// nextSequence atomically increments the task counter and returns its new value.
+func (h *Handler) nextSequence(ctx context.Context) (int64, error) {
+	for attempt := 0; attempt < counterAttempts; attempt++ {
+		resp, err := h.Client.Get(ctx, taskCounterKey)
+		if err != nil {
+			return 0, err
+		}
+		var n, rev int64
+		if len(resp.Kvs) > 0 {
+			if n, err = strconv.ParseInt(string(resp.Kvs[0].Value), 10, 64); err != nil {
+				return 0, err
+			}
+			rev = resp.Kvs[0].ModRevision
+		}
+
+		n++
+		txn, err := h.Client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(taskCounterKey), "=", rev)).
+			Then(clientv3.OpPut(taskCounterKey, strconv.FormatInt(n, 10))).
+			Commit()
+		if err != nil {
+			return 0, err
+		}
+		if txn.Succeeded {
+			return n, nil
+		}
+	}
+	return 0, errors.New("Task counter is too contended")
+}
+
+// v7 is the state keeping the UUIDv7 generated in one process increasing.
+var v7 struct {
+	sync.Mutex
+	ms  int64  // Timestamp of the last UUID
+	seq uint16 // Counter of the last UUID within its millisecond
+}
+
+// NewUUIDv7 generates a time-ordered UUID (RFC 9562, version 7). UUIDs generated
+// within the same millisecond are ordered by a 12-bit counter.
+func NewUUIDv7() string {
+	var u uuid.UUID
+	rand.Read(u[6:])
+
+	v7.Lock()
+	ms := time.Now().UnixMilli()
+	if ms <= v7.ms {
+		ms = v7.ms
+		if v7.seq++; v7.seq > 0xfff {
+			ms++
+			v7.seq = 0
+		}
+	} else {
+		// Start from a random counter in the lower half, leaving room to increment
+		v7.seq = binary.BigEndian.Uint16(u[6:8]) & 0x7ff
+	}
+	v7.ms = ms
+	seq := v7.seq
+	v7.Unlock()
+
+	putMillis(u[:6], ms)
+	u[6] = 0x70 | byte(seq>>8)
+	u[7] = byte(seq)
+	u[8] = u[8]&0x3f | 0x80
+	return u.String()
+}
+
+// ulid is the state keeping the ULIDs generated in one process increasing.
+var ulid struct {
+	sync.Mutex
+	ms      int64    // Timestamp of the last ULID
+	entropy [10]byte // Random part of the last ULID
+}
+
+// crockford is the Crockford base32 alphabet ULIDs are written in.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID generates a ULID: a 48-bit millisecond timestamp followed by 80
+// random bits, as 26 Crockford base32 characters. ULIDs generated within the
+// same millisecond increment the random part of the previous one.
+func NewULID() string {
+	var b [16]byte
+
+	ulid.Lock()
+	ms := time.Now().UnixMilli()
+	if ms <= ulid.ms && increment(ulid.entropy[:]) {
+		ms = ulid.ms
+	} else {
+		if ms <= ulid.ms {
+			ms = ulid.ms + 1
+		}
+		rand.Read(ulid.entropy[:])
+	}
+	ulid.ms = ms
+	copy(b[6:], ulid.entropy[:])
+	ulid.Unlock()
+	putMillis(b[:6], ms)
+
+	// The 128 bits are read as 130, padded with two leading zero bits
+	bit := func(i int) byte {
+		if i < 0 {
+			return 0
+		}
+		return b[i/8] >> (7 - i%8) & 1
+	}
+	var s [26]byte
+	for i := range s {
+		var v byte
+		for j := 0; j < 5; j++ {
+			v = v<<1 | bit(i*5+j-2)
+		}
+		s[i] = crockford[v]
+	}
+	return string(s[:])
+}
+
+// increment adds one to a big-endian number, reporting false if it overflowed.
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i]++; b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// putMillis writes a millisecond timestamp as 48 big-endian bits.
+func putMillis(b []byte, ms int64) {
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// IdempotencyPrefix is the etcd prefix under which responses to requests
+// sent with an Idempotency-Key are stored.
+const IdempotencyPrefix = "idempotency/"
+
+// DefaultIdempotencyTTL is how long responses are kept when IDEMPOTENCY_TTL is not set.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// MaxIdempotencyKeyLength is the maximum length of an Idempotency-Key, in characters.
+const MaxIdempotencyKeyLength = 255
+
+// ErrIdempotencyMismatch is returned when an Idempotency-Key is reused for a different request.
+var ErrIdempotencyMismatch = errors.New("Idempotency-Key was already used for a different request")
+
+// IdempotentResponse is the stored response to a request sent with an Idempotency-Key.
+type IdempotentResponse struct {
+	Fingerprint string          `json:"fingerprint"` // SHA-256 of the request body
+	Status      int             `json:"status"`      // HTTP status of the response
+	Body        json.RawMessage `json:"body"`        // JSON body of the response
+	CreatedAt   time.Time       `json:"created_at"`  // When the request was first served
+}
+
+// IdempotencyTTL returns how long responses to requests sent with an
+// Idempotency-Key are kept. It is read from the IDEMPOTENCY_TTL environment
+// variable (e.g. "1h"), falling back to DefaultIdempotencyTTL when unset or invalid.
+func IdempotencyTTL() time.Duration {
+	if v := os.Getenv("IDEMPOTENCY_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= time.Second {
+			return d
+		}
+	}
+	return DefaultIdempotencyTTL
+}
+
+// ValidateIdempotencyKey checks an Idempotency-Key sent by a client.
+func ValidateIdempotencyKey(key string) error {
+	if fields := checkText(nil, "Idempotency-Key", "Idempotency-Key", key, MaxIdempotencyKeyLength, false); len(fields) > 0 {
+		return &ValidationError{Msg: fields[0].Message}
+	}
+	return nil
+}
+
+// Fingerprint returns the fingerprint of a request body, compared when an
+// Idempotency-Key is reused.
+func Fingerprint(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// idempotencyKey returns the etcd key of the response to a request, scoped by
+// the operation it was sent to and by the client that sent it. Keys are hashed so that any client key is usable.
+func idempotencyKey(scope, key string) string {
+	sum := sha256.Sum256([]byte(scope + "\x00" + key))
+	return IdempotencyPrefix + hex.EncodeToString(sum[:])
+}
+
+// GetIdempotentResponse fetches the stored response to a request sent to an
+// operation with an Idempotency-Key, nil if there is none.
+func (h *Handler) GetIdempotentResponse(ctx context.Context, scope, key string) (*IdempotentResponse, error) {
+	resp, err := h.Client.Get(ctx, idempotencyKey(scope, key))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+	var stored IdempotentResponse
+	if err := json.Unmarshal(resp.Kvs[0].Value, &stored); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// Remember adds storing the response to a request sent with an Idempotency-Key
+// to a mutation, so that the response is only stored if the mutation applies.
+// The mutation fails with ErrConflict if a response is already stored under the key;
+// the response is kept for IdempotencyTTL.
+func (h *Handler) Remember(ctx context.Context, m *Mutation, scope, key string, resp IdempotentResponse) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	lease, err := h.Client.Grant(ctx, int64(IdempotencyTTL()/time.Second))
+	if err != nil {
+		return err
+	}
+	m.remembered = lease.ID
+
+	k := idempotencyKey(scope, key)
+	m.Cmps = append(m.Cmps, clientv3.Compare(clientv3.CreateRevision(k), "=", 0))
+	m.Ops = append(m.Ops, clientv3.OpPut(k, string(data), clientv3.WithLease(lease.ID)))
+	return nil
+}
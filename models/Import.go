@@ -0,0 +1,35 @@
+package models
+
+// Strategies for imported tasks whose ID is already taken.
+const (
+	ConflictSkip       = "skip"       // Keep the stored task and ignore the imported one
+	ConflictOverwrite  = "overwrite"  // Replace the stored task with the imported one
+	ConflictRegenerate = "regenerate" // Import the task under a new ID
+)
+
+// Outcomes of importing a task.
+const (
+	ImportCreated     = "created"
+	ImportOverwritten = "overwritten"
+	ImportSkipped     = "skipped"
+	ImportFailed      = "failed"
+)
+
+// ImportResult reports the outcome of importing a single row.
+type ImportResult struct {
+	Row    int          `json:"row"`              // Position of the row in the input, from 1
+	ID     string       `json:"id,omitempty"`     // ID the task is stored under
+	Result string       `json:"result"`           // Outcome (created, overwritten, skipped, failed)
+	Error  string       `json:"error,omitempty"`  // Why the row was not imported
+	Fields []FieldError `json:"fields,omitempty"` // Invalid fields of the row
+}
+
+// ImportResp represents the outcome of an import.
+type ImportResp struct {
+	DryRun      bool           `json:"dry_run"`     // Whether the import was only validated
+	Created     int            `json:"created"`     // Number of tasks created
+	Overwritten int            `json:"overwritten"` // Number of stored tasks replaced
+	Skipped     int            `json:"skipped"`     // Number of rows skipped due to ID conflicts
+	Failed      int            `json:"failed"`      // Number of rows that could not be imported
+	Results     []ImportResult `json:"results"`     // Outcome of each row, in input order
+}
@@ -0,0 +1,317 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// IndexPrefix is the etcd key prefix of the secondary indexes over tasks.
+// Index keys have the form index/<field>/<value>/<task ID> and an empty value.
+const IndexPrefix = "index/"
+
+// Indexed task fields.
+const (
+	IndexCompleted = "completed"
+	IndexTag       = "tag"
+	IndexDue       = "due"
+	IndexAssignee  = "assignee"
+	IndexPosition  = "position"
+	IndexStatus    = "status"
+	IndexLabel     = "label"
+)
+
+// dueFormat renders due dates so that their lexicographic order is chronological.
+const dueFormat = "2006-01-02T15:04:05Z"
+
+// IndexKey returns the index key of a task for a field value.
+func IndexKey(field, value, id string) string {
+	return IndexValuePrefix(field, value) + id
+}
+
+// IndexValuePrefix returns the prefix of all index keys of tasks having a field value.
+func IndexValuePrefix(field, value string) string {
+	return IndexPrefix + field + "/" + url.PathEscape(value) + "/"
+}
+
+// DueIndexKey returns the smallest due date index key for tasks due at or after t.
+func DueIndexKey(t time.Time) string {
+	return IndexPrefix + IndexDue + "/" + t.UTC().Format(dueFormat)
+}
+
+// IndexKeys returns the index keys of a task, sorted.
+func IndexKeys(t *Task) []string {
+	if t == nil {
+		return nil
+	}
+
+	keys := []string{IndexKey(IndexCompleted, strconv.FormatBool(t.Completed), t.ID)}
+	for _, tag := range t.Tags {
+		keys = append(keys, IndexKey(IndexTag, tag, t.ID))
+	}
+	for _, label := range t.Labels {
+		keys = append(keys, IndexKey(IndexLabel, label, t.ID))
+	}
+	if t.Due != nil {
+		keys = append(keys, IndexKey(IndexDue, t.Due.UTC().Format(dueFormat), t.ID))
+	}
+	if t.Assignee != "" {
+		keys = append(keys, IndexKey(IndexAssignee, t.Assignee, t.ID))
+	}
+	if t.Status != "" {
+		keys = append(keys, IndexKey(IndexStatus, t.Status, t.ID))
+	}
+	if t.Position != "" {
+		keys = append(keys, IndexKey(IndexPosition, t.Position, t.ID))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// IndexOps returns the operations moving the index entries of a task from its
+// state before to its state after a mutation. The entries of after are attached
+// to lease, so that they expire together with the task.
+func IndexOps(before, after *Task, lease clientv3.LeaseID) []clientv3.Op {
+	return indexOps(before, after, lease, true)
+}
+
+// indexOps returns the operations of IndexOps. Unless all is set, the entries
+// before already has are not written again, which is only right if they are
+// attached to lease already.
+func indexOps(before, after *Task, lease clientv3.LeaseID, all bool) []clientv3.Op {
+	had := map[string]bool{}
+	for _, key := range IndexKeys(before) {
+		had[key] = true
+	}
+	keep := map[string]bool{}
+	var ops []clientv3.Op
+	for _, key := range IndexKeys(after) {
+		keep[key] = true
+		if all || !had[key] {
+			ops = append(ops, clientv3.OpPut(key, "", clientv3.WithLease(lease)))
+		}
+	}
+	for _, key := range IndexKeys(before) {
+		if !keep[key] {
+			ops = append(ops, clientv3.OpDelete(key))
+		}
+	}
+	return ops
+}
+
+// IndexedIDs returns the IDs of the tasks whose index keys fall in [key, end).
+// An empty end selects all keys with key as prefix.
+func (h *Handler) IndexedIDs(ctx context.Context, key, end string) ([]string, error) {
+	opts := []clientv3.OpOption{clientv3.WithKeysOnly()}
+	if end == "" {
+		opts = append(opts, clientv3.WithPrefix())
+	} else {
+		opts = append(opts, clientv3.WithRange(end))
+	}
+
+	resp, err := h.Client.Get(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		k := string(kv.Key)
+		ids = append(ids, k[strings.LastIndex(k, "/")+1:])
+	}
+	return ids, nil
+}
+
+// GetTasksByID fetches the tasks with the given IDs, skipping IDs without a task,
+// using as few transactions as the etcd operation limit allows.
+func (h *Handler) GetTasksByID(ctx context.Context, ids []string) ([]Task, error) {
+	kvs, err := h.GetTaskKVs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := []Task{}
+	for _, kv := range kvs {
+		var task Task
+		if err := json.Unmarshal(kv.Value, &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// RebuildIndexes brings the secondary indexes in line with the stored tasks,
+// adding missing entries and removing stale ones.
+func (h *Handler) RebuildIndexes(ctx context.Context) error {
+	resp, err := h.Client.Get(ctx, TaskPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+
+	// Work out the wanted index entries, each attached to the lease of its task
+	want := map[string]clientv3.LeaseID{}
+	for _, kv := range resp.Kvs {
+		var task Task
+		if err := json.Unmarshal(kv.Value, &task); err != nil {
+			return err
+		}
+		for _, key := range IndexKeys(&task) {
+			want[key] = clientv3.LeaseID(kv.Lease)
+		}
+	}
+
+	existing, err := h.Client.Get(ctx, IndexPrefix, clientv3.WithPrefix(), clientv3.WithKeysOnly(), clientv3.WithRev(resp.Header.Revision))
+	if err != nil {
+		return err
+	}
+
+	var ops []clientv3.Op
+	have := map[string]bool{}
+	for _, kv := range existing.Kvs {
+		key := string(kv.Key)
+		have[key] = true
+		if _, ok := want[key]; !ok {
+			ops = append(ops, clientv3.OpDelete(key))
+		}
+	}
+	for key, lease := range want {
+		if !have[key] {
+			ops = append(ops, clientv3.OpPut(key, "", clientv3.WithLease(lease)))
+		}
+	}
+
+	limit := MaxTxnOps()
+	for start := 0; start < len(ops); start += limit {
+		end := start + limit
+		if end > len(ops) {
+			end = len(ops)
+		}
+		if _, err := h.Client.Txn(ctx).Then(ops[start:end]...).Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TaskFilter selects tasks by their indexed fields. Zero values do not filter.
+type TaskFilter struct {
+	Completed *bool      // Only tasks with this completion status
+	Tag       string     // Only tasks with this tag
+	Label     string     // Only tasks with the label of this ID
+	Assignee  string     // Only tasks assigned to this person
+	Status    string     // Only tasks in this workflow state
+	DueAfter  *time.Time // Only tasks due at or after this time
+	DueBefore *time.Time // Only tasks due before this time
+}
+
+// IsEmpty reports whether the filter selects all tasks.
+func (f TaskFilter) IsEmpty() bool {
+	return f.Completed == nil && f.Tag == "" && f.Label == "" && f.Assignee == "" && f.Status == "" && f.DueAfter == nil && f.DueBefore == nil
+}
+
+// Match reports whether a task is selected by the filter, as the index lookup would.
+func (f TaskFilter) Match(t Task) bool {
+	if f.Completed != nil && t.Completed != *f.Completed {
+		return false
+	}
+	if f.Tag != "" && !containsString(t.Tags, f.Tag) {
+		return false
+	}
+	if f.Label != "" && !containsString(t.Labels, f.Label) {
+		return false
+	}
+	if f.Assignee != "" && t.Assignee != f.Assignee {
+		return false
+	}
+	if f.Status != "" && t.Status != f.Status {
+		return false
+	}
+	if f.DueAfter != nil || f.DueBefore != nil {
+		if t.Due == nil {
+			return false
+		}
+		due := t.Due.UTC().Format(dueFormat)
+		if f.DueAfter != nil && due < f.DueAfter.UTC().Format(dueFormat) {
+			return false
+		}
+		if f.DueBefore != nil && due >= f.DueBefore.UTC().Format(dueFormat) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterTaskIDs returns the sorted IDs of the tasks matching a non-empty filter,
+// looked up in the secondary indexes.
+func (h *Handler) FilterTaskIDs(ctx context.Context, f TaskFilter) ([]string, error) {
+	var lookups [][2]string
+	if f.Completed != nil {
+		lookups = append(lookups, [2]string{IndexValuePrefix(IndexCompleted, strconv.FormatBool(*f.Completed)), ""})
+	}
+	if f.Tag != "" {
+		lookups = append(lookups, [2]string{IndexValuePrefix(IndexTag, f.Tag), ""})
+	}
+	if f.Label != "" {
+		lookups = append(lookups, [2]string{IndexValuePrefix(IndexLabel, f.Label), ""})
+	}
+	if f.Assignee != "" {
+		lookups = append(lookups, [2]string{IndexValuePrefix(IndexAssignee, f.Assignee), ""})
+	}
+	if f.Status != "" {
+		lookups = append(lookups, [2]string{IndexValuePrefix(IndexStatus, f.Status), ""})
+	}
+	if f.DueAfter != nil || f.DueBefore != nil {
+		start := IndexPrefix + IndexDue + "/"
+		end := clientv3.GetPrefixRangeEnd(start)
+		if f.DueAfter != nil {
+			start = DueIndexKey(*f.DueAfter)
+		}
+		if f.DueBefore != nil {
+			end = DueIndexKey(*f.DueBefore)
+		}
+		lookups = append(lookups, [2]string{start, end})
+	}
+
+	// Intersect the IDs found for each filtered field
+	var ids []string
+	for i, lookup := range lookups {
+		found, err := h.IndexedIDs(ctx, lookup[0], lookup[1])
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			ids = found
+			continue
+		}
+		matched := map[string]bool{}
+		for _, id := range found {
+			matched[id] = true
+		}
+		kept := ids[:0]
+		for _, id := range ids {
+			if matched[id] {
+				kept = append(kept, id)
+			}
+		}
+		ids = kept
+	}
+
+	sort.Strings(ids)
+	return ids, nil
+}
@@ -0,0 +1,481 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// LabelPrefix is the etcd key prefix under which labels are stored by ID.
+const LabelPrefix = "labels/"
+
+// LabelNamePrefix is the etcd key prefix reserving label names. Names are
+// reserved case-insensitively, so that "Backend" cannot be created next to
+// "backend"; the value of a name key is the ID of its label.
+const LabelNamePrefix = "labelnames/"
+
+// Limits on labels.
+const (
+	MaxLabelNameLength        = 50
+	MaxLabelDescriptionLength = 500
+	MaxLabels                 = 50 // Labels of a task
+	MaxMergedLabels           = 20 // Labels merged at once
+)
+
+// DefaultLabelColor is the color of labels created without one.
+const DefaultLabelColor = "#9e9e9e"
+
+// Errors returned for labels.
+var (
+	ErrLabelNotFound = errors.New("Label not found")
+	ErrLabelExists   = errors.New("Label name is already taken")
+	ErrLabelGone     = errors.New("Label no longer exists")
+)
+
+// Label is a named, colored label tasks reference by ID, so that renaming it
+// applies to all of its tasks at once.
+type Label struct {
+	ID          string    `json:"id"`                    // ID of the label
+	Name        string    `json:"name"`                  // Name of the label, unique regardless of case
+	Color       string    `json:"color"`                 // Color of the label as #rrggbb
+	Description string    `json:"description,omitempty"` // What the label is for
+	CreatedAt   time.Time `json:"created_at"`            // When the label was created
+	UpdatedAt   time.Time `json:"updated_at"`            // When the label was last changed
+}
+
+// LabelInfo is a label with the number of tasks having it.
+type LabelInfo struct {
+	Label
+	Usage int `json:"usage"` // Number of tasks with the label
+}
+
+// LabelReq represents a request to create a label or change one.
+type LabelReq struct {
+	Name        string `json:"name"`                  // Name of the label
+	Color       string `json:"color,omitempty"`       // Color of the label as #rrggbb; omitted uses the default color
+	Description string `json:"description,omitempty"` // What the label is for
+}
+
+// MergeReq represents a request to merge labels into another one.
+type MergeReq struct {
+	From []string `json:"from"` // IDs of the labels to merge and delete
+}
+
+// MergeResult reports the outcome of merging labels.
+type MergeResult struct {
+	Label LabelInfo `json:"label"` // Label the others were merged into
+	Tasks int       `json:"tasks"` // Number of tasks relabeled
+}
+
+// LabelKey returns the key of a label.
+func LabelKey(id string) string {
+	return LabelPrefix + id
+}
+
+// labelNameKey returns the key reserving a label name.
+func labelNameKey(name string) string {
+	return LabelNamePrefix + url.PathEscape(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// validateLabel checks a label request, filling in the default color.
+func validateLabel(req *LabelReq) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Color == "" {
+		req.Color = DefaultLabelColor
+	}
+
+	var fields []FieldError
+	if req.Name == "" {
+		fields = append(fields, FieldError{"name", "Name cannot be empty"})
+	}
+	fields = checkText(fields, "name", "Name", req.Name, MaxLabelNameLength, false)
+	if !validColor(req.Color) {
+		fields = append(fields, FieldError{"color", "Color must be written as #rrggbb"})
+	}
+	fields = checkText(fields, "description", "Description", req.Description, MaxLabelDescriptionLength, true)
+	return fieldErrors(fields)
+}
+
+func validColor(color string) bool {
+	if len(color) != 7 || color[0] != '#' {
+		return false
+	}
+	_, err := strconv.ParseUint(color[1:], 16, 32)
+	return err == nil
+}
+
+// checkLabels checks the label IDs of a task.
+func checkLabels(fields []FieldError, labels []string) []FieldError {
+	if len(labels) > MaxLabels {
+		return append(fields, FieldError{"labels", fmt.Sprintf("A task cannot have more than %d labels", MaxLabels)})
+	}
+	for i, id := range labels {
+		field := "labels." + strconv.Itoa(i)
+		fields = checkText(fields, field, "Label", id, MaxIDLength, false)
+		if strings.Contains(id, "/") {
+			fields = append(fields, FieldError{field, "Label cannot contain /"})
+		}
+	}
+	return fields
+}
+
+// labelsExist reports which of the labels exist.
+func (h *Handler) labelsExist(ctx context.Context, labels []string) ([]bool, error) {
+	ops := make([]clientv3.Op, len(labels))
+	for i, id := range labels {
+		ops[i] = clientv3.OpGet(LabelKey(id), clientv3.WithCountOnly())
+	}
+	txn, err := h.Client.Txn(ctx).Then(ops...).Commit()
+	if err != nil {
+		return nil, err
+	}
+	exist := make([]bool, len(labels))
+	for i := range labels {
+		exist[i] = txn.Responses[i].GetResponseRange().Count > 0
+	}
+	return exist, nil
+}
+
+// existingLabels returns the labels that still exist, in order.
+func (h *Handler) existingLabels(ctx context.Context, labels []string) ([]string, error) {
+	if len(labels) == 0 {
+		return labels, nil
+	}
+	exist, err := h.labelsExist(ctx, labels)
+	if err != nil {
+		return nil, err
+	}
+	var kept []string
+	for i, id := range labels {
+		if exist[i] {
+			kept = append(kept, id)
+		}
+	}
+	return kept, nil
+}
+
+// labelCmps checks that the labels of a task exist and returns the guards
+// keeping them from being deleted before the task is stored.
+func (h *Handler) labelCmps(ctx context.Context, labels []string) ([]clientv3.Cmp, error) {
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	exist, err := h.labelsExist(ctx, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []FieldError
+	var cmps []clientv3.Cmp
+	for i, id := range labels {
+		if !exist[i] {
+			fields = append(fields, FieldError{"labels." + strconv.Itoa(i), "Label " + id + " not found"})
+			continue
+		}
+		cmps = append(cmps, clientv3.Compare(clientv3.CreateRevision(LabelKey(id)), ">", 0))
+	}
+	if err := fieldErrors(fields); err != nil {
+		return nil, err
+	}
+	return cmps, nil
+}
+
+// GetLabel fetches a label by ID together with its stored key-value.
+func (h *Handler) GetLabel(ctx context.Context, id string) (*Label, *mvccpb.KeyValue, error) {
+	resp, err := h.Client.Get(ctx, LabelKey(id))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil, ErrLabelNotFound
+	}
+
+	var label Label
+	if err := json.Unmarshal(resp.Kvs[0].Value, &label); err != nil {
+		return nil, nil, err
+	}
+	return &label, resp.Kvs[0], nil
+}
+
+// GetLabelInfo fetches a label by ID with the number of tasks having it.
+func (h *Handler) GetLabelInfo(ctx context.Context, id string) (*LabelInfo, error) {
+	label, _, err := h.GetLabel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := h.Client.Get(ctx, IndexValuePrefix(IndexLabel, id), clientv3.WithPrefix(), clientv3.WithCountOnly())
+	if err != nil {
+		return nil, err
+	}
+	return &LabelInfo{Label: *label, Usage: int(resp.Count)}, nil
+}
+
+// GetLabels fetches all labels, sorted by name, with the number of tasks having each.
+func (h *Handler) GetLabels(ctx context.Context) ([]LabelInfo, error) {
+	txn, err := h.Client.Txn(ctx).Then(
+		clientv3.OpGet(LabelPrefix, clientv3.WithPrefix()),
+		clientv3.OpGet(IndexPrefix+IndexLabel+"/", clientv3.WithPrefix(), clientv3.WithKeysOnly()),
+	).Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	// Count the index entries of each label
+	usage := map[string]int{}
+	for _, kv := range txn.Responses[1].GetResponseRange().Kvs {
+		rest := strings.TrimPrefix(string(kv.Key), IndexPrefix+IndexLabel+"/")
+		if i := strings.Index(rest, "/"); i >= 0 {
+			if id, err := url.PathUnescape(rest[:i]); err == nil {
+				usage[id]++
+			}
+		}
+	}
+
+	labels := []LabelInfo{}
+	for _, kv := range txn.Responses[0].GetResponseRange().Kvs {
+		var label Label
+		if err := json.Unmarshal(kv.Value, &label); err != nil {
+			return nil, err
+		}
+		labels = append(labels, LabelInfo{Label: label, Usage: usage[label.ID]})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return strings.ToLower(labels[i].Name) < strings.ToLower(labels[j].Name)
+	})
+	return labels, nil
+}
+
+// CreateLabel creates a label. It fails with ErrLabelExists if the name is taken.
+func (h *Handler) CreateLabel(ctx context.Context, req LabelReq) (*Label, error) {
+	if err := validateLabel(&req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	label := Label{ID: NewULID(), Name: req.Name, Color: strings.ToLower(req.Color), Description: req.Description, CreatedAt: now, UpdatedAt: now}
+	data, err := json.Marshal(label)
+	if err != nil {
+		return nil, err
+	}
+
+	nameKey := labelNameKey(label.Name)
+	txn, err := h.Client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(nameKey), "=", 0)).
+		Then(clientv3.OpPut(LabelKey(label.ID), string(data)), clientv3.OpPut(nameKey, label.ID)).
+		Commit()
+	if err != nil {
+		return nil, err
+	}
+	if !txn.Succeeded {
+		return nil, ErrLabelExists
+	}
+	return &label, nil
+}
+
+// UpdateLabel renames a label and changes its color and description. As
+// tasks reference the label by ID, they all show the new name at once. It
+// fails with ErrLabelExists if another label has the name.
+func (h *Handler) UpdateLabel(ctx context.Context, id string, req LabelReq) (*Label, error) {
+	if err := validateLabel(&req); err != nil {
+		return nil, err
+	}
+	existing, kv, err := h.GetLabel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	label := *existing
+	label.Name = req.Name
+	label.Color = strings.ToLower(req.Color)
+	label.Description = req.Description
+	label.UpdatedAt = time.Now().UTC()
+	data, err := json.Marshal(label)
+	if err != nil {
+		return nil, err
+	}
+
+	// Move the name reservation along unless only the case of the name changes
+	cmps := []clientv3.Cmp{clientv3.Compare(clientv3.ModRevision(LabelKey(id)), "=", kv.ModRevision)}
+	ops := []clientv3.Op{clientv3.OpPut(LabelKey(id), string(data))}
+	oldName, newName := labelNameKey(existing.Name), labelNameKey(label.Name)
+	if newName != oldName {
+		cmps = append(cmps, clientv3.Compare(clientv3.CreateRevision(newName), "=", 0))
+		ops = append(ops, clientv3.OpDelete(oldName), clientv3.OpPut(newName, id))
+	}
+
+	txn, err := h.Client.Txn(ctx).If(cmps...).Then(ops...).Commit()
+	if err != nil {
+		return nil, err
+	}
+	if !txn.Succeeded {
+		if newName != oldName {
+			resp, err := h.Client.Get(ctx, newName, clientv3.WithCountOnly())
+			if err != nil {
+				return nil, err
+			}
+			if resp.Count > 0 {
+				return nil, ErrLabelExists
+			}
+		}
+		return nil, ErrConflict
+	}
+	return &label, nil
+}
+
+// DeleteLabel deletes a label and removes it from the tasks having it. It
+// returns the number of tasks changed.
+func (h *Handler) DeleteLabel(ctx context.Context, id string, meta AuditMeta) (int, error) {
+	return h.relabel(ctx, "", []string{id}, meta)
+}
+
+// MergeLabels merges labels into another one: the tasks having any of them
+// get the target label instead, and the merged labels are deleted. It returns
+// the number of tasks changed.
+func (h *Handler) MergeLabels(ctx context.Context, into string, req MergeReq, meta AuditMeta) (int, error) {
+	from := NormalizeTags(req.From)
+	var fields []FieldError
+	switch {
+	case len(from) == 0:
+		fields = append(fields, FieldError{"from", "Labels to merge are required"})
+	case len(from) > MaxMergedLabels:
+		fields = append(fields, FieldError{"from", fmt.Sprintf("Cannot merge more than %d labels at once", MaxMergedLabels)})
+	case containsString(from, into):
+		fields = append(fields, FieldError{"from", "Label cannot be merged into itself"})
+	}
+	if err := fieldErrors(fields); err != nil {
+		return 0, err
+	}
+	if _, _, err := h.GetLabel(ctx, into); err != nil {
+		return 0, err
+	}
+	return h.relabel(ctx, into, from, meta)
+}
+
+// relabel replaces the labels from with the label into on every task having
+// them, or removes them if into is empty, then deletes the labels from. The
+// tasks are updated with audit entries and the labels deleted in a single
+// transaction, which fails with ErrConflict if a task got one of them
+// meanwhile. A relabel needing more operations than a transaction allows is
+// refused with a ValidationError rather than applied in parts.
+func (h *Handler) relabel(ctx context.Context, into string, from []string, meta AuditMeta) (int, error) {
+	// Read the labels and which tasks have them at a single revision
+	var reads []clientv3.Op
+	for _, id := range from {
+		reads = append(reads,
+			clientv3.OpGet(LabelKey(id)),
+			clientv3.OpGet(IndexValuePrefix(IndexLabel, id), clientv3.WithPrefix(), clientv3.WithKeysOnly()))
+	}
+	txn, err := h.Client.Txn(ctx).Then(reads...).Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	var cmps []clientv3.Cmp
+	var ops []clientv3.Op
+	ids := map[string]bool{}
+	for i, id := range from {
+		labelKVs := txn.Responses[2*i].GetResponseRange().Kvs
+		if len(labelKVs) == 0 {
+			return 0, fmt.Errorf("%w: %s", ErrLabelNotFound, id)
+		}
+		var label Label
+		if err := json.Unmarshal(labelKVs[0].Value, &label); err != nil {
+			return 0, err
+		}
+
+		// No task may get the label after the read, nor the label change
+		prefix := IndexValuePrefix(IndexLabel, id)
+		cmps = append(cmps,
+			clientv3.Compare(clientv3.ModRevision(LabelKey(id)), "=", labelKVs[0].ModRevision),
+			clientv3.Compare(clientv3.ModRevision(prefix), "<", txn.Header.Revision+1).WithPrefix())
+		ops = append(ops, clientv3.OpDelete(LabelKey(id)), clientv3.OpDelete(labelNameKey(label.Name)))
+		for _, kv := range txn.Responses[2*i+1].GetResponseRange().Kvs {
+			k := string(kv.Key)
+			ids[k[strings.LastIndex(k, "/")+1:]] = true
+		}
+	}
+	if into != "" {
+		cmps = append(cmps, clientv3.Compare(clientv3.CreateRevision(LabelKey(into)), ">", 0))
+	}
+
+	taskIDs := make([]string, 0, len(ids))
+	for id := range ids {
+		taskIDs = append(taskIDs, id)
+	}
+	sort.Strings(taskIDs)
+	kvs, err := h.GetTaskKVs(ctx, taskIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	// Prepare rewriting each task with its labels replaced
+	now := time.Now().UTC()
+	var muts []*Mutation
+	for _, kv := range kvs {
+		var task Task
+		if err := json.Unmarshal(kv.Value, &task); err != nil {
+			return 0, err
+		}
+		updated := task
+		updated.Labels = nil
+		for _, id := range task.Labels {
+			if containsString(from, id) {
+				id = into
+			}
+			if id != "" {
+				updated.Labels = append(updated.Labels, id)
+			}
+		}
+		updated.Labels = NormalizeTags(updated.Labels)
+		updated.UpdatedAt = now
+
+		m := &Mutation{Action: AuditUpdate, Key: string(kv.Key), Before: &task, After: &updated, prev: kv, meta: meta}
+		m.Cmps = append(m.Cmps, clientv3.Compare(clientv3.ModRevision(m.Key), "=", kv.ModRevision))
+		if err := m.put(clientv3.LeaseID(kv.Lease)); err != nil {
+			return 0, err
+		}
+		if err := m.audit(); err != nil {
+			return 0, err
+		}
+		muts = append(muts, m)
+	}
+
+	if len(muts) == 0 {
+		resp, err := h.Client.Txn(ctx).If(cmps...).Then(ops...).Commit()
+		if err != nil {
+			return 0, err
+		}
+		if !resp.Succeeded {
+			return 0, ErrConflict
+		}
+		return 0, nil
+	}
+
+	// Delete the labels together with the tasks
+	last := muts[len(muts)-1]
+	last.Cmps = append(last.Cmps, cmps...)
+	last.Ops = append(last.Ops, ops...)
+	size := 0
+	for _, m := range muts {
+		size += m.Size()
+	}
+	if size > MaxTxnOps() {
+		h.Release(ctx, false, muts...)
+		return 0, &ValidationError{Msg: fmt.Sprintf("Relabeling %d tasks needs %d of the %d operations of a transaction; remove the label from some of them first", len(muts), size, MaxTxnOps())}
+	}
+	_, err = h.Commit(ctx, muts...)
+	h.Release(ctx, err == nil, muts...)
+	if err != nil {
+		return 0, err
+	}
+	return len(muts), nil
+}
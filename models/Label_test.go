@@ -0,0 +1,77 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// createTestLabel creates a label, which is deleted when the test ends if it is left.
+func createTestLabel(t *testing.T, h *Handler) *Label {
+	t.Helper()
+	label, err := h.CreateLabel(context.Background(), LabelReq{Name: fmt.Sprintf("%s %d", t.Name(), testRun)})
+	if err != nil {
+		t.Fatalf("CreateLabel: %v", err)
+	}
+	t.Cleanup(func() { h.DeleteLabel(context.Background(), label.ID, AuditMeta{Actor: "test"}) })
+	return label
+}
+
+// labelTestTask creates a task having the label, as createTestTask does.
+func labelTestTask(t *testing.T, h *Handler, label string) Task {
+	t.Helper()
+	ctx := context.Background()
+	task := createTestTask(t, h)
+	m, err := h.PrepareUpdate(ctx, task.ID, UpdateReq{Title: task.Title, Labels: &[]string{label}}, AuditMeta{Actor: "test"})
+	if err != nil {
+		t.Fatalf("PrepareUpdate: %v", err)
+	}
+	if err := h.Apply(ctx, m); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	return *m.After
+}
+
+func TestDeleteLabelTooLarge(t *testing.T) {
+	h := testHandler(t)
+	ctx := context.Background()
+	label := createTestLabel(t, h)
+	first := labelTestTask(t, h, label.ID)
+	second := labelTestTask(t, h, label.ID)
+
+	// Two tasks with their audit entries do not fit in four operations
+	t.Setenv("ETCD_MAX_TXN_OPS", "4")
+	_, err := h.DeleteLabel(ctx, label.ID, AuditMeta{Actor: "test"})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("DeleteLabel over the limit: %v, want a ValidationError", err)
+	}
+	for _, id := range []string{first.ID, second.ID} {
+		task, _, err := h.GetTaskKV(ctx, id)
+		if err != nil {
+			t.Fatalf("GetTask: %v", err)
+		}
+		if len(task.Labels) != 1 || task.Labels[0] != label.ID {
+			t.Errorf("labels of task %s after a refused delete = %v, want %v", id, task.Labels, []string{label.ID})
+		}
+	}
+
+	t.Setenv("ETCD_MAX_TXN_OPS", "")
+	n, err := h.DeleteLabel(ctx, label.ID, AuditMeta{Actor: "test"})
+	if err != nil || n != 2 {
+		t.Fatalf("DeleteLabel = %d, %v; want 2 tasks", n, err)
+	}
+	for _, id := range []string{first.ID, second.ID} {
+		task, _, err := h.GetTaskKV(ctx, id)
+		if err != nil {
+			t.Fatalf("GetTask: %v", err)
+		}
+		if len(task.Labels) != 0 {
+			t.Errorf("labels of task %s after delete = %v, want none", id, task.Labels)
+		}
+	}
+	if _, _, err := h.GetLabel(ctx, label.ID); !errors.Is(err, ErrLabelNotFound) {
+		t.Errorf("GetLabel after delete: %v, want ErrLabelNotFound", err)
+	}
+}
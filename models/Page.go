@@ -0,0 +1,101 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Page is a page of stored tasks, ordered by ID.
+type Page struct {
+	Kvs      []*mvccpb.KeyValue // Stored tasks of the page
+	Next     string             // ID of the last task examined, to continue after; empty on the last page
+	Revision int64              // etcd revision the tasks were read at
+}
+
+// ListPage returns up to limit stored tasks with IDs after the given one that
+// match the filter and, if given, the match function.
+//
+// Unfiltered pages are read at revision rev, or the current revision if it is
+// 0, so that a listing continued with the returned revision sees a consistent
+// snapshot. Filtered pages are looked up in the secondary indexes, which are
+// always read at the current revision.
+func (h *Handler) ListPage(ctx context.Context, f TaskFilter, match func(Task) bool, after string, limit int, rev int64) (*Page, error) {
+	page := &Page{Revision: rev}
+	keep := func(kv *mvccpb.KeyValue) error {
+		if match != nil {
+			var task Task
+			if err := json.Unmarshal(kv.Value, &task); err != nil {
+				return err
+			}
+			if !match(task) {
+				return nil
+			}
+		}
+		page.Kvs = append(page.Kvs, kv)
+		return nil
+	}
+
+	if !f.IsEmpty() {
+		ids, err := h.FilterTaskIDs(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		ids = ids[sort.SearchStrings(ids, after+"\x00"):]
+		for len(ids) > 0 && len(page.Kvs) < limit {
+			chunk := ids
+			if len(chunk) > limit-len(page.Kvs) {
+				chunk = chunk[:limit-len(page.Kvs)]
+			}
+			kvs, err := h.GetTaskKVs(ctx, chunk)
+			if err != nil {
+				return nil, err
+			}
+			for _, kv := range kvs {
+				if err := keep(kv); err != nil {
+					return nil, err
+				}
+			}
+			ids = ids[len(chunk):]
+			page.Next = chunk[len(chunk)-1]
+		}
+		if len(ids) == 0 {
+			page.Next = ""
+		}
+		return page, nil
+	}
+
+	key, end := TaskPrefix, clientv3.GetPrefixRangeEnd(TaskPrefix)
+	if after != "" {
+		key = TaskPrefix + after + "\x00"
+	}
+	for len(page.Kvs) < limit {
+		opts := []clientv3.OpOption{clientv3.WithRange(end), clientv3.WithLimit(int64(limit - len(page.Kvs)))}
+		if page.Revision != 0 {
+			opts = append(opts, clientv3.WithRev(page.Revision))
+		}
+		resp, err := h.Client.Get(ctx, key, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if page.Revision == 0 {
+			page.Revision = resp.Header.Revision
+		}
+		for _, kv := range resp.Kvs {
+			if err := keep(kv); err != nil {
+				return nil, err
+			}
+		}
+		if !resp.More {
+			page.Next = ""
+			break
+		}
+		last := string(resp.Kvs[len(resp.Kvs)-1].Key)
+		page.Next = last[len(TaskPrefix):]
+		key = last + "\x00"
+	}
+	return page, nil
+}
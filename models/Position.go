@@ -0,0 +1,484 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// positionDigits are the digits of positions, in lexicographic order.
+// Positions are fractions in base 62 written without the leading "0.",
+// so any two of them leave room for a third one in between.
+const positionDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// MaxPositionLength is the maximum length of a position given by a client, in characters.
+const MaxPositionLength = 256
+
+// positionJitter is the number of random digits appended to new positions, so
+// that tasks created concurrently at the end of the list do not tie.
+const positionJitter = 2
+
+// startPosition is the position the first task of an empty list goes to, in
+// the middle of the range.
+const startPosition = "V"
+
+// appendWidth is the number of leading digits of the last position incremented
+// to append tasks after it, which leaves room for 62^appendWidth/2 appends.
+const appendWidth = 3
+
+// rebalanceLength is the length of positions past which the list is rebalanced.
+const rebalanceLength = 64
+
+// MoveReq represents a request to move a task next to other tasks. Given both
+// anchors, the task is placed between them.
+type MoveReq struct {
+	Before string `json:"before,omitempty"` // ID of the task to place the moved task before
+	After  string `json:"after,omitempty"`  // ID of the task to place the moved task after
+}
+
+func checkPosition(fields []FieldError, pos string) []FieldError {
+	switch {
+	case pos == "":
+		return fields
+	case len(pos) > MaxPositionLength:
+		return append(fields, FieldError{"position", fmt.Sprintf("Position cannot be longer than %d characters", MaxPositionLength)})
+	case strings.Trim(pos, positionDigits) != "":
+		return append(fields, FieldError{"position", "Position must only contain digits and ASCII letters"})
+	case strings.HasSuffix(pos, "0"):
+		return append(fields, FieldError{"position", "Position cannot end with 0"})
+	}
+	return fields
+}
+
+// ComparePositions orders two tasks by position, then by ID. Tasks without a
+// position come last.
+func ComparePositions(a, b Task) bool {
+	switch {
+	case a.Position == b.Position:
+		return a.ID < b.ID
+	case a.Position == "":
+		return false
+	case b.Position == "":
+		return true
+	default:
+		return a.Position < b.Position
+	}
+}
+
+// SortByPosition sorts tasks in their manual order.
+func SortByPosition(tasks []Task) {
+	sort.SliceStable(tasks, func(i, j int) bool { return ComparePositions(tasks[i], tasks[j]) })
+}
+
+// PositionBetween returns a position sorting strictly between a and b. An
+// empty a stands for the start of the list and an empty b for its end.
+func PositionBetween(a, b string) (string, error) {
+	if b != "" && a >= b {
+		return "", errors.New("Position " + a + " does not sort before " + b)
+	}
+	return midpoint(a, b), nil
+}
+
+// midpoint returns a position between a and b, neither of which ends with 0.
+func midpoint(a, b string) string {
+	if b != "" {
+		// Keep the prefix both share, reading a as padded with zeros
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + midpoint(rest, b[n:])
+		}
+	}
+
+	lo := strings.IndexByte(positionDigits, digitAt(a, 0))
+	hi := len(positionDigits)
+	if b != "" {
+		hi = strings.IndexByte(positionDigits, b[0])
+	}
+	if hi-lo > 1 {
+		return string(positionDigits[(lo+hi)/2])
+	}
+	// The first digits are adjacent: a longer b leaves room below it, otherwise
+	// continue after a's first digit
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if a != "" {
+		rest = a[1:]
+	}
+	return string(positionDigits[lo]) + midpoint(rest, "")
+}
+
+func digitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return '0'
+}
+
+// AppendPositions returns n increasing positions after the last task of the
+// list, for tasks created in that order. Each one increments the leading
+// digits of the one before, so that appending does not make positions longer.
+func (h *Handler) AppendPositions(ctx context.Context, n int) ([]string, error) {
+	last, err := h.lastPosition(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := make([]string, n)
+	for i := range positions {
+		pos := startPosition
+		if last != "" {
+			pos = incrementPosition(last)
+		}
+		for j := 0; j < positionJitter; j++ {
+			pos += string(positionDigits[1+rand.Intn(len(positionDigits)-1)])
+		}
+		positions[i] = pos
+		last = pos
+	}
+	if len(positions) > 0 && len(positions[n-1]) > rebalanceLength {
+		h.rebalanceSoon()
+	}
+	return positions, nil
+}
+
+// incrementPosition returns the first appendWidth digits of pos read as a
+// number plus one, or more digits if those are all the highest. The result
+// sorts after pos but may end with 0, so it needs digits appended.
+func incrementPosition(pos string) string {
+	for width := appendWidth; ; width++ {
+		digits := []byte(pos)
+		for len(digits) < width {
+			digits = append(digits, '0')
+		}
+		digits = digits[:width]
+		for i := width - 1; i >= 0; i-- {
+			if d := strings.IndexByte(positionDigits, digits[i]); d < len(positionDigits)-1 {
+				digits[i] = positionDigits[d+1]
+				return string(digits)
+			}
+			digits[i] = '0'
+		}
+	}
+}
+
+// lastPosition returns the position of the last task of the list, empty if no task has one.
+func (h *Handler) lastPosition(ctx context.Context) (string, error) {
+	resp, err := h.Client.Get(ctx, IndexPrefix+IndexPosition+"/", clientv3.WithPrefix(), clientv3.WithKeysOnly(),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortDescend), clientv3.WithLimit(1))
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", nil
+	}
+	pos, _ := positionOf(resp.Kvs[0])
+	return pos, nil
+}
+
+// positionOf returns the position and task ID of a position index entry.
+func positionOf(kv *mvccpb.KeyValue) (string, string) {
+	rest := strings.TrimPrefix(string(kv.Key), IndexPrefix+IndexPosition+"/")
+	i := strings.LastIndex(rest, "/")
+	if i < 0 {
+		return "", ""
+	}
+	pos, err := url.PathUnescape(rest[:i])
+	if err != nil {
+		return "", ""
+	}
+	return pos, rest[i+1:]
+}
+
+// neighbour returns the position of the closest task before or after a
+// position in the list, skipping the task being moved and the tasks tied with
+// the position. It is empty if there is none.
+func (h *Handler) neighbour(ctx context.Context, pos, moving string, after bool) (string, error) {
+	prefix := IndexPrefix + IndexPosition + "/"
+	opts := []clientv3.OpOption{clientv3.WithKeysOnly(), clientv3.WithLimit(2)}
+	key := IndexValuePrefix(IndexPosition, pos)
+	if after {
+		// Keys of later positions start past every key of this one
+		key = clientv3.GetPrefixRangeEnd(key)
+		opts = append(opts, clientv3.WithRange(clientv3.GetPrefixRangeEnd(prefix)))
+	} else {
+		opts = append(opts, clientv3.WithRange(key), clientv3.WithSort(clientv3.SortByKey, clientv3.SortDescend))
+		key = prefix
+	}
+
+	resp, err := h.Client.Get(ctx, key, opts...)
+	if err != nil {
+		return "", err
+	}
+	for _, kv := range resp.Kvs {
+		if p, id := positionOf(kv); id != moving {
+			return p, nil
+		}
+	}
+	return "", nil
+}
+
+// PrepareMove prepares placing a task before or after other tasks of the list,
+// which only rewrites the moved task.
+func (h *Handler) PrepareMove(ctx context.Context, id string, req MoveReq, meta AuditMeta) (*Mutation, error) {
+	if req.Before == "" && req.After == "" {
+		return nil, &ValidationError{Msg: "Before or after is required"}
+	}
+	if req.Before == id || req.After == id {
+		return nil, &ValidationError{Msg: "Task cannot be moved next to itself"}
+	}
+
+	existing, kv, err := h.GetTaskKV(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Work out the positions the task goes between
+	anchor := func(field, anchorID string) (string, error) {
+		task, _, err := h.GetTaskKV(ctx, anchorID)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			return "", &ValidationError{Fields: []FieldError{{field, "Task " + anchorID + " not found"}}}
+		case err != nil:
+			return "", err
+		case task.Position == "":
+			return "", &ValidationError{Fields: []FieldError{{field, "Task " + anchorID + " has no position"}}}
+		}
+		return task.Position, nil
+	}
+	var lo, hi string
+	if req.After != "" {
+		if lo, err = anchor("after", req.After); err != nil {
+			return nil, err
+		}
+	}
+	if req.Before != "" {
+		if hi, err = anchor("before", req.Before); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case req.Before == "":
+		hi, err = h.neighbour(ctx, lo, id, true)
+	case req.After == "":
+		lo, err = h.neighbour(ctx, hi, id, false)
+	case lo >= hi:
+		return nil, &ValidationError{Msg: "Task " + req.After + " does not come before task " + req.Before}
+	}
+	if err != nil {
+		return nil, err
+	}
+	pos, err := PositionBetween(lo, hi)
+	if err != nil {
+		return nil, err
+	}
+	if len(pos) > rebalanceLength {
+		// Tasks moved between the same neighbours over and over need shorter
+		// positions; until the list is rebalanced, one too long is refused
+		h.rebalanceSoon()
+		if err := fieldErrors(checkPosition(nil, pos)); err != nil {
+			return nil, err
+		}
+	}
+
+	moved := *existing
+	moved.Position = pos
+	moved.UpdatedAt = time.Now().UTC()
+
+	m := &Mutation{Action: AuditUpdate, Key: string(kv.Key), Before: existing, After: &moved, prev: kv, meta: meta}
+	m.Cmps = append(m.Cmps, clientv3.Compare(clientv3.ModRevision(m.Key), "=", kv.ModRevision))
+	if err := m.put(clientv3.LeaseID(kv.Lease)); err != nil {
+		return nil, err
+	}
+	if err := m.audit(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AssignPositions gives the tasks stored without a position, such as the ones
+// created before tasks could be ordered, positions at the end of the list in
+// ID order. Tasks modified in the meantime are left for the next run.
+func (h *Handler) AssignPositions(ctx context.Context) error {
+	resp, err := h.Client.Get(ctx, TaskPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+
+	var kvs []*mvccpb.KeyValue
+	var tasks []Task
+	for _, kv := range resp.Kvs {
+		var task Task
+		if err := json.Unmarshal(kv.Value, &task); err != nil {
+			return err
+		}
+		if task.Position == "" {
+			kvs = append(kvs, kv)
+			tasks = append(tasks, task)
+		}
+	}
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	positions, err := h.AppendPositions(ctx, len(tasks))
+	if err != nil {
+		return err
+	}
+	for i, kv := range kvs {
+		after := tasks[i]
+		after.Position = positions[i]
+		if err := h.rewrite(ctx, kv, tasks[i], after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebalancing is set while a rebalance started by rebalanceSoon is running.
+var rebalancing int32
+
+// rebalanceSoon rebalances the positions of the list in the background, unless
+// a rebalance is already running.
+func (h *Handler) rebalanceSoon() {
+	if !atomic.CompareAndSwapInt32(&rebalancing, 0, 1) {
+		return
+	}
+	go func() {
+		defer atomic.StoreInt32(&rebalancing, 0)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := h.RebalancePositions(ctx); err != nil {
+			log.Println("Failed to rebalance task positions:", err)
+		}
+	}()
+}
+
+// RebalancePositions gives the tasks of the list short positions spread over
+// the lower half of the range, leaving the upper half for appended tasks, once
+// a position grew longer than rebalanceLength. The tasks are rewritten in
+// order, each to a position below the old one of the next task, so that the
+// list keeps its order between the transactions. It stops with ErrConflict if
+// the list changes meanwhile, to be run again later.
+func (h *Handler) RebalancePositions(ctx context.Context) error {
+	resp, err := h.Client.Get(ctx, IndexPrefix+IndexPosition+"/", clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return err
+	}
+	var old, ids []string
+	long := false
+	for _, kv := range resp.Kvs {
+		pos, id := positionOf(kv)
+		if pos == "" {
+			continue
+		}
+		old = append(old, pos)
+		ids = append(ids, id)
+		long = long || len(pos) > rebalanceLength
+	}
+	if !long {
+		return nil
+	}
+
+	kvs, err := h.GetTaskKVs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byKey := map[string]*mvccpb.KeyValue{}
+	for _, kv := range kvs {
+		byKey[string(kv.Key)] = kv
+	}
+
+	// Spread the new positions evenly over as many digits as it takes
+	n := len(ids)
+	width, span := 1, len(positionDigits)
+	for span/2 <= n {
+		width++
+		span *= len(positionDigits)
+	}
+
+	rev := resp.Header.Revision
+	var cmps []clientv3.Cmp
+	var ops []clientv3.Op
+	commit := func() error {
+		if len(ops) == 0 {
+			return nil
+		}
+		cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(IndexPrefix+IndexPosition+"/"), "<", rev+1).WithPrefix())
+		txn, err := h.Client.Txn(ctx).If(cmps...).Then(ops...).Commit()
+		if err != nil {
+			return err
+		}
+		if !txn.Succeeded {
+			return ErrConflict
+		}
+		rev = txn.Header.Revision
+		cmps, ops = nil, nil
+		return nil
+	}
+
+	prev := ""
+	for i, id := range ids {
+		digits := make([]byte, width)
+		for v, d := (i+1)*(span/2)/(n+1), width-1; d >= 0; d-- {
+			digits[d] = positionDigits[v%len(positionDigits)]
+			v /= len(positionDigits)
+		}
+		pos := strings.TrimRight(string(digits), "0")
+
+		// Stay below the next task, which still has its old position
+		next := ""
+		if i+1 < n {
+			next = old[i+1]
+		}
+		if pos <= prev || (next != "" && pos >= next) {
+			pos = midpoint(prev, next)
+		}
+		prev = pos
+
+		kv := byKey[TaskPrefix+id]
+		if kv == nil || pos == old[i] {
+			continue
+		}
+		var task Task
+		if err := json.Unmarshal(kv.Value, &task); err != nil {
+			return err
+		}
+		moved := task
+		moved.Position = pos
+		data, err := json.Marshal(moved)
+		if err != nil {
+			return err
+		}
+		lease := clientv3.LeaseID(kv.Lease)
+		taskOps := append([]clientv3.Op{clientv3.OpPut(string(kv.Key), string(data), clientv3.WithLease(lease))},
+			IndexOps(&task, &moved, lease)...)
+		if len(ops)+len(taskOps) > MaxTxnOps() || len(cmps)+2 > MaxTxnOps() {
+			if err := commit(); err != nil {
+				return err
+			}
+		}
+		cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(string(kv.Key)), "=", kv.ModRevision))
+		ops = append(ops, taskOps...)
+	}
+	return commit()
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestIncrementPosition(t *testing.T) {
+	tests := []struct {
+		pos, want string
+	}{
+		{"V", "V01"},
+		{"Vab", "Vac"},
+		{"Vabxy", "Vac"},
+		{"Vaz", "Vb0"},
+		{"Vzz", "W00"},
+		{"zzz", "zzz1"},
+		{"zzzz", "zzzz1"},
+		{"zzzzA", "zzzzB"},
+		{"1", "101"},
+	}
+	for _, tt := range tests {
+		if got := incrementPosition(tt.pos); got != tt.want {
+			t.Errorf("incrementPosition(%q) = %q, want %q", tt.pos, got, tt.want)
+		}
+	}
+}
+
+func TestAppendedPositionsStayShort(t *testing.T) {
+	pos := startPosition
+	for i := 0; i < 100000; i++ {
+		next := incrementPosition(pos) + "ab"
+		if next <= pos {
+			t.Fatalf("append %d: %q does not sort after %q", i, next, pos)
+		}
+		if len(checkPosition(nil, next)) > 0 {
+			t.Fatalf("append %d: invalid position %q", i, next)
+		}
+		pos = next
+	}
+	if len(pos) > appendWidth+2 {
+		t.Errorf("position grew to %q after 100000 appends", pos)
+	}
+
+	// Positions grown long by bisecting towards the end come back to short ones
+	long := strings.Repeat("z", 200) + "A"
+	if got := incrementPosition(long); got <= long || len(got) > len(long) {
+		t.Errorf("incrementPosition(%q) = %q", long, got)
+	}
+}
+
+func TestPositionBetween(t *testing.T) {
+	tests := []struct {
+		a, b, want string
+	}{
+		{"", "", "V"},
+		{"V", "", "k"},
+		{"", "V", "F"},
+		{"zz", "", "zzV"},
+		{"", "1", "0V"},
+		{"", "01", "00V"},
+		{"V", "W", "VV"},
+		{"V", "V1", "V0V"},
+		{"V1", "V2", "V1V"},
+		{"Vz", "W", "VzV"},
+		{"V", "W1", "W"},
+	}
+	for _, tt := range tests {
+		got, err := PositionBetween(tt.a, tt.b)
+		if err != nil {
+			t.Errorf("PositionBetween(%q, %q): %v", tt.a, tt.b, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("PositionBetween(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+		if got <= tt.a || (tt.b != "" && got >= tt.b) || len(checkPosition(nil, got)) > 0 {
+			t.Errorf("PositionBetween(%q, %q) = %q, not a valid position between them", tt.a, tt.b, got)
+		}
+	}
+
+	for _, bounds := range [][2]string{{"V", "V"}, {"W", "V"}, {"V1", "V"}} {
+		if got, err := PositionBetween(bounds[0], bounds[1]); err == nil {
+			t.Errorf("PositionBetween(%q, %q) = %q, want an error", bounds[0], bounds[1], got)
+		}
+	}
+
+	// Bisecting towards one side keeps finding room
+	lo, hi := "V", "W"
+	for i := 0; i < 500; i++ {
+		pos, err := PositionBetween(lo, hi)
+		if err != nil || pos <= lo || pos >= hi {
+			t.Fatalf("step %d: PositionBetween(%q, %q) = %q, %v", i, lo, hi, pos, err)
+		}
+		if i%2 == 0 {
+			lo = pos
+		} else {
+			hi = pos
+		}
+	}
+}
+
+// positionTestTask creates a task at pos, as createTestTask does.
+func positionTestTask(t *testing.T, h *Handler, pos string) Task {
+	t.Helper()
+	ctx := context.Background()
+	m, err := h.PrepareInsert(ctx, Task{ID: GenerateUniqueID(), Title: t.Name(), Position: pos, TTL: 3600}, AuditMeta{Actor: "test"})
+	if err != nil {
+		t.Fatalf("PrepareInsert: %v", err)
+	}
+	if err := h.Apply(ctx, m); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	t.Cleanup(func() { expireTestTask(t, h, m.After.ID) })
+	return *m.After
+}
+
+func TestMoveRebalances(t *testing.T) {
+	// Rebalancing rewrites the whole list, so the test gets a keyspace of its own
+	t.Setenv("ETCD_NAMESPACE", fmt.Sprintf("models-test-%d/", time.Now().UnixNano()))
+	h := testHandler(t)
+	ctx := context.Background()
+	t.Cleanup(func() { h.Client.Delete(context.Background(), "", clientv3.WithPrefix()) })
+
+	// A move between neighbours too close for a position of the longest length is refused
+	prefix := strings.Repeat("V", MaxPositionLength-1)
+	first := positionTestTask(t, h, prefix+"1")
+	second := positionTestTask(t, h, prefix+"2")
+	moved := positionTestTask(t, h, "k")
+	_, err := h.PrepareMove(ctx, moved.ID, MoveReq{After: first.ID, Before: second.ID}, AuditMeta{Actor: "test"})
+	if fields := ErrorFields(err); len(fields) != 1 || fields[0].Field != "position" {
+		t.Fatalf("PrepareMove between %q and %q: %v, want an invalid position", first.Position, second.Position, err)
+	}
+
+	// It starts a rebalance all the same, after which the move fits
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		task, _, err := h.GetTaskKV(ctx, first.ID)
+		if err != nil {
+			t.Fatalf("GetTaskKV: %v", err)
+		}
+		if len(task.Position) <= rebalanceLength {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("positions were not rebalanced; the first task is still at %q", task.Position)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	m, err := h.PrepareMove(ctx, moved.ID, MoveReq{After: first.ID, Before: second.ID}, AuditMeta{Actor: "test"})
+	if err != nil {
+		t.Fatalf("PrepareMove after rebalancing: %v", err)
+	}
+	if err := h.Apply(ctx, m); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	var positions []string
+	for _, id := range []string{first.ID, moved.ID, second.ID} {
+		task, _, err := h.GetTaskKV(ctx, id)
+		if err != nil {
+			t.Fatalf("GetTaskKV: %v", err)
+		}
+		if len(task.Position) > rebalanceLength {
+			t.Errorf("task %s is at the long position %q", id, task.Position)
+		}
+		positions = append(positions, task.Position)
+	}
+	if !(positions[0] < positions[1] && positions[1] < positions[2]) {
+		t.Errorf("positions %v are not in the order of the moves", positions)
+	}
+}
@@ -0,0 +1,603 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// TaskPrefix is the etcd key prefix under which tasks are stored.
+const TaskPrefix = "tasks/"
+
+// DefaultMaxTxnOps mirrors the default --max-txn-ops limit of etcd.
+const DefaultMaxTxnOps = 128
+
+// Errors returned when preparing or committing mutations.
+var (
+	ErrNotFound = errors.New("Task not found")
+	ErrExists   = errors.New("Task already exists")
+	ErrConflict = errors.New("Task was modified concurrently")
+	ErrInTrash  = errors.New("A task with this ID is already in the trash; purge or restore it first")
+)
+
+// ValidationError reports a request that cannot be applied as given, with
+// the invalid fields if it can tell them.
+type ValidationError struct {
+	Msg    string
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if e.Msg != "" || len(e.Fields) == 0 {
+		return e.Msg
+	}
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AuditMeta identifies who performed a mutation, for the audit log.
+type AuditMeta struct {
+	Actor     string // Who performed the mutation
+	RequestID string // ID of the request that caused the mutation
+}
+
+// Mutation is a prepared change of a single task. Its comparisons and
+// operations are committed in an etcd transaction, possibly together with
+// those of other mutations.
+type Mutation struct {
+	Action   string           // Audit action of the mutation
+	Key      string           // Key of the mutated task
+	Before   *Task            // Task before the mutation, nil on create
+	After    *Task            // Task after the mutation, nil on delete
+	Cmps     []clientv3.Cmp   // Guards that must hold for the mutation to apply
+	Ops      []clientv3.Op    // Operations performing the mutation
+	prev     *mvccpb.KeyValue // Stored key-value before the mutation
+	lease    clientv3.LeaseID // Lease granted for the mutation, revoked if it is not committed
+	stale    clientv3.LeaseID // Lease no longer used once the mutation is committed
+	meta     AuditMeta        // Who performed the mutation
+	labels   []string         // Labels guarded by Cmps to still exist
+	workflow *clientv3.Cmp    // Guard in Cmps keeping the workflow of the task unchanged
+
+	remembered clientv3.LeaseID // Lease of the response stored by Remember, revoked if the mutation is not committed
+}
+
+// Prev returns the stored key-value the mutation was prepared from, nil if it creates the task.
+func (m *Mutation) Prev() *mvccpb.KeyValue {
+	return m.prev
+}
+
+// Size returns the number of comparisons or operations the mutation adds
+// to a transaction, whichever is larger.
+func (m *Mutation) Size() int {
+	if len(m.Cmps) > len(m.Ops) {
+		return len(m.Cmps)
+	}
+	return len(m.Ops)
+}
+
+// fits fails with a ValidationError if the mutation does not fit in a single
+// etcd transaction, as happens when a task swaps most of its tags and labels.
+func (m *Mutation) fits() error {
+	if m.Size() > MaxTxnOps() {
+		return &ValidationError{Msg: "Too many tags and labels change at once; change fewer of them per request"}
+	}
+	return nil
+}
+
+// ETag returns the entity tag of a task stored at an etcd revision.
+func ETag(modRevision int64) string {
+	return `"` + strconv.FormatInt(modRevision, 10) + `"`
+}
+
+// MaxTxnOps returns the maximum number of operations per etcd transaction.
+// It is read from the ETCD_MAX_TXN_OPS environment variable and must match
+// the --max-txn-ops setting of the cluster.
+func MaxTxnOps() int {
+	if v := os.Getenv("ETCD_MAX_TXN_OPS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return DefaultMaxTxnOps
+}
+
+// GrantLease grants an etcd lease that runs out at expiresAt.
+// Keys attached to the lease are removed by etcd once it expires.
+func (h *Handler) GrantLease(ctx context.Context, expiresAt time.Time) (clientv3.LeaseID, error) {
+	ttl := int64(time.Until(expiresAt).Round(time.Second) / time.Second)
+	if ttl < 1 {
+		ttl = 1
+	}
+	lease, err := h.Client.Grant(ctx, ttl)
+	if err != nil {
+		return 0, err
+	}
+	return lease.ID, nil
+}
+
+// GrantTrashLease grants the lease that purges trashed tasks once the retention period is over.
+func (h *Handler) GrantTrashLease(ctx context.Context) (clientv3.LeaseID, error) {
+	return h.GrantLease(ctx, time.Now().Add(TrashRetention()))
+}
+
+// GetTaskKV fetches a task together with its stored key-value.
+func (h *Handler) GetTaskKV(ctx context.Context, id string) (*Task, *mvccpb.KeyValue, error) {
+	resp, err := h.Client.Get(ctx, TaskPrefix+id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil, ErrNotFound
+	}
+
+	var task Task
+	if err := json.Unmarshal(resp.Kvs[0].Value, &task); err != nil {
+		return nil, nil, err
+	}
+	return &task, resp.Kvs[0], nil
+}
+
+// GetTaskKVs fetches the stored key-values of the tasks with the given IDs, in
+// transactions of at most MaxTxnOps reads. Missing tasks are left out.
+func (h *Handler) GetTaskKVs(ctx context.Context, ids []string) ([]*mvccpb.KeyValue, error) {
+	var kvs []*mvccpb.KeyValue
+	limit := MaxTxnOps()
+	for start := 0; start < len(ids); start += limit {
+		end := start + limit
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		ops := make([]clientv3.Op, 0, end-start)
+		for _, id := range ids[start:end] {
+			ops = append(ops, clientv3.OpGet(TaskPrefix+id))
+		}
+		txn, err := h.Client.Txn(ctx).Then(ops...).Commit()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range txn.Responses {
+			kvs = append(kvs, r.GetResponseRange().Kvs...)
+		}
+	}
+	return kvs, nil
+}
+
+// PrepareCreate validates a new task, assigns it an ID and prepares its creation.
+func (h *Handler) PrepareCreate(ctx context.Context, task Task, meta AuditMeta) (*Mutation, error) {
+	// Check if the request contains an ID
+	if task.ID != "" {
+		return nil, &ValidationError{Msg: "Manual ID entry is not allowed"}
+	}
+
+	// Generate an ID with the configured strategy
+	id, err := h.NewTaskID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	task.ID = id
+
+	return h.PrepareInsert(ctx, task, meta)
+}
+
+// PrepareInsert validates a new task with an ID chosen by the client and
+// prepares its creation. The mutation fails with ErrExists if the ID is taken.
+func (h *Handler) PrepareInsert(ctx context.Context, task Task, meta AuditMeta) (*Mutation, error) {
+	if task.ID == "" {
+		return nil, &ValidationError{Msg: "ID cannot be empty"}
+	}
+
+	// Stamp the creation time
+	task.CreatedAt = time.Now().UTC()
+	task.UpdatedAt = task.CreatedAt
+
+	return h.preparePut(ctx, task, nil, AuditCreate, meta)
+}
+
+// PrepareImport validates an imported task and prepares storing it under its
+// own ID, replacing the stored task kv if there is one. The timestamps of the
+// imported task are kept; missing ones are stamped with the current time.
+func (h *Handler) PrepareImport(ctx context.Context, task Task, kv *mvccpb.KeyValue, meta AuditMeta) (*Mutation, error) {
+	if task.ID == "" {
+		return nil, &ValidationError{Msg: "ID cannot be empty"}
+	}
+
+	now := time.Now().UTC()
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = now
+	}
+	if task.UpdatedAt.IsZero() {
+		task.UpdatedAt = now
+	}
+
+	action := AuditCreate
+	if kv != nil {
+		action = AuditUpdate
+	}
+	return h.preparePut(ctx, task, kv, action, meta)
+}
+
+// PrepareRevert prepares writing an earlier version of a task in place of the
+// stored one, kv. The lifetime of the task is not reverted, and labels
+// deleted since the earlier version are left out.
+func (h *Handler) PrepareRevert(ctx context.Context, task Task, kv *mvccpb.KeyValue, meta AuditMeta) (*Mutation, error) {
+	var existing Task
+	if err := json.Unmarshal(kv.Value, &existing); err != nil {
+		return nil, err
+	}
+	task.ID = existing.ID
+	task.UpdatedAt = time.Now().UTC()
+	task.ExpiresAt = existing.ExpiresAt
+	task.TTL = 0
+
+	var err error
+	if task.Labels, err = h.existingLabels(ctx, task.Labels); err != nil {
+		return nil, err
+	}
+	return h.preparePut(ctx, task, kv, AuditRevert, meta)
+}
+
+// PrepareRestore prepares moving the trashed task stored in kv back to the
+// task list, provided the trash entry is unchanged and no task took its ID.
+// An expiring task keeps the rest of its lifetime, or loses its expiry if that
+// passed while it was in the trash. Labels deleted meanwhile are left out.
+func (h *Handler) PrepareRestore(ctx context.Context, kv *mvccpb.KeyValue, meta AuditMeta) (*Mutation, error) {
+	var trashed TrashedTask
+	if err := json.Unmarshal(kv.Value, &trashed); err != nil {
+		return nil, err
+	}
+	task := trashed.Task
+	task.UpdatedAt = time.Now().UTC()
+	task.TTL = 0
+	if task.ExpiresAt != nil && !task.ExpiresAt.After(time.Now()) {
+		task.ExpiresAt = nil
+	}
+
+	var err error
+	if task.Labels, err = h.existingLabels(ctx, task.Labels); err != nil {
+		return nil, err
+	}
+	m, err := h.preparePut(ctx, task, nil, AuditRestore, meta)
+	if err != nil {
+		return nil, err
+	}
+	m.Cmps = append(m.Cmps, clientv3.Compare(clientv3.ModRevision(string(kv.Key)), "=", kv.ModRevision))
+	m.Ops = append(m.Ops, clientv3.OpDelete(string(kv.Key)))
+	if err := m.fits(); err != nil {
+		h.Release(ctx, false, m)
+		return nil, err
+	}
+	return m, nil
+}
+
+// preparePut validates a complete task and prepares storing it, either as a
+// new task or, if kv is given, in place of the stored one. The mutation is
+// recorded in the audit log under action.
+func (h *Handler) preparePut(ctx context.Context, task Task, kv *mvccpb.KeyValue, action string, meta AuditMeta) (*Mutation, error) {
+	if err := ValidateTask(task); err != nil {
+		return nil, err
+	}
+
+	// Resolve the optional lifetime of the task; the TTL itself is not stored
+	expiresAt, err := ResolveExpiry(task.TTL, task.ExpiresAt)
+	if err != nil {
+		return nil, &ValidationError{Msg: err.Error()}
+	}
+	task.ExpiresAt = expiresAt
+	task.TTL = 0
+	task.Tags = NormalizeTags(task.Tags)
+	task.Labels = NormalizeTags(task.Labels)
+
+	m := &Mutation{Action: action, Key: TaskPrefix + task.ID, After: &task, meta: meta}
+	if m.Cmps, err = h.labelCmps(ctx, task.Labels); err != nil {
+		return nil, err
+	}
+	m.labels = task.Labels
+	if kv != nil {
+		var existing Task
+		if err := json.Unmarshal(kv.Value, &existing); err != nil {
+			return nil, err
+		}
+		m.Before = &existing
+		m.prev = kv
+		m.stale = clientv3.LeaseID(kv.Lease)
+		m.Cmps = append(m.Cmps, clientv3.Compare(clientv3.ModRevision(m.Key), "=", kv.ModRevision))
+	} else {
+		m.Cmps = append(m.Cmps, clientv3.Compare(clientv3.CreateRevision(m.Key), "=", 0))
+	}
+
+	workflowCmp, err := h.setInitialStatus(ctx, &task)
+	if err != nil {
+		return nil, err
+	}
+	m.Cmps = append(m.Cmps, workflowCmp)
+	m.workflow = &workflowCmp
+
+	// Replaced tasks keep their position, new ones go to the end of the list
+	if task.Position == "" && m.Before != nil {
+		task.Position = m.Before.Position
+	}
+	if task.Position == "" {
+		positions, err := h.AppendPositions(ctx, 1)
+		if err != nil {
+			return nil, err
+		}
+		task.Position = positions[0]
+	}
+
+	// Bind expiring tasks to a lease so etcd removes them automatically
+	if task.ExpiresAt != nil {
+		if m.lease, err = h.GrantLease(ctx, *task.ExpiresAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := m.put(m.lease); err != nil {
+		h.Release(ctx, false, m)
+		return nil, err
+	}
+	if err := m.audit(); err != nil {
+		h.Release(ctx, false, m)
+		return nil, err
+	}
+	if err := m.fits(); err != nil {
+		h.Release(ctx, false, m)
+		return nil, err
+	}
+	return m, nil
+}
+
+// PrepareUpdate applies an update request to the stored task and prepares writing it back.
+// Expiring tasks get their lifetime renewed, under a fresh lease replacing the
+// current one on commit, unless the request sets a new lifetime.
+func (h *Handler) PrepareUpdate(ctx context.Context, id string, req UpdateReq, meta AuditMeta) (*Mutation, error) {
+	if err := ValidateUpdate(req); err != nil {
+		return nil, err
+	}
+
+	existingTask, kv, err := h.GetTaskKV(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update a copy of the existing task with the new title and the other
+	// fields present in the request
+	updatedTask := *existingTask
+	updatedTask.Title = req.Title
+	if req.Description != nil {
+		updatedTask.Description = *req.Description
+	}
+	if req.Priority != nil {
+		updatedTask.Priority = *req.Priority
+	}
+	if req.Tags != nil {
+		updatedTask.Tags = NormalizeTags(*req.Tags)
+	}
+	if req.Labels != nil {
+		updatedTask.Labels = NormalizeTags(*req.Labels)
+	}
+	if req.Due != nil {
+		updatedTask.Due = req.Due
+	}
+	if req.ClearDue {
+		updatedTask.Due = nil
+	}
+	if req.Assignee != nil {
+		updatedTask.Assignee = *req.Assignee
+	}
+	if req.Recurrence != nil {
+		updatedTask.Recurrence = *req.Recurrence
+	}
+	if req.Workflow != nil {
+		updatedTask.Workflow = *req.Workflow
+	}
+	updatedTask.UpdatedAt = time.Now().UTC()
+
+	// Move the task through its workflow as the request asks
+	workflowCmp, err := h.updateStatus(ctx, *existingTask, &updatedTask, req)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Mutation{Action: AuditUpdate, Key: string(kv.Key), Before: existingTask, After: &updatedTask, prev: kv, meta: meta}
+	if req.Labels != nil {
+		if m.Cmps, err = h.labelCmps(ctx, updatedTask.Labels); err != nil {
+			return nil, err
+		}
+		m.labels = updatedTask.Labels
+	}
+	m.Cmps = append(m.Cmps, workflowCmp)
+	m.workflow = &workflowCmp
+
+	// Work out the lease the updated task is bound to
+	oldLease := clientv3.LeaseID(kv.Lease)
+	newLease := oldLease
+	switch {
+	case req.TTL != nil && *req.TTL == 0 && req.ExpiresAt == nil:
+		// A zero TTL removes the expiry; putting without a lease detaches the key
+		updatedTask.ExpiresAt = nil
+		newLease = 0
+	case req.TTL != nil || req.ExpiresAt != nil:
+		// A new lifetime binds the task to a fresh lease
+		var ttl int64
+		if req.TTL != nil {
+			ttl = *req.TTL
+		}
+		expiresAt, err := ResolveExpiry(ttl, req.ExpiresAt)
+		if err != nil {
+			return nil, &ValidationError{Msg: err.Error()}
+		}
+		if newLease, err = h.GrantLease(ctx, *expiresAt); err != nil {
+			return nil, err
+		}
+		m.lease = newLease
+		updatedTask.ExpiresAt = expiresAt
+	case oldLease != 0:
+		// Otherwise an update renews the lifetime of an expiring task. It gets
+		// a fresh lease as long as the current one was granted for, so that
+		// the current one runs on untouched if the update is not committed.
+		ttl, err := h.Client.TimeToLive(ctx, oldLease)
+		if err != nil {
+			return nil, err
+		}
+		if ttl.TTL <= 0 {
+			// The lease ran out; the task is about to go
+			return nil, ErrNotFound
+		}
+		expiresAt := time.Now().UTC().Add(time.Duration(ttl.GrantedTTL) * time.Second)
+		if newLease, err = h.GrantLease(ctx, expiresAt); err != nil {
+			return nil, err
+		}
+		m.lease = newLease
+		updatedTask.ExpiresAt = &expiresAt
+	}
+	if newLease != oldLease {
+		m.stale = oldLease
+	}
+
+	m.Cmps = append(m.Cmps, clientv3.Compare(clientv3.ModRevision(m.Key), "=", kv.ModRevision))
+	if err := m.put(newLease); err != nil {
+		h.Release(ctx, false, m)
+		return nil, err
+	}
+	if err := m.audit(); err != nil {
+		h.Release(ctx, false, m)
+		return nil, err
+	}
+	if err := m.fits(); err != nil {
+		h.Release(ctx, false, m)
+		return nil, err
+	}
+	return m, nil
+}
+
+// PrepareDelete prepares moving a task to the trash, where it is kept under trashLease.
+func (h *Handler) PrepareDelete(ctx context.Context, id string, trashLease clientv3.LeaseID, meta AuditMeta) (*Mutation, error) {
+	_, kv, err := h.GetTaskKV(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return NewDeleteMutation(kv, trashLease, meta)
+}
+
+// NewDeleteMutation prepares moving the task stored in kv to the trash,
+// where it is kept under trashLease, provided it is not modified in between
+// and no task with its ID is in the trash already. The comments and attachments of the task stay with it in the trash, so
+// that restoring it brings them back.
+func NewDeleteMutation(kv *mvccpb.KeyValue, trashLease clientv3.LeaseID, meta AuditMeta) (*Mutation, error) {
+	var task Task
+	if err := json.Unmarshal(kv.Value, &task); err != nil {
+		return nil, err
+	}
+
+	trashJSON, err := json.Marshal(NewTrashedTask(task, TrashRetention()))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Mutation{
+		Action: AuditDelete,
+		Key:    string(kv.Key),
+		Before: &task,
+		prev:   kv,
+		meta:   meta,
+	}
+	m.Cmps = append(m.Cmps,
+		clientv3.Compare(clientv3.ModRevision(m.Key), "=", kv.ModRevision),
+		clientv3.Compare(clientv3.CreateRevision(TrashPrefix+task.ID), "=", 0),
+	)
+	m.Ops = append(m.Ops,
+		clientv3.OpDelete(m.Key),
+		clientv3.OpPut(TrashPrefix+task.ID, string(trashJSON), clientv3.WithLease(trashLease)),
+	)
+	m.Ops = append(m.Ops, IndexOps(&task, nil, 0)...)
+	if err := m.audit(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Commit applies the mutations in a single etcd transaction and returns its revision.
+// ErrConflict is returned if any of their guards did not hold.
+func (h *Handler) Commit(ctx context.Context, muts ...*Mutation) (int64, error) {
+	var cmps []clientv3.Cmp
+	var ops []clientv3.Op
+	for _, m := range muts {
+		cmps = append(cmps, m.Cmps...)
+		ops = append(ops, m.Ops...)
+	}
+
+	txn, err := h.Client.Txn(ctx).If(cmps...).Then(ops...).Commit()
+	if err != nil {
+		return 0, err
+	}
+	if !txn.Succeeded {
+		return 0, ErrConflict
+	}
+	return txn.Header.Revision, nil
+}
+
+// Apply commits a single mutation and releases the leases it no longer needs.
+func (h *Handler) Apply(ctx context.Context, m *Mutation) error {
+	_, err := h.Commit(ctx, m)
+	h.Release(ctx, err == nil, m)
+	return h.mutationError(ctx, m, err)
+}
+
+// Release revokes the leases the mutations no longer need: the ones they
+// granted if they were not committed, otherwise the ones they replaced.
+func (h *Handler) Release(ctx context.Context, committed bool, muts ...*Mutation) {
+	for _, m := range muts {
+		lease := m.lease
+		if committed {
+			lease = m.stale
+		} else if m.remembered != 0 {
+			h.Client.Revoke(ctx, m.remembered)
+		}
+		if lease != 0 {
+			h.Client.Revoke(ctx, lease)
+		}
+	}
+}
+
+// put adds the operations storing the task after the mutation and its index
+// entries. Entries the task already had are only written again if its lease changes.
+func (m *Mutation) put(lease clientv3.LeaseID) error {
+	data, err := json.Marshal(m.After)
+	if err != nil {
+		return err
+	}
+	m.Ops = append(m.Ops, clientv3.OpPut(m.Key, string(data), clientv3.WithLease(lease)))
+	if m.prev != nil && clientv3.LeaseID(m.prev.Lease) == lease {
+		m.Ops = append(m.Ops, indexOps(m.Before, m.After, lease, false)...)
+	} else {
+		m.Ops = append(m.Ops, IndexOps(m.Before, m.After, lease)...)
+	}
+	return nil
+}
+
+// audit adds the audit entry of the mutation to it.
+func (m *Mutation) audit() error {
+	entry, err := NewAuditEntry(m.Action, m.meta.Actor, m.meta.RequestID, m.Before, m.After)
+	if err != nil {
+		return err
+	}
+	op, err := entry.Op()
+	if err != nil {
+		return err
+	}
+	m.Cmps = append(m.Cmps, entry.Cmp())
+	m.Ops = append(m.Ops, op)
+	return nil
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestUpdateRenewsLeaseOnCommit(t *testing.T) {
+	h := testHandler(t)
+	ctx := context.Background()
+	task := createTestTask(t, h)
+	_, kv, err := h.GetTaskKV(ctx, task.ID)
+	if err != nil {
+		t.Fatalf("GetTaskKV: %v", err)
+	}
+	oldLease := clientv3.LeaseID(kv.Lease)
+	remaining := func(lease clientv3.LeaseID) int64 {
+		t.Helper()
+		ttl, err := h.Client.TimeToLive(ctx, lease)
+		if err != nil {
+			t.Fatalf("TimeToLive: %v", err)
+		}
+		return ttl.TTL
+	}
+	time.Sleep(1100 * time.Millisecond)
+
+	// An update that is not committed leaves the lease running down
+	m, err := h.PrepareUpdate(ctx, task.ID, UpdateReq{Title: "rejected"}, AuditMeta{Actor: "test"})
+	if err != nil {
+		t.Fatalf("PrepareUpdate: %v", err)
+	}
+	h.Release(ctx, false, m)
+	if ttl := remaining(oldLease); ttl <= 0 || ttl >= 3600 {
+		t.Errorf("lease TTL after a rejected update = %d, want it below 3600 and running", ttl)
+	}
+
+	// A committed one moves the task to a fresh lease of the same length
+	m, err = h.PrepareUpdate(ctx, task.ID, UpdateReq{Title: "renewed"}, AuditMeta{Actor: "test"})
+	if err != nil {
+		t.Fatalf("PrepareUpdate: %v", err)
+	}
+	if err := h.Apply(ctx, m); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	_, kv, err = h.GetTaskKV(ctx, task.ID)
+	if err != nil {
+		t.Fatalf("GetTaskKV: %v", err)
+	}
+	newLease := clientv3.LeaseID(kv.Lease)
+	if newLease == oldLease || remaining(oldLease) != -1 {
+		t.Errorf("task kept lease %x after the update, or the old one was not revoked", newLease)
+	}
+	if ttl := remaining(newLease); ttl < 3599 {
+		t.Errorf("new lease TTL = %d, want 3600", ttl)
+	}
+	if m.After.ExpiresAt == nil || time.Until(*m.After.ExpiresAt) < 3599*time.Second {
+		t.Errorf("expires_at = %v, want an hour from now", m.After.ExpiresAt)
+	}
+}
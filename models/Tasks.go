@@ -1,23 +1,94 @@
 package models
 
 import (
+	"errors"
+	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/namespace"
 )
 
 // Task represents a task with an ID, title, and completion status.
 type Task struct {
-	ID        string `json:"id"`        // ID of the task (string format)
-	Title     string `json:"title"`     // Title of the task
-	Completed bool   `json:"completed"` // Completion status of the task
+	ID          string       `json:"id"`                    // ID of the task (string format)
+	Title       string       `json:"title"`                 // Title of the task
+	Description string       `json:"description,omitempty"` // Longer description of the task
+	Completed   bool         `json:"completed"`             // Completion status of the task
+	Priority    string       `json:"priority,omitempty"`    // Priority of the task (low, medium, high, urgent)
+	Tags        []string     `json:"tags,omitempty"`        // Free-form tags of the task
+	Labels      []string     `json:"labels,omitempty"`      // IDs of the labels of the task
+	Due         *time.Time   `json:"due,omitempty"`         // When the task is due
+	Assignee    string       `json:"assignee,omitempty"`    // Who the task is assigned to
+	Recurrence  string       `json:"recurrence,omitempty"`  // How the task recurs, as an iCalendar RRULE value
+	Position    string       `json:"position,omitempty"`    // Rank of the task in the manual order of the list
+	Workflow    string       `json:"workflow,omitempty"`    // Workflow the status follows; empty for the default one
+	Status      string       `json:"status,omitempty"`      // State of the task in its workflow
+	Transitions []Transition `json:"transitions,omitempty"` // Changes of the status, oldest first
+	CreatedAt   time.Time    `json:"created_at"`            // When the task was created
+	UpdatedAt   time.Time    `json:"updated_at"`            // When the task was last modified
+	ExpiresAt   *time.Time   `json:"expires_at,omitempty"`  // When the task is removed automatically, if ever
+	TTL         int64        `json:"ttl,omitempty"`         // Seconds until the task expires (not stored)
 }
 
 // UpdateReq represents a request to update a task with a new title and completion status.
 type UpdateReq struct {
-	Title     string `json:"title"`     // New title for the task update
-	Completed bool   `json:"completed"` // New completion status for the task update
+	Title       string     `json:"title"`                 // New title for the task update
+	Description *string    `json:"description,omitempty"` // New description; omitted keeps the current one
+	Completed   *bool      `json:"completed,omitempty"`   // New completion status; omitted keeps the current one, ignored if status is given
+	Status      *string    `json:"status,omitempty"`      // New status; omitted moves the task to match completed
+	Workflow    *string    `json:"workflow,omitempty"`    // New workflow; omitted keeps the current one
+	Priority    *string    `json:"priority,omitempty"`    // New priority; omitted keeps the current one
+	Tags        *[]string  `json:"tags,omitempty"`        // New tags; omitted keeps the current ones
+	Labels      *[]string  `json:"labels,omitempty"`      // New label IDs; omitted keeps the current ones
+	Due         *time.Time `json:"due,omitempty"`         // New due date; omitted keeps the current one
+	ClearDue    bool       `json:"clear_due,omitempty"`   // Removes the due date
+	Assignee    *string    `json:"assignee,omitempty"`    // New assignee; omitted keeps the current one, empty removes it
+	Recurrence  *string    `json:"recurrence,omitempty"`  // New recurrence rule; omitted keeps the current one
+	TTL         *int64     `json:"ttl,omitempty"`         // New lifetime in seconds; 0 removes the expiry
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`  // New absolute expiry time
+}
+
+// Task returns the task an update request creates when the task does not
+// exist yet, for upserts. Omitted fields are left empty.
+func (req UpdateReq) Task(id string) Task {
+	task := Task{ID: id, Title: req.Title, Completed: req.Completed != nil && *req.Completed, Due: req.Due, ExpiresAt: req.ExpiresAt}
+	if req.Description != nil {
+		task.Description = *req.Description
+	}
+	if req.Priority != nil {
+		task.Priority = *req.Priority
+	}
+	if req.Tags != nil {
+		task.Tags = *req.Tags
+	}
+	if req.Labels != nil {
+		task.Labels = *req.Labels
+	}
+	if req.Assignee != nil {
+		task.Assignee = *req.Assignee
+	}
+	if req.Recurrence != nil {
+		task.Recurrence = *req.Recurrence
+	}
+	if req.TTL != nil {
+		task.TTL = *req.TTL
+	}
+	if req.Status != nil {
+		task.Status = *req.Status
+	}
+	if req.Workflow != nil {
+		task.Workflow = *req.Workflow
+	}
+	return task
+}
+
+// RevertReq represents a request to restore a task to the version stored at an etcd revision.
+type RevertReq struct {
+	Revision int64 `json:"revision"` // Revision holding the version to restore
 }
 
 type Handler struct {
@@ -48,6 +119,16 @@ func Init() (*Handler, *Handler, error) {
 		return nil, nil, err
 	}
 
+	// Keep every key under ETCD_NAMESPACE, if set, so that several
+	// deployments or test runs can share the etcd members
+	if ns := os.Getenv("ETCD_NAMESPACE"); ns != "" {
+		for _, cli := range []*clientv3.Client{cli1, cli2} {
+			cli.KV = namespace.NewKV(cli.KV, ns)
+			cli.Watcher = namespace.NewWatcher(cli.Watcher, ns)
+			cli.Lease = namespace.NewLease(cli.Lease, ns)
+		}
+	}
+
 	// Create handlers for each client
 	handler1 := &Handler{Client: cli1}
 	handler2 := &Handler{Client: cli2}
@@ -56,7 +137,83 @@ func Init() (*Handler, *Handler, error) {
 	return handler1, handler2, nil
 }
 
-// GenerateUniqueID generates a unique ID for a new task using UUID.
+// ResolveExpiry validates a requested lifetime, given either as a TTL in seconds
+// or as an absolute time, and returns the resulting expiry (nil if none was requested).
+func ResolveExpiry(ttl int64, expiresAt *time.Time) (*time.Time, error) {
+	if ttl != 0 && expiresAt != nil {
+		return nil, errors.New("specify either ttl or expires_at, not both")
+	}
+	if ttl < 0 {
+		return nil, errors.New("ttl cannot be negative")
+	}
+	if ttl > 0 {
+		t := time.Now().UTC().Add(time.Duration(ttl) * time.Second)
+		return &t, nil
+	}
+	if expiresAt != nil {
+		if !expiresAt.After(time.Now()) {
+			return nil, errors.New("expires_at must be in the future")
+		}
+		t := expiresAt.UTC()
+		return &t, nil
+	}
+	return nil, nil
+}
+
+// Priorities lists the valid task priorities from lowest to highest.
+var Priorities = []string{"low", "medium", "high", "urgent"}
+
+// PriorityRank returns the position of a priority in Priorities.
+func PriorityRank(priority string) (int, bool) {
+	for i, p := range Priorities {
+		if p == priority {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// NormalizeTags trims the tags, drops empty ones and duplicates and sorts the rest.
+func NormalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// recurrenceFreqs lists the valid FREQ values of recurrence rules.
+var recurrenceFreqs = []string{"SECONDLY", "MINUTELY", "HOURLY", "DAILY", "WEEKLY", "MONTHLY", "YEARLY"}
+
+// ValidateRecurrence checks that a recurrence rule has the form of an iCalendar
+// RRULE value, such as FREQ=WEEKLY;BYDAY=MO, with a valid FREQ.
+func ValidateRecurrence(rule string) error {
+	freq := ""
+	for _, part := range strings.Split(rule, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || name == "" || value == "" || strings.ToUpper(name) != name {
+			return errors.New("recurrence must be an RRULE value such as FREQ=WEEKLY;BYDAY=MO")
+		}
+		if name == "FREQ" {
+			freq = value
+		}
+	}
+	for _, f := range recurrenceFreqs {
+		if f == freq {
+			return nil
+		}
+	}
+	return errors.New("recurrence FREQ must be one of " + strings.Join(recurrenceFreqs, ", "))
+}
+
+// GenerateUniqueID generates a random UUIDv4, the default ID of new tasks.
 func GenerateUniqueID() string {
 	return uuid.New().String()
 }
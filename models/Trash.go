@@ -0,0 +1,62 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// TrashPrefix is the etcd key prefix under which deleted tasks are kept until they expire.
+const TrashPrefix = "trash/"
+
+// DefaultTrashRetention is how long deleted tasks are kept when TRASH_RETENTION is not set.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// TrashedTask represents a deleted task kept in the trash.
+type TrashedTask struct {
+	Task
+	DeletedAt time.Time `json:"deleted_at"` // When the task was moved to the trash
+	PurgeAt   time.Time `json:"purge_at"`   // When the task is purged automatically
+}
+
+// TrashRetention returns how long deleted tasks are kept in the trash.
+// It is read from the TRASH_RETENTION environment variable (e.g. "72h"),
+// falling back to DefaultTrashRetention when unset or invalid.
+func TrashRetention() time.Duration {
+	if v := os.Getenv("TRASH_RETENTION"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= time.Second {
+			return d
+		}
+	}
+	return DefaultTrashRetention
+}
+
+// NewTrashedTask wraps a task being deleted now and kept for the given retention.
+func NewTrashedTask(task Task, retention time.Duration) TrashedTask {
+	now := time.Now().UTC()
+	return TrashedTask{Task: task, DeletedAt: now, PurgeAt: now.Add(retention)}
+}
+
+// ReleaseTrashLease revokes the lease a purged trash entry was kept under,
+// unless other entries still use it: the tasks deleted by a single request
+// share one lease.
+func (h *Handler) ReleaseTrashLease(ctx context.Context, lease clientv3.LeaseID) error {
+	if lease == 0 {
+		return nil
+	}
+	ttl, err := h.Client.TimeToLive(ctx, lease, clientv3.WithAttachedKeys())
+	if err != nil {
+		return err
+	}
+	if ttl.TTL <= 0 || len(ttl.Keys) > 0 {
+		return nil
+	}
+	if _, err := h.Client.Revoke(ctx, lease); err != nil && !errors.Is(err, rpctypes.ErrLeaseNotFound) {
+		return err
+	}
+	return nil
+}
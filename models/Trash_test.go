@@ -0,0 +1,100 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// trashTestTask moves a task to the trash under lease, deleting the entry when the test ends.
+func trashTestTask(t *testing.T, h *Handler, task Task, lease clientv3.LeaseID) error {
+	t.Helper()
+	ctx := context.Background()
+	m, err := h.PrepareDelete(ctx, task.ID, lease, AuditMeta{Actor: "test"})
+	if err != nil {
+		t.Fatalf("PrepareDelete: %v", err)
+	}
+	t.Cleanup(func() { h.Client.Delete(context.Background(), TrashPrefix+task.ID) })
+	return h.Apply(ctx, m)
+}
+
+// grantTestLease grants a lease revoked when the test ends.
+func grantTestLease(t *testing.T, h *Handler) clientv3.LeaseID {
+	t.Helper()
+	lease, err := h.Client.Grant(context.Background(), 600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Client.Revoke(context.Background(), lease.ID) })
+	return lease.ID
+}
+
+func TestDeleteKeepsTrashEntry(t *testing.T) {
+	h := testHandler(t)
+	ctx := context.Background()
+	task := createTestTask(t, h)
+	if err := trashTestTask(t, h, task, grantTestLease(t, h)); err != nil {
+		t.Fatalf("first delete: %v", err)
+	}
+	first, err := h.Client.Get(ctx, TrashPrefix+task.ID)
+	if err != nil || len(first.Kvs) == 0 {
+		t.Fatalf("trash entry: %v", err)
+	}
+
+	// A task taking the ID again cannot be deleted over the entry
+	m, err := h.PrepareInsert(ctx, Task{ID: task.ID, Title: t.Name(), TTL: 3600}, AuditMeta{Actor: "test"})
+	if err != nil {
+		t.Fatalf("PrepareInsert: %v", err)
+	}
+	if err := h.Apply(ctx, m); err != nil {
+		t.Fatalf("Apply create: %v", err)
+	}
+	if err := trashTestTask(t, h, task, grantTestLease(t, h)); !errors.Is(err, ErrInTrash) {
+		t.Errorf("second delete: %v, want ErrInTrash", err)
+	}
+	second, err := h.Client.Get(ctx, TrashPrefix+task.ID)
+	if err != nil || len(second.Kvs) == 0 {
+		t.Fatalf("trash entry: %v", err)
+	}
+	if second.Kvs[0].ModRevision != first.Kvs[0].ModRevision {
+		t.Error("the second delete overwrote the trash entry")
+	}
+}
+
+func TestReleaseTrashLease(t *testing.T) {
+	h := testHandler(t)
+	ctx := context.Background()
+	lease := grantTestLease(t, h)
+	first, second := createTestTask(t, h), createTestTask(t, h)
+	for _, task := range []Task{first, second} {
+		if err := trashTestTask(t, h, task, lease); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+	}
+
+	// The lease stays while another entry uses it
+	alive := func() bool {
+		t.Helper()
+		ttl, err := h.Client.TimeToLive(ctx, lease)
+		if err != nil {
+			t.Fatalf("TimeToLive: %v", err)
+		}
+		return ttl.TTL > 0
+	}
+	for i, task := range []Task{first, second} {
+		if _, err := h.Client.Delete(ctx, TrashPrefix+task.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.ReleaseTrashLease(ctx, lease); err != nil {
+			t.Fatalf("ReleaseTrashLease: %v", err)
+		}
+		if want := i == 0; alive() != want {
+			t.Errorf("lease alive after purging %d of 2 entries = %v, want %v", i+1, !want, want)
+		}
+	}
+	if err := h.ReleaseTrashLease(ctx, lease); err != nil {
+		t.Errorf("ReleaseTrashLease of a revoked lease: %v", err)
+	}
+}
@@ -0,0 +1,347 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits on the text fields of tasks, in characters.
+const (
+	MaxIDLength          = 128
+	MaxTitleLength       = 200
+	MaxDescriptionLength = 10000
+	MaxTagLength         = 50
+	MaxTags              = 50
+	MaxAssigneeLength    = 100
+	MaxRecurrenceLength  = 500
+)
+
+// FieldError reports an invalid field of a request.
+type FieldError struct {
+	Field   string `json:"field"`   // JSON name of the field, dotted for nested fields
+	Message string `json:"message"` // What is wrong with the field
+}
+
+// fieldErrors returns a validation error listing the given field errors, nil if there are none.
+func fieldErrors(fields []FieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: fields}
+}
+
+// ErrorFields returns the invalid fields reported by a validation error, nil
+// for other errors.
+func ErrorFields(err error) []FieldError {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr.Fields
+	}
+	return nil
+}
+
+// ValidateTask checks the fields of a complete task: the title is required,
+// text fields are limited in length and must be valid UTF-8 without control
+// characters (line breaks and tabs are allowed in the description), and the
+// priority and recurrence rule must be well-formed.
+func ValidateTask(t Task) error {
+	var fields []FieldError
+	if t.ID != "" {
+		fields = checkText(fields, "id", "ID", t.ID, MaxIDLength, false)
+		if strings.Contains(t.ID, "/") {
+			fields = append(fields, FieldError{"id", "ID cannot contain /"})
+		}
+	}
+	fields = checkTitle(fields, t.Title)
+	fields = checkText(fields, "description", "Description", t.Description, MaxDescriptionLength, true)
+	fields = checkPriority(fields, t.Priority)
+	fields = checkTags(fields, t.Tags)
+	fields = checkLabels(fields, t.Labels)
+	fields = checkText(fields, "assignee", "Assignee", t.Assignee, MaxAssigneeLength, false)
+	fields = checkRecurrence(fields, t.Recurrence)
+	fields = checkPosition(fields, t.Position)
+	fields = checkText(fields, "workflow", "Workflow", t.Workflow, MaxWorkflowNameLength, false)
+	return fieldErrors(fields)
+}
+
+// ValidateUpdate checks the fields an update request sets, as ValidateTask does.
+func ValidateUpdate(req UpdateReq) error {
+	fields := checkTitle(nil, req.Title)
+	if req.Description != nil {
+		fields = checkText(fields, "description", "Description", *req.Description, MaxDescriptionLength, true)
+	}
+	if req.Priority != nil {
+		fields = checkPriority(fields, *req.Priority)
+	}
+	if req.Tags != nil {
+		fields = checkTags(fields, *req.Tags)
+	}
+	if req.Labels != nil {
+		fields = checkLabels(fields, *req.Labels)
+	}
+	if req.ClearDue && req.Due != nil {
+		fields = append(fields, FieldError{"due", "Due cannot be set and cleared at once"})
+	}
+	if req.Assignee != nil {
+		fields = checkText(fields, "assignee", "Assignee", *req.Assignee, MaxAssigneeLength, false)
+	}
+	if req.Recurrence != nil {
+		fields = checkRecurrence(fields, *req.Recurrence)
+	}
+	if req.Workflow != nil {
+		fields = checkText(fields, "workflow", "Workflow", *req.Workflow, MaxWorkflowNameLength, false)
+	}
+	return fieldErrors(fields)
+}
+
+func checkTitle(fields []FieldError, title string) []FieldError {
+	if strings.TrimSpace(title) == "" {
+		return append(fields, FieldError{"title", "Title cannot be empty"})
+	}
+	return checkText(fields, "title", "Title", title, MaxTitleLength, false)
+}
+
+func checkPriority(fields []FieldError, priority string) []FieldError {
+	if _, ok := PriorityRank(priority); priority != "" && !ok {
+		return append(fields, FieldError{"priority", "Priority must be one of " + strings.Join(Priorities, ", ")})
+	}
+	return fields
+}
+
+func checkTags(fields []FieldError, tags []string) []FieldError {
+	if len(tags) > MaxTags {
+		return append(fields, FieldError{"tags", fmt.Sprintf("A task cannot have more than %d tags", MaxTags)})
+	}
+	for i, tag := range tags {
+		fields = checkText(fields, "tags."+strconv.Itoa(i), "Tag", tag, MaxTagLength, false)
+	}
+	return fields
+}
+
+func checkRecurrence(fields []FieldError, rule string) []FieldError {
+	if rule == "" {
+		return fields
+	}
+	n := len(fields)
+	if fields = checkText(fields, "recurrence", "Recurrence", rule, MaxRecurrenceLength, false); len(fields) > n {
+		return fields
+	}
+	if err := ValidateRecurrence(rule); err != nil {
+		return append(fields, FieldError{"recurrence", err.Error()})
+	}
+	return fields
+}
+
+// checkText checks the length and characters of a text field, named field in
+// JSON and label in messages. Line breaks and tabs are allowed if multiline is set.
+func checkText(fields []FieldError, field, label, s string, max int, multiline bool) []FieldError {
+	if !utf8.ValidString(s) {
+		return append(fields, FieldError{field, label + " must be valid UTF-8"})
+	}
+	if n := utf8.RuneCountInString(s); n > max {
+		return append(fields, FieldError{field, fmt.Sprintf("%s cannot be longer than %d characters", label, max)})
+	}
+	for _, r := range s {
+		if multiline && (r == '\n' || r == '\r' || r == '\t') {
+			continue
+		}
+		if unicode.IsControl(r) {
+			return append(fields, FieldError{field, label + " cannot contain control characters"})
+		}
+	}
+	return fields
+}
+
+// DecodeStrict decodes a single JSON value into v, rejecting object keys that
+// appear twice, regardless of case in objects decoding into structs as
+// encoding/json matches their fields so, and fields v does not have. Invalid input is reported as a
+// ValidationError naming the offending field where possible.
+func DecodeStrict(data []byte, v interface{}) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return &ValidationError{Msg: "Request body cannot be empty"}
+	}
+	if field := duplicateKey(data, reflect.TypeOf(v)); field != "" {
+		return &ValidationError{Fields: []FieldError{{field, "Duplicate key " + strconv.Quote(field)}}}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &typeErr) && typeErr.Field != "":
+			return &ValidationError{Fields: []FieldError{{typeErr.Field, "Cannot be a JSON " + typeErr.Value}}}
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+			return &ValidationError{Fields: []FieldError{{field, "Unknown field " + strconv.Quote(field)}}}
+		default:
+			return &ValidationError{Msg: "Invalid JSON: " + err.Error()}
+		}
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return &ValidationError{Msg: "Invalid JSON: unexpected data after the top-level value"}
+	}
+	return nil
+}
+
+// jsonFrame is an object or array being read by duplicateKey.
+type jsonFrame struct {
+	object  bool
+	typ     reflect.Type    // Type the value decodes into, nil if unknown
+	keys    map[string]bool // Keys of an object, or the fields they decode into
+	key     string          // Current key of an object
+	index   int             // Current index of an array
+	wantKey bool            // Whether an object expects a key next
+}
+
+// slot returns what a key of the object decodes into: the struct field it
+// matches, regardless of case as encoding/json does, or else the key itself.
+func (f *jsonFrame) slot(key string) string {
+	if f.typ != nil && f.typ.Kind() == reflect.Struct {
+		if name, _ := jsonField(f.typ, key); name != "" {
+			return "." + name
+		}
+	}
+	return key
+}
+
+// childType returns the type the current value of the frame decodes into,
+// nil if unknown.
+func (f *jsonFrame) childType() reflect.Type {
+	if f.typ == nil {
+		return nil
+	}
+	switch f.typ.Kind() {
+	case reflect.Struct:
+		_, t := jsonField(f.typ, f.key)
+		return t
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return decodedType(f.typ.Elem())
+	}
+	return nil
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// decodedType returns the type a JSON value is decoded into through t,
+// dereferencing pointers; nil if the type decodes itself.
+func decodedType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+	return t
+}
+
+// jsonField returns the Go name and the decoded type of the field of struct t
+// that encoding/json decodes key into, preferring an exact match of its JSON
+// name over one ignoring case; an empty name if there is none.
+func jsonField(t reflect.Type, key string) (string, reflect.Type) {
+	var foldedName string
+	var foldedType reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			if embedded := decodedType(field.Type); embedded != nil && embedded.Kind() == reflect.Struct {
+				if fieldName, fieldType := jsonField(embedded, key); fieldName != "" {
+					return field.Name + "." + fieldName, fieldType
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if name == key {
+			return field.Name, decodedType(field.Type)
+		}
+		if foldedName == "" && strings.EqualFold(name, key) {
+			foldedName, foldedType = field.Name, decodedType(field.Type)
+		}
+	}
+	return foldedName, foldedType
+}
+
+// duplicateKey returns the dotted path of the first object key of a JSON
+// document decoding into t that appears twice in its object, empty if there
+// is none or the document is malformed.
+func duplicateKey(data []byte, t reflect.Type) string {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var stack []*jsonFrame
+	valueDone := func() {
+		if len(stack) == 0 {
+			return
+		}
+		if top := stack[len(stack)-1]; top.object {
+			top.wantKey = true
+		} else {
+			top.index++
+		}
+	}
+	valueType := func() reflect.Type {
+		if len(stack) == 0 {
+			return decodedType(t)
+		}
+		return stack[len(stack)-1].childType()
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+
+		if n := len(stack); n > 0 && stack[n-1].object && stack[n-1].wantKey {
+			top := stack[n-1]
+			if tok == json.Delim('}') {
+				stack = stack[:n-1]
+				valueDone()
+				continue
+			}
+			key, _ := tok.(string)
+			if top.keys[top.slot(key)] {
+				var path []string
+				for _, f := range stack[:n-1] {
+					if f.object {
+						path = append(path, f.key)
+					} else {
+						path = append(path, strconv.Itoa(f.index))
+					}
+				}
+				return strings.Join(append(path, key), ".")
+			}
+			top.keys[top.slot(key)], top.key, top.wantKey = true, key, false
+			continue
+		}
+
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, &jsonFrame{object: true, typ: valueType(), keys: map[string]bool{}, wantKey: true})
+		case json.Delim('['):
+			stack = append(stack, &jsonFrame{typ: valueType()})
+		case json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			valueDone()
+		default:
+			valueDone()
+		}
+		if len(stack) == 0 {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecodeStrictDuplicateKeys(t *testing.T) {
+	for _, tc := range []struct {
+		body  string
+		field string // Field reported as a duplicate, empty if the body decodes
+	}{
+		{`{"title":"a","title":"b"}`, "title"},
+		// encoding/json matches struct fields regardless of case, so these collide
+		{`{"title":"a","Title":"b"}`, "Title"},
+		{`{"title":"a","labels":["x"],"LABELS":["y"]}`, "LABELS"},
+		{`{"title":"a","tags":["x","x"]}`, ""},
+		{`{"title":"a","transitions":[{"to":"todo"},{"to":"done","TO":"todo"}]}`, "transitions.1.TO"},
+		{`{"title":"a","transitions":[{"to":"todo"},{"to":"done"}]}`, ""},
+	} {
+		var task Task
+		err := DecodeStrict([]byte(tc.body), &task)
+		fields := ErrorFields(err)
+		switch {
+		case tc.field == "" && err != nil:
+			t.Errorf("DecodeStrict(%s): %v, want success", tc.body, err)
+		case tc.field != "" && (len(fields) != 1 || fields[0].Field != tc.field):
+			t.Errorf("DecodeStrict(%s): %v, want a duplicate %s", tc.body, err, tc.field)
+		}
+	}
+
+	// Keys of a map are only duplicates when equal
+	var m map[string]string
+	if err := DecodeStrict([]byte(`{"a":"1","A":"2"}`), &m); err != nil {
+		t.Errorf("DecodeStrict into a map: %v, want success", err)
+	}
+	var validationErr *ValidationError
+	if err := DecodeStrict([]byte(`{"a":"1","a":"2"}`), &m); !errors.As(err, &validationErr) {
+		t.Errorf("DecodeStrict into a map: %v, want a ValidationError", err)
+	}
+}
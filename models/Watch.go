@@ -0,0 +1,88 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Kinds of task changes reported by WatchTasks.
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+// TaskChange is a change to the set of tasks selected by a watch.
+type TaskChange struct {
+	Type         string // ChangeCreated, ChangeUpdated or ChangeDeleted
+	Task         Task   // The task after the change, or before it when deleted
+	TaskRevision int64  // etcd revision at which Task was written
+	Revision     int64  // etcd revision of the change
+}
+
+// WatchTasks calls fn with every change to the tasks matching the filter and,
+// if given, the match function, from revision rev on, or from now if it is 0.
+// A task that starts or stops matching is reported as created or deleted, so
+// that the changes mirror the matching set. It returns when ctx is done, or
+// with the error of the watch or of fn.
+func (h *Handler) WatchTasks(ctx context.Context, f TaskFilter, match func(Task) bool, rev int64, fn func(TaskChange) error) error {
+	matches := func(kv *mvccpb.KeyValue) (*Task, bool, error) {
+		if kv == nil {
+			return nil, false, nil
+		}
+		var task Task
+		if err := json.Unmarshal(kv.Value, &task); err != nil {
+			return nil, false, err
+		}
+		return &task, f.Match(task) && (match == nil || match(task)), nil
+	}
+
+	opts := []clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithPrevKV()}
+	if rev > 0 {
+		opts = append(opts, clientv3.WithRev(rev))
+	}
+	for resp := range h.Client.Watch(clientv3.WithRequireLeader(ctx), TaskPrefix, opts...) {
+		if err := resp.Err(); err != nil {
+			return err
+		}
+
+		for _, ev := range resp.Events {
+			before, matchedBefore, err := matches(ev.PrevKv)
+			if err != nil {
+				return err
+			}
+			var after *Task
+			matchedAfter := false
+			if ev.Type == clientv3.EventTypePut {
+				if after, matchedAfter, err = matches(ev.Kv); err != nil {
+					return err
+				}
+			}
+
+			var change TaskChange
+			switch {
+			case matchedAfter && matchedBefore:
+				change = TaskChange{Type: ChangeUpdated, Task: *after, TaskRevision: ev.Kv.ModRevision}
+			case matchedAfter:
+				change = TaskChange{Type: ChangeCreated, Task: *after, TaskRevision: ev.Kv.ModRevision}
+			case matchedBefore:
+				change = TaskChange{Type: ChangeDeleted, Task: *before, TaskRevision: ev.PrevKv.ModRevision}
+			case ev.Type == clientv3.EventTypeDelete && ev.PrevKv == nil && f.IsEmpty() && match == nil:
+				// Without the previous value, which compaction may have removed,
+				// only an unfiltered watch knows the task was reported
+				id := string(ev.Kv.Key)[len(TaskPrefix):]
+				change = TaskChange{Type: ChangeDeleted, Task: Task{ID: id}}
+			default:
+				continue
+			}
+			change.Revision = ev.Kv.ModRevision
+			if err := fn(change); err != nil {
+				return err
+			}
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,547 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// WorkflowPrefix is the etcd key prefix under which workflows are stored.
+const WorkflowPrefix = "workflows/"
+
+// DefaultWorkflow is the workflow of tasks that do not name one. Unless a
+// workflow is stored under this name, it is the built-in one returned by
+// BuiltinWorkflow.
+const DefaultWorkflow = "default"
+
+// MaxTransitions is the number of status transitions kept on a task; older ones are dropped.
+const MaxTransitions = 100
+
+// Limits on the names of workflows and states, in characters.
+const (
+	MaxWorkflowNameLength = 100
+	MaxStateNameLength    = 50
+)
+
+// Errors returned for workflows.
+var (
+	ErrWorkflowNotFound = errors.New("Workflow not found")
+	ErrWorkflowInUse    = errors.New("Workflow is used by tasks")
+	ErrWorkflowChanged  = errors.New("Workflow was modified concurrently")
+)
+
+// Workflow is a state machine the status of tasks follows.
+type Workflow struct {
+	Name        string              `json:"name"`                  // Name of the workflow
+	States      []WorkflowState     `json:"states"`                // States of the workflow, in board order
+	Initial     string              `json:"initial"`               // State of new tasks
+	Transitions map[string][]string `json:"transitions,omitempty"` // States reachable from each state; any state is reachable if empty
+	CreatedAt   time.Time           `json:"created_at"`            // When the workflow was first saved
+	UpdatedAt   time.Time           `json:"updated_at"`            // When the workflow was last saved
+}
+
+// WorkflowState is a state of a workflow.
+type WorkflowState struct {
+	Name     string `json:"name"`               // Name of the state
+	Terminal bool   `json:"terminal,omitempty"` // Whether tasks in the state are completed
+}
+
+// WorkflowReq represents a request to save a workflow under a name.
+type WorkflowReq struct {
+	States      []WorkflowState     `json:"states"`                // States of the workflow, in board order
+	Initial     string              `json:"initial"`               // State of new tasks
+	Transitions map[string][]string `json:"transitions,omitempty"` // States reachable from each state; any state is reachable if empty
+}
+
+// WorkflowInfo is a workflow with the number of tasks in each of its states.
+type WorkflowInfo struct {
+	Workflow
+	Usage map[string]int `json:"usage"` // Number of tasks by status
+}
+
+// Transition records a change of the status of a task.
+type Transition struct {
+	From string    `json:"from,omitempty"` // Previous status, empty when the task was created
+	To   string    `json:"to"`             // New status
+	At   time.Time `json:"at"`             // When the status changed
+}
+
+// BuiltinWorkflow returns the default workflow used until another one is
+// stored under its name: todo, in_progress, in_review and the terminal done.
+func BuiltinWorkflow() *Workflow {
+	return &Workflow{
+		Name: DefaultWorkflow,
+		States: []WorkflowState{
+			{Name: "todo"},
+			{Name: "in_progress"},
+			{Name: "in_review"},
+			{Name: "done", Terminal: true},
+		},
+		Initial: "todo",
+		Transitions: map[string][]string{
+			"todo":        {"in_progress", "done"},
+			"in_progress": {"todo", "in_review", "done"},
+			"in_review":   {"in_progress", "done"},
+			"done":        {"todo"},
+		},
+	}
+}
+
+// Validate checks that a workflow is a well-formed state machine: state names
+// are unique, the initial state exists and is not terminal, at least one
+// state is terminal and transitions only name existing states.
+func (w *Workflow) Validate() error {
+	var fields []FieldError
+	fields = checkText(fields, "name", "Name", w.Name, MaxWorkflowNameLength, false)
+	if w.Name == "" || strings.Contains(w.Name, "/") {
+		fields = append(fields, FieldError{"name", "Name cannot be empty or contain /"})
+	}
+	if len(w.States) == 0 {
+		fields = append(fields, FieldError{"states", "States cannot be empty"})
+	}
+
+	names := map[string]bool{}
+	terminal := false
+	for i, s := range w.States {
+		field := "states." + strconv.Itoa(i) + ".name"
+		switch {
+		case !validStateName(s.Name):
+			fields = append(fields, FieldError{field, "State names must be made of lowercase letters, digits, _ and -, up to 50 characters"})
+		case names[s.Name]:
+			fields = append(fields, FieldError{field, "State " + s.Name + " appears more than once"})
+		}
+		names[s.Name] = true
+		terminal = terminal || s.Terminal
+	}
+	if len(w.States) > 0 && !terminal {
+		fields = append(fields, FieldError{"states", "At least one state must be terminal"})
+	}
+
+	switch {
+	case !names[w.Initial]:
+		fields = append(fields, FieldError{"initial", "Initial state must be one of the states"})
+	case w.IsTerminal(w.Initial):
+		fields = append(fields, FieldError{"initial", "Initial state cannot be terminal"})
+	}
+	for from, targets := range w.Transitions {
+		if !names[from] {
+			fields = append(fields, FieldError{"transitions." + from, "Unknown state " + from})
+		}
+		for _, to := range targets {
+			if !names[to] {
+				fields = append(fields, FieldError{"transitions." + from, "Unknown state " + to})
+			}
+		}
+	}
+	return fieldErrors(fields)
+}
+
+func validStateName(name string) bool {
+	if name == "" || len(name) > MaxStateNameLength {
+		return false
+	}
+	return strings.Trim(name, "abcdefghijklmnopqrstuvwxyz0123456789_-") == ""
+}
+
+// Has reports whether a workflow has a state.
+func (w *Workflow) Has(state string) bool {
+	for _, s := range w.States {
+		if s.Name == state {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal reports whether a state of the workflow completes tasks.
+func (w *Workflow) IsTerminal(state string) bool {
+	for _, s := range w.States {
+		if s.Name == state {
+			return s.Terminal
+		}
+	}
+	return false
+}
+
+// Allows reports whether tasks may move from one state to another. Tasks in a
+// state the workflow no longer has may move to any state.
+func (w *Workflow) Allows(from, to string) bool {
+	if from == to || len(w.Transitions) == 0 || !w.Has(from) {
+		return w.Has(to)
+	}
+	for _, s := range w.Transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StateNames returns the names of the states of the workflow, in order.
+func (w *Workflow) StateNames() []string {
+	names := make([]string, len(w.States))
+	for i, s := range w.States {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// defaultStatus returns the status of a task known only by its completion:
+// the initial state, or the first terminal one for completed tasks.
+func (w *Workflow) defaultStatus(completed bool) string {
+	if !completed {
+		return w.Initial
+	}
+	for _, s := range w.States {
+		if s.Terminal {
+			return s.Name
+		}
+	}
+	return w.Initial
+}
+
+// completionTarget returns the state a task in state from moves to when its
+// completion status is set, for clients that only know about completion:
+// the first reachable terminal state, or the initial state (else the first
+// reachable open one) when reopening. It is empty if no such state is reachable.
+func (w *Workflow) completionTarget(from string, completed bool) string {
+	if !completed && w.Allows(from, w.Initial) {
+		return w.Initial
+	}
+	for _, s := range w.States {
+		if s.Terminal == completed && s.Name != from && w.Allows(from, s.Name) {
+			return s.Name
+		}
+	}
+	return ""
+}
+
+// workflowName returns the name of the workflow of a task.
+func (t *Task) workflowName() string {
+	if t.Workflow == "" {
+		return DefaultWorkflow
+	}
+	return t.Workflow
+}
+
+// GetWorkflow fetches a workflow by name. The default workflow is the
+// built-in one unless another one is stored under its name.
+func (h *Handler) GetWorkflow(ctx context.Context, name string) (*Workflow, error) {
+	w, _, err := h.getWorkflow(ctx, name)
+	return w, err
+}
+
+// getWorkflow fetches a workflow by name together with the revision it was
+// last modified at, 0 for the built-in one.
+func (h *Handler) getWorkflow(ctx context.Context, name string) (*Workflow, int64, error) {
+	resp, err := h.Client.Get(ctx, WorkflowPrefix+name)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		if name == DefaultWorkflow {
+			return BuiltinWorkflow(), 0, nil
+		}
+		return nil, 0, ErrWorkflowNotFound
+	}
+
+	var w Workflow
+	if err := json.Unmarshal(resp.Kvs[0].Value, &w); err != nil {
+		return nil, 0, err
+	}
+	return &w, resp.Kvs[0].ModRevision, nil
+}
+
+// taskWorkflow fetches the workflow of a task, reporting a missing one as a
+// validation error, and returns the guard keeping the workflow from changing
+// before the task is stored.
+func (h *Handler) taskWorkflow(ctx context.Context, t *Task) (*Workflow, clientv3.Cmp, error) {
+	w, rev, err := h.getWorkflow(ctx, t.workflowName())
+	if errors.Is(err, ErrWorkflowNotFound) {
+		return nil, clientv3.Cmp{}, &ValidationError{Fields: []FieldError{{"workflow", "Workflow " + t.workflowName() + " not found"}}}
+	}
+	if err != nil {
+		return nil, clientv3.Cmp{}, err
+	}
+	return w, clientv3.Compare(clientv3.ModRevision(WorkflowPrefix+t.workflowName()), "=", rev), nil
+}
+
+// setInitialStatus checks the status of a task being stored as a whole, on
+// creation or import, and derives the missing one of status and completion
+// from the other. Tasks without transitions get one into their status. The
+// returned guard keeps the workflow from changing before the task is stored.
+func (h *Handler) setInitialStatus(ctx context.Context, t *Task) (clientv3.Cmp, error) {
+	w, cmp, err := h.taskWorkflow(ctx, t)
+	if err != nil {
+		return cmp, err
+	}
+	switch {
+	case t.Status == "":
+		t.Status = w.defaultStatus(t.Completed)
+	case !w.Has(t.Status):
+		return cmp, &ValidationError{Fields: []FieldError{{"status", "Status must be one of " + strings.Join(w.StateNames(), ", ")}}}
+	}
+	t.Completed = w.IsTerminal(t.Status)
+	if len(t.Transitions) == 0 {
+		t.Transitions = []Transition{{To: t.Status, At: t.UpdatedAt}}
+	}
+	return cmp, nil
+}
+
+// updateStatus moves an updated task to the status the request asks for,
+// either directly or, for clients only setting completed, to the matching
+// state of its workflow. The move must be allowed by the workflow and is
+// recorded in the transitions of the task. The returned guard keeps the
+// workflow from changing before the task is stored.
+func (h *Handler) updateStatus(ctx context.Context, before Task, t *Task, req UpdateReq) (clientv3.Cmp, error) {
+	w, cmp, err := h.taskWorkflow(ctx, t)
+	if err != nil {
+		return cmp, err
+	}
+	from := before.Status
+	if from == "" {
+		from = w.defaultStatus(before.Completed)
+	}
+
+	to := from
+	switch {
+	case req.Status != nil:
+		to = *req.Status
+		if !w.Has(to) {
+			return cmp, &ValidationError{Fields: []FieldError{{"status", "Status must be one of " + strings.Join(w.StateNames(), ", ")}}}
+		}
+		if t.workflowName() == before.workflowName() && !w.Allows(from, to) {
+			return cmp, &ValidationError{Fields: []FieldError{{"status", "Status cannot change from " + from + " to " + to}}}
+		}
+	case req.Completed != nil && *req.Completed != w.IsTerminal(from):
+		if to = w.completionTarget(from, *req.Completed); to == "" {
+			return cmp, &ValidationError{Fields: []FieldError{{"completed", "Status " + from + " cannot change to a state with completed " + strconv.FormatBool(*req.Completed)}}}
+		}
+	case !w.Has(from):
+		return cmp, &ValidationError{Fields: []FieldError{{"status", "Status " + from + " is not a state of workflow " + w.Name}}}
+	}
+
+	t.Status = to
+	t.Completed = w.IsTerminal(to)
+	if to != from || len(t.Transitions) == 0 {
+		prev := from
+		if len(t.Transitions) == 0 {
+			prev = ""
+		}
+		t.Transitions = append(t.Transitions, Transition{From: prev, To: to, At: t.UpdatedAt})
+		if n := len(t.Transitions); n > MaxTransitions {
+			t.Transitions = t.Transitions[n-MaxTransitions:]
+		}
+	}
+	return cmp, nil
+}
+
+// WorkflowUsage counts the tasks following a workflow, by status.
+func (h *Handler) WorkflowUsage(ctx context.Context, name string) (map[string]int, error) {
+	resp, err := h.Client.Get(ctx, TaskPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	usage := map[string]int{}
+	for _, kv := range resp.Kvs {
+		var task Task
+		if err := json.Unmarshal(kv.Value, &task); err != nil {
+			return nil, err
+		}
+		if task.workflowName() == name {
+			usage[task.Status]++
+		}
+	}
+	return usage, nil
+}
+
+// AssignStatuses gives the tasks stored without a status, such as the ones
+// created before tasks had workflows, the state of their workflow matching
+// their completion. Tasks modified in the meantime are left for the next run.
+func (h *Handler) AssignStatuses(ctx context.Context) error {
+	resp, err := h.Client.Get(ctx, TaskPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+
+	workflows := map[string]*Workflow{}
+	for _, kv := range resp.Kvs {
+		var task Task
+		if err := json.Unmarshal(kv.Value, &task); err != nil {
+			return err
+		}
+		if task.Status != "" {
+			continue
+		}
+		w, ok := workflows[task.workflowName()]
+		if !ok {
+			if w, err = h.GetWorkflow(ctx, task.workflowName()); err != nil {
+				// Tasks of missing workflows keep no status until it is saved again
+				if errors.Is(err, ErrWorkflowNotFound) {
+					continue
+				}
+				return err
+			}
+			workflows[task.workflowName()] = w
+		}
+
+		updated := task
+		updated.Status = w.defaultStatus(task.Completed)
+		updated.Transitions = []Transition{{To: updated.Status, At: task.UpdatedAt}}
+		if err := h.rewrite(ctx, kv, task, updated); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewrite replaces a stored task and its index entries, keeping its lease,
+// unless it was modified since it was read. Used to fill in new fields of
+// tasks stored before they existed, without an audit entry.
+func (h *Handler) rewrite(ctx context.Context, kv *mvccpb.KeyValue, before, after Task) error {
+	data, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+	lease := clientv3.LeaseID(kv.Lease)
+	ops := append([]clientv3.Op{clientv3.OpPut(string(kv.Key), string(data), clientv3.WithLease(lease))},
+		IndexOps(&before, &after, lease)...)
+	_, err = h.Client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(string(kv.Key)), "=", kv.ModRevision)).
+		Then(ops...).
+		Commit()
+	return err
+}
+
+// SaveWorkflow validates and stores a workflow under a name, keeping the
+// creation time of the one it replaces. It fails with ErrWorkflowInUse if
+// tasks, including the ones in the trash, are in states the new workflow drops.
+func (h *Handler) SaveWorkflow(ctx context.Context, name string, req WorkflowReq) (*Workflow, error) {
+	now := time.Now().UTC()
+	w := &Workflow{Name: name, States: req.States, Initial: req.Initial, Transitions: req.Transitions, CreatedAt: now, UpdatedAt: now}
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	err := h.writeWorkflow(ctx, name, func(existing *Workflow, usage map[string]int) (clientv3.Op, error) {
+		w.CreatedAt = now
+		if existing != nil && !existing.CreatedAt.IsZero() {
+			w.CreatedAt = existing.CreatedAt
+		}
+		if err := checkStatesInUse(w, usage); err != nil {
+			return clientv3.Op{}, err
+		}
+		data, err := json.Marshal(w)
+		if err != nil {
+			return clientv3.Op{}, err
+		}
+		return clientv3.OpPut(WorkflowPrefix+name, string(data)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// DeleteWorkflow deletes a stored workflow. Deleting the default workflow
+// brings back the built-in one. It fails with ErrWorkflowInUse if tasks,
+// including the ones in the trash, still follow the workflow, or are in
+// states the built-in one lacks.
+func (h *Handler) DeleteWorkflow(ctx context.Context, name string) error {
+	return h.writeWorkflow(ctx, name, func(existing *Workflow, usage map[string]int) (clientv3.Op, error) {
+		switch {
+		case existing == nil:
+			return clientv3.Op{}, ErrWorkflowNotFound
+		case name == DefaultWorkflow:
+			if err := checkStatesInUse(BuiltinWorkflow(), usage); err != nil {
+				return clientv3.Op{}, err
+			}
+		case len(usage) > 0:
+			return clientv3.Op{}, ErrWorkflowInUse
+		}
+		return clientv3.OpDelete(WorkflowPrefix + name), nil
+	})
+}
+
+// workflowAttempts bounds the retries of a workflow change racing with task changes.
+const workflowAttempts = 10
+
+// writeWorkflow changes the workflow stored under a name with the operation
+// returned by write, which is given the stored workflow, nil if there is none,
+// and the number of tasks following it by status, counting the ones in the
+// trash. The operation is only applied if neither the workflow nor any task
+// changed since they were read, and is prepared again otherwise.
+func (h *Handler) writeWorkflow(ctx context.Context, name string, write func(existing *Workflow, usage map[string]int) (clientv3.Op, error)) error {
+	key := WorkflowPrefix + name
+	for attempt := 0; attempt < workflowAttempts; attempt++ {
+		txn, err := h.Client.Txn(ctx).Then(
+			clientv3.OpGet(key),
+			clientv3.OpGet(TaskPrefix, clientv3.WithPrefix()),
+			clientv3.OpGet(TrashPrefix, clientv3.WithPrefix()),
+		).Commit()
+		if err != nil {
+			return err
+		}
+
+		var existing *Workflow
+		var rev int64
+		if kvs := txn.Responses[0].GetResponseRange().Kvs; len(kvs) > 0 {
+			existing = &Workflow{}
+			if err := json.Unmarshal(kvs[0].Value, existing); err != nil {
+				return err
+			}
+			rev = kvs[0].ModRevision
+		}
+		usage := map[string]int{}
+		for _, r := range txn.Responses[1:] {
+			for _, kv := range r.GetResponseRange().Kvs {
+				var task Task
+				if err := json.Unmarshal(kv.Value, &task); err != nil {
+					return err
+				}
+				if task.workflowName() == name {
+					usage[task.Status]++
+				}
+			}
+		}
+
+		op, err := write(existing, usage)
+		if err != nil {
+			return err
+		}
+		next := txn.Header.Revision + 1
+		resp, err := h.Client.Txn(ctx).
+			If(
+				clientv3.Compare(clientv3.ModRevision(key), "=", rev),
+				clientv3.Compare(clientv3.ModRevision(TaskPrefix), "<", next).WithPrefix(),
+				clientv3.Compare(clientv3.ModRevision(TrashPrefix), "<", next).WithPrefix(),
+			).
+			Then(op).
+			Commit()
+		if err != nil {
+			return err
+		}
+		if resp.Succeeded {
+			return nil
+		}
+	}
+	return ErrConflict
+}
+
+// checkStatesInUse fails with ErrWorkflowInUse if tasks following a workflow,
+// counted by status in usage, are in states its new version does not have.
+func checkStatesInUse(w *Workflow, usage map[string]int) error {
+	for status := range usage {
+		if status != "" && !w.Has(status) {
+			return fmt.Errorf("%w: tasks are in state %s", ErrWorkflowInUse, status)
+		}
+	}
+	return nil
+}
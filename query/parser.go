@@ -0,0 +1,300 @@
+package query
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"task-organizer/models"
+	"time"
+)
+
+// Filter is a compiled filter expression.
+type Filter struct {
+	source string
+	match  func(models.Task) bool
+}
+
+// Match reports whether a task satisfies the filter.
+func (f *Filter) Match(task models.Task) bool {
+	return f.match(task)
+}
+
+// String returns the expression the filter was compiled from.
+func (f *Filter) String() string {
+	return f.source
+}
+
+// Parse compiles a filter expression such as
+//
+//	completed = false AND priority >= high AND tag:backend AND due < 2026-11-01
+//
+// Comparisons take the form <field> <op> <value> with the operators
+// =, !=, <, <=, >, >= and : (contains for text, has for tags). They combine
+// with AND, OR, NOT and parentheses; AND binds tighter than OR. Values are bare
+// words or double-quoted strings; dates are YYYY-MM-DD or quoted RFC 3339 times.
+func Parse(expr string) (*Filter, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	match, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorAt(tok, "expected AND, OR or end of expression")
+	}
+	return &Filter{source: expr, match: match}, nil
+}
+
+// parser is a recursive descent parser over the tokens of an expression.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) errorAt(tok token, msg string) *Error {
+	return &Error{Pos: tok.pos, Token: tok.text, Msg: msg}
+}
+
+// parseOr parses: and ("OR" and)*
+func (p *parser) parseOr() (func(models.Task) bool, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(t models.Task) bool { return l(t) || right(t) }
+	}
+	return left, nil
+}
+
+// parseAnd parses: unary ("AND" unary)*
+func (p *parser) parseAnd() (func(models.Task) bool, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(t models.Task) bool { return l(t) && right(t) }
+	}
+	return left, nil
+}
+
+// parseUnary parses: "NOT" unary | "(" or ")" | comparison
+func (p *parser) parseUnary() (func(models.Task) bool, error) {
+	tok := p.peek()
+	switch tok.kind {
+	case tokenNot:
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(t models.Task) bool { return !inner(t) }, nil
+	case tokenLParen:
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, p.errorAt(closing, "expected )")
+		}
+		return inner, nil
+	case tokenWord:
+		return p.parseComparison()
+	default:
+		return nil, p.errorAt(tok, "expected a field name, NOT or (")
+	}
+}
+
+// parseComparison parses: field op value
+func (p *parser) parseComparison() (func(models.Task) bool, error) {
+	fieldTok := p.next()
+	field, ok := fields[strings.ToLower(fieldTok.text)]
+	if !ok {
+		return nil, p.errorAt(fieldTok, "unknown field")
+	}
+
+	opTok := p.next()
+	if opTok.kind != tokenOp {
+		return nil, p.errorAt(opTok, "expected an operator (=, !=, <, <=, >, >=, :)")
+	}
+	if !strings.Contains(field.ops, " "+opTok.text+" ") {
+		return nil, p.errorAt(opTok, "operator not supported for field "+fieldTok.text)
+	}
+
+	valueTok := p.next()
+	if valueTok.kind != tokenWord && valueTok.kind != tokenString {
+		return nil, p.errorAt(valueTok, "expected a value")
+	}
+
+	match, err := field.compile(opTok.text, valueTok.text)
+	if err != nil {
+		return nil, p.errorAt(valueTok, err.Error())
+	}
+	return match, nil
+}
+
+// field describes how a task field is compared.
+type field struct {
+	ops     string // Supported operators, space separated and padded
+	compile func(op, value string) (func(models.Task) bool, error)
+}
+
+const (
+	allOps      = " = != < <= > >= : "
+	equalityOps = " = != : "
+)
+
+// fields lists the task fields usable in filter expressions.
+var fields = map[string]field{
+	"id":          {allOps, textField(func(t models.Task) string { return t.ID })},
+	"title":       {allOps, textField(func(t models.Task) string { return t.Title })},
+	"description": {allOps, textField(func(t models.Task) string { return t.Description })},
+	"assignee":    {allOps, textField(func(t models.Task) string { return t.Assignee })},
+	"completed":   {equalityOps, boolField(func(t models.Task) bool { return t.Completed })},
+	"status":      {equalityOps, textField(func(t models.Task) string { return t.Status })},
+	"workflow":    {equalityOps, textField(func(t models.Task) string { return t.Workflow })},
+	"tag":         {equalityOps, tagField},
+	"tags":        {equalityOps, tagField},
+	"label":       {equalityOps, labelField},
+	"labels":      {equalityOps, labelField},
+	"priority":    {allOps, priorityField},
+	"due":         {allOps, timeField(func(t models.Task) *time.Time { return t.Due })},
+	"created":     {allOps, timeField(func(t models.Task) *time.Time { return &t.CreatedAt })},
+	"created_at":  {allOps, timeField(func(t models.Task) *time.Time { return &t.CreatedAt })},
+	"updated":     {allOps, timeField(func(t models.Task) *time.Time { return &t.UpdatedAt })},
+	"updated_at":  {allOps, timeField(func(t models.Task) *time.Time { return &t.UpdatedAt })},
+}
+
+// compare applies an ordering operator to the result of a three-way comparison.
+func compare(op string, cmp int) bool {
+	switch op {
+	case "=", ":":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// textField compares text case-insensitively; ":" tests whether it contains the value.
+func textField(get func(models.Task) string) func(op, value string) (func(models.Task) bool, error) {
+	return func(op, value string) (func(models.Task) bool, error) {
+		value = strings.ToLower(value)
+		if op == ":" {
+			return func(t models.Task) bool { return strings.Contains(strings.ToLower(get(t)), value) }, nil
+		}
+		return func(t models.Task) bool { return compare(op, strings.Compare(strings.ToLower(get(t)), value)) }, nil
+	}
+}
+
+// boolField compares a boolean with true or false.
+func boolField(get func(models.Task) bool) func(op, value string) (func(models.Task) bool, error) {
+	return func(op, value string) (func(models.Task) bool, error) {
+		want, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("expected true or false")
+		}
+		if op == "!=" {
+			return func(t models.Task) bool { return get(t) != want }, nil
+		}
+		return func(t models.Task) bool { return get(t) == want }, nil
+	}
+}
+
+// tagField tests whether a task has a tag; "!=" tests that it does not.
+func tagField(op, value string) (func(models.Task) bool, error) {
+	has := func(t models.Task) bool {
+		for _, tag := range t.Tags {
+			if strings.EqualFold(tag, value) {
+				return true
+			}
+		}
+		return false
+	}
+	if op == "!=" {
+		return func(t models.Task) bool { return !has(t) }, nil
+	}
+	return has, nil
+}
+
+// labelField tests whether a task has the label of an ID; "!=" tests that it does not.
+func labelField(op, value string) (func(models.Task) bool, error) {
+	has := func(t models.Task) bool {
+		for _, id := range t.Labels {
+			if id == value {
+				return true
+			}
+		}
+		return false
+	}
+	if op == "!=" {
+		return func(t models.Task) bool { return !has(t) }, nil
+	}
+	return has, nil
+}
+
+// priorityField compares priorities by rank; tasks without a priority never match.
+func priorityField(op, value string) (func(models.Task) bool, error) {
+	want, ok := models.PriorityRank(strings.ToLower(value))
+	if !ok {
+		return nil, errors.New("unknown priority, expected one of " + strings.Join(models.Priorities, ", "))
+	}
+	return func(t models.Task) bool {
+		rank, ok := models.PriorityRank(t.Priority)
+		return ok && compare(op, rank-want)
+	}, nil
+}
+
+// timeField compares times with a date (YYYY-MM-DD) or an RFC 3339 time;
+// tasks without the time never match.
+func timeField(get func(models.Task) *time.Time) func(op, value string) (func(models.Task) bool, error) {
+	return func(op, value string) (func(models.Task) bool, error) {
+		want, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			if want, err = time.Parse("2006-01-02", value); err != nil {
+				return nil, errors.New("expected a date (YYYY-MM-DD) or RFC 3339 time")
+			}
+		}
+		return func(t models.Task) bool {
+			got := get(t)
+			return got != nil && compare(op, got.Compare(want))
+		}, nil
+	}
+}
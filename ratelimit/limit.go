@@ -0,0 +1,110 @@
+// Package ratelimit limits the rate of requests of every client with token
+// buckets, kept in memory or shared across replicas through etcd.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows a number of requests per period. Clients may burst up to the
+// whole number at once, after which their allowance refills evenly over the
+// period.
+type Limit struct {
+	Requests int           // Size of the bucket; 0 disables the limit
+	Period   time.Duration // Time to refill the bucket completely
+}
+
+// ParseLimit parses a limit written as <requests>/<period>, e.g. "60/1m".
+// A period of 1 may be left out, as in "10/s". "0" and "off" disable the limit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "0" || strings.EqualFold(s, "off") {
+		return Limit{}, nil
+	}
+
+	n, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<period>", s)
+	}
+	requests, err := strconv.Atoi(n)
+	if err != nil || requests < 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: bad number of requests", s)
+	}
+	if per != "" && (per[0] < '0' || per[0] > '9') {
+		per = "1" + per
+	}
+	period, err := time.ParseDuration(per)
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: bad period", s)
+	}
+	return Limit{Requests: requests, Period: period}, nil
+}
+
+// Enabled reports whether the limit restricts requests at all.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0
+}
+
+// String formats the limit as ParseLimit reads it.
+func (l Limit) String() string {
+	if !l.Enabled() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// rate returns how many tokens the bucket regains per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed    bool          // Whether the request may proceed
+	Limit      int           // Size of the bucket
+	Remaining  int           // Whole tokens left in the bucket
+	Reset      time.Duration // Time until the bucket is full again
+	RetryAfter time.Duration // Time until a token is available, if the request was denied
+}
+
+// bucket is the state of a token bucket.
+type bucket struct {
+	Tokens  float64   `json:"tokens"`  // Tokens left as of Updated
+	Updated time.Time `json:"updated"` // When Tokens was computed
+}
+
+// take refills the bucket up to now and removes a token from it if it has one.
+// A zero bucket is full.
+func (b *bucket) take(l Limit, now time.Time) Result {
+	capacity := float64(l.Requests)
+	if b.Updated.IsZero() {
+		b.Tokens = capacity
+	} else if elapsed := now.Sub(b.Updated).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(capacity, b.Tokens+elapsed*l.rate())
+	}
+	b.Updated = now
+
+	res := Result{Limit: l.Requests}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.Tokens) / l.rate())
+	}
+	res.Remaining = int(b.Tokens)
+	res.Reset = seconds((capacity - b.Tokens) / l.rate())
+	return res
+}
+
+// full returns when the bucket is full again, after which it can be forgotten.
+func (b *bucket) full(l Limit) time.Time {
+	return b.Updated.Add(seconds((float64(l.Requests) - b.Tokens) / l.rate()))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,183 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Default limits of read and write requests per client.
+const (
+	DefaultReadLimit  = "600/1m"
+	DefaultWriteLimit = "60/1m"
+)
+
+// storeTimeout bounds the time spent on the store for a request.
+const storeTimeout = 2 * time.Second
+
+// Limiter limits the rate of requests of every client, with separate buckets
+// for reads and writes.
+type Limiter struct {
+	Read  Limit           // Limit of GET, HEAD, OPTIONS, PROPFIND and REPORT requests
+	Write Limit           // Limit of all other requests
+	Store Store           // Where the buckets are kept
+	Keys  map[string]bool // Hashes of the API keys clients are told apart by, see HashKey
+}
+
+// FromEnv configures a limiter from the environment:
+//
+//   - RATE_LIMIT_READ and RATE_LIMIT_WRITE set the limits of read and write
+//     requests as ParseLimit reads them, "off" disabling them;
+//   - RATE_LIMIT_STORE selects where buckets are kept: "memory", the default,
+//     or "etcd" to share them across replicas through the given client;
+//   - RATE_LIMIT_API_KEYS lists the API keys, separated by commas, that give
+//     their clients a bucket of their own.
+func FromEnv(client *clientv3.Client) (*Limiter, error) {
+	l := &Limiter{Keys: map[string]bool{}}
+	for _, key := range strings.Split(os.Getenv("RATE_LIMIT_API_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			l.Keys[HashKey(key)] = true
+		}
+	}
+	var err error
+	if l.Read, err = ParseLimit(envOr("RATE_LIMIT_READ", DefaultReadLimit)); err != nil {
+		return nil, err
+	}
+	if l.Write, err = ParseLimit(envOr("RATE_LIMIT_WRITE", DefaultWriteLimit)); err != nil {
+		return nil, err
+	}
+
+	switch store := envOr("RATE_LIMIT_STORE", "memory"); store {
+	case "memory":
+		l.Store = NewMemoryStore()
+	case "etcd":
+		ttl := l.Read.Period
+		if l.Write.Period > ttl {
+			ttl = l.Write.Period
+		}
+		l.Store = NewEtcdStore(client, ttl)
+	default:
+		return nil, fmt.Errorf("invalid rate limit store %q: expected memory or etcd", store)
+	}
+	return l, nil
+}
+
+// TrustedProxies returns the addresses and networks of the proxies trusted to
+// report the client IP in X-Forwarded-For, read from the TRUSTED_PROXIES
+// environment variable as a list separated by commas. It returns nil, trusting
+// no proxy, when unset.
+func TrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+func envOr(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// Middleware limits the requests of every client, identified by its API key
+// if it sends one of Keys and by its IP address otherwise. Responses carry the
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy
+// headers; requests over the limit are answered with 429 and Retry-After.
+// Requests are let through when the store cannot be reached, so that an
+// unavailable etcd does not take the API down with it.
+func (l *Limiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		class, limit := "write", l.Write
+		if isRead(c.Request.Method) {
+			class, limit = "read", l.Read
+		}
+		if !limit.Enabled() {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), storeTimeout)
+		res, err := l.Store.Take(ctx, class+"/"+l.clientKey(c), limit, time.Now())
+		cancel()
+		// A bucket updated too often by other replicas belongs to a client
+		// sending requests faster than any limit would allow
+		if errors.Is(err, ErrContention) {
+			res = Result{Limit: limit.Requests, Reset: limit.Period, RetryAfter: time.Second}
+			err = nil
+		}
+		if err != nil {
+			log.Println("Failed to apply the rate limit:", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Period)))
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// isRead reports whether requests with the method only read.
+func isRead(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, "PROPFIND", "REPORT":
+		return true
+	}
+	return false
+}
+
+// HashKey returns the hash an API key is known by in Keys and in the store.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
+
+// APIKey returns the API key a request is sent with, in X-API-Key or as a
+// bearer token, empty if there is none.
+func APIKey(c *gin.Context) string {
+	key := c.GetHeader("X-API-Key")
+	if auth := c.GetHeader("Authorization"); key == "" && len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		key = auth[7:]
+	}
+	return key
+}
+
+// clientKey identifies the client of a request: by a hash of its API key,
+// sent in X-API-Key or as a bearer token, or else by its IP address. Keys
+// missing from Keys are ignored, so that clients cannot escape their limit by
+// making up a new key for every request.
+func (l *Limiter) clientKey(c *gin.Context) string {
+	if key := APIKey(c); key != "" {
+		if hash := HashKey(key); l.Keys[hash] {
+			return "key/" + hash
+		}
+	}
+	return "ip/" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,185 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Store keeps the token buckets of the clients.
+type Store interface {
+	// Take removes a token from the bucket under key, refilled to now
+	// according to the limit.
+	Take(ctx context.Context, key string, l Limit, now time.Time) (Result, error)
+}
+
+// sweepInterval is how often full buckets are dropped from a MemoryStore.
+const sweepInterval = time.Minute
+
+// MemoryStore keeps token buckets in memory, for a single replica.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	swept   time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	limit Limit
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*memoryBucket{}}
+}
+
+// Take implements Store.
+func (s *MemoryStore) Take(ctx context.Context, key string, l Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Forget the buckets that have filled up again, which are as good as new
+	if now.Sub(s.swept) >= sweepInterval {
+		for k, b := range s.buckets {
+			if !b.full(b.limit).After(now) {
+				delete(s.buckets, k)
+			}
+		}
+		s.swept = now
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{}
+		s.buckets[key] = b
+	}
+	b.limit = l
+	return b.take(l, now), nil
+}
+
+// EtcdPrefix is the etcd key prefix of the token buckets shared by replicas.
+const EtcdPrefix = "ratelimit/"
+
+// maxAttempts bounds how often a bucket update is retried when other
+// replicas update the same bucket concurrently.
+const maxAttempts = 10
+
+// retryBackoff is the longest wait before retrying a bucket update.
+const retryBackoff = 20 * time.Millisecond
+
+// lockStripes is the number of locks serialising the updates of buckets
+// within a replica, each guarding the buckets whose keys hash to it.
+const lockStripes = 64
+
+// ErrContention is returned when a bucket kept changing under a Take.
+var ErrContention = errors.New("rate limit bucket is updated too often concurrently")
+
+// EtcdStore keeps token buckets in etcd, so that replicas share them. Buckets
+// are attached to a lease renewed every period, so that those of clients that
+// went away are removed by etcd.
+type EtcdStore struct {
+	client *clientv3.Client
+	ttl    time.Duration // Lifetime of a bucket after its last update, at least
+	locks  [lockStripes]sync.Mutex
+
+	mu           sync.Mutex
+	lease        clientv3.LeaseID
+	leaseExpires time.Time
+}
+
+// NewEtcdStore returns a store keeping buckets in etcd under EtcdPrefix. Buckets
+// live at least ttl after their last update, which should be the longest period
+// of the limits applied.
+func NewEtcdStore(client *clientv3.Client, ttl time.Duration) *EtcdStore {
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	return &EtcdStore{client: client, ttl: ttl}
+}
+
+// Take implements Store. The bucket is updated in a transaction that fails if
+// another replica updated it in the meantime, in which case the update is
+// retried on the value the transaction read. Updates from within the replica
+// are serialised instead, as they would only get in each other's way.
+func (s *EtcdStore) Take(ctx context.Context, key string, l Limit, now time.Time) (Result, error) {
+	key = EtcdPrefix + key
+	lease, err := s.currentLease(ctx, now)
+	if err != nil {
+		return Result{}, err
+	}
+
+	lock := s.lock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	resp, err := s.client.Get(ctx, key)
+	if err != nil {
+		return Result{}, err
+	}
+	kvs := resp.Kvs
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(rand.Int63n(int64(retryBackoff))))
+		}
+
+		var b bucket
+		var rev int64
+		if len(kvs) > 0 {
+			rev = kvs[0].ModRevision
+			if err := json.Unmarshal(kvs[0].Value, &b); err != nil {
+				b = bucket{}
+			}
+		}
+		res := b.take(l, now)
+		data, err := json.Marshal(b)
+		if err != nil {
+			return Result{}, err
+		}
+
+		txn, err := s.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(key), "=", rev)).
+			Then(clientv3.OpPut(key, string(data), clientv3.WithLease(lease))).
+			Else(clientv3.OpGet(key)).
+			Commit()
+		if err != nil {
+			return Result{}, err
+		}
+		if txn.Succeeded {
+			return res, nil
+		}
+		kvs = []*mvccpb.KeyValue(txn.Responses[0].GetResponseRange().Kvs)
+	}
+	return Result{}, ErrContention
+}
+
+// lock returns the lock serialising the updates of the bucket under key.
+func (s *EtcdStore) lock(key string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &s.locks[h.Sum32()%lockStripes]
+}
+
+// currentLease returns the lease to attach buckets to, granting a new one
+// when the current one would expire within ttl.
+func (s *EtcdStore) currentLease(ctx context.Context, now time.Time) (clientv3.LeaseID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lease != 0 && s.leaseExpires.Sub(now) > s.ttl {
+		return s.lease, nil
+	}
+
+	lifetime := 2 * s.ttl
+	lease, err := s.client.Grant(ctx, int64(lifetime.Round(time.Second)/time.Second))
+	if err != nil {
+		return 0, err
+	}
+	s.lease, s.leaseExpires = lease.ID, now.Add(lifetime)
+	return s.lease, nil
+}
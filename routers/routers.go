@@ -1,8 +1,17 @@
 package routers
 
 import (
+	"context"
+	"log"
+	"net/http"
+	"task-organizer/blob"
+	"task-organizer/graphqlserver"
+	"task-organizer/grpcserver"
 	"task-organizer/handlers"
 	"task-organizer/models"
+	"task-organizer/ratelimit"
+	"task-organizer/search"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	swaggerfiles "github.com/swaggo/files"
@@ -18,10 +27,68 @@ func IdeaRouter(r *gin.Engine) {
 		panic(err)
 	}
 
+	// Bring the secondary indexes in line with the stored tasks
+	if err := handler1.RebuildIndexes(context.Background()); err != nil {
+		log.Println("Failed to rebuild task indexes:", err)
+	}
+
+	// Give the tasks stored before they could be ordered a position
+	if err := handler1.AssignPositions(context.Background()); err != nil {
+		log.Println("Failed to assign task positions:", err)
+	}
+
+	// Shorten the positions of the list if they grew long
+	if err := handler1.RebalancePositions(context.Background()); err != nil {
+		log.Println("Failed to rebalance task positions:", err)
+	}
+
+	// Give the tasks stored before they had workflows the status matching their completion
+	if err := handler1.AssignStatuses(context.Background()); err != nil {
+		log.Println("Failed to assign task statuses:", err)
+	}
+
+	// Build the full-text search index and keep it current by watching etcd
+	index := search.New()
+	if err := index.Load(context.Background(), handler1.Client); err != nil {
+		log.Println("Failed to load search index:", err)
+	}
+	go index.Watch(context.Background(), handler1.Client)
+
+	// Keep the contents of attachments out of etcd, collecting the unused ones every hour
+	blobs, err := blob.FromEnv()
+	if err != nil {
+		panic(err)
+	}
+	go handler1.CollectBlobsEvery(context.Background(), blobs, time.Hour)
+
+	// Delete the comments of tasks that expired or were purged from the trash every hour
+	go handler1.CollectCommentsEvery(context.Background(), time.Hour)
+
+	// Serve the same tasks over gRPC on a separate port
+	go func() {
+		if err := grpcserver.ListenAndServe(grpcserver.Addr(), handler1, handler2); err != nil {
+			log.Fatal("Failed to start the gRPC server:", err)
+		}
+	}()
+
 	// Setup the route for Swagger documentation.
 	// This serves the Swagger UI to visualize and interact with the API documentation.
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
+	// Only take the client IP from X-Forwarded-For when the request comes
+	// through one of the proxies listed in TRUSTED_PROXIES
+	if err := r.SetTrustedProxies(ratelimit.TrustedProxies()); err != nil {
+		panic(err)
+	}
+
+	// Limit the rate of requests of every client to the routes below,
+	// keeping the counters in etcd on port 2379 if they are shared
+	limiter, err := ratelimit.FromEnv(handler1.Client)
+	if err != nil {
+		panic(err)
+	}
+	r.Use(limiter.Middleware())
+
 	// Create a new route group for the "/tasks" endpoint.
 	iR := r.Group("/tasks")
 
@@ -34,6 +101,36 @@ func IdeaRouter(r *gin.Engine) {
 		handlers.GetAllTasks(c)
 	})
 
+	// Search tasks by words in their title or description
+	iR.GET("search", func(c *gin.Context) {
+		c.Set("index", index) // Set the search index
+		handlers.SearchTasks(c)
+	})
+
+	// Export all tasks from port 2379
+	iR.GET("export", func(c *gin.Context) {
+		c.Set("handler", handler1) // Set the handler for port 2379
+		handlers.ExportTasks(c)
+	})
+
+	// Import tasks from port 2379
+	iR.POST("import", func(c *gin.Context) {
+		c.Set("handler", handler1) // Set the handler for port 2379
+		handlers.ImportTasks(c)
+	})
+
+	// Export all tasks as todo.txt from port 2379
+	iR.GET("todo.txt", func(c *gin.Context) {
+		c.Set("handler", handler1) // Set the handler for port 2379
+		handlers.ExportTodoTxt(c)
+	})
+
+	// Import tasks from todo.txt from port 2379
+	iR.POST("todo.txt", func(c *gin.Context) {
+		c.Set("handler", handler1) // Set the handler for port 2379
+		handlers.ImportTodoTxt(c)
+	})
+
 	// Get a task by its ID from port 2380
 	iR.GET(":id", func(c *gin.Context) {
 		c.Set("handler", handler2) // Set the handler for port 2380
@@ -63,4 +160,301 @@ func IdeaRouter(r *gin.Engine) {
 		c.Set("handler", handler2) // Set the handler for port 2380
 		handlers.UpdateTask(c)
 	})
+
+	// Create, update and delete tasks in bulk from port 2379.
+	// gin reads the colon of the custom method as the start of a parameter,
+	// which holds the method with its colon
+	r.POST("/tasks:method", func(c *gin.Context) {
+		if c.Param("method") != ":batch" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			return
+		}
+		c.Set("handler", handler1) // Set the handler for port 2379
+		handlers.BatchTasks(c)
+	})
+
+	// Get the history of a task by its ID from port 2380
+	iR.GET(":id/history", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.GetTaskHistory(c)
+	})
+
+	// Move a task by its ID in the list from port 2380
+	iR.POST(":id/move", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.MoveTask(c)
+	})
+
+	// Revert a task by its ID to a previous revision from port 2380
+	iR.POST(":id/revert", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.RevertTask(c)
+	})
+
+	// Get the comments of a task by its ID from port 2380
+	iR.GET(":id/comments", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.GetComments(c)
+	})
+
+	// Comment on a task by its ID from port 2380
+	iR.POST(":id/comments", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.AddComment(c)
+	})
+
+	// Get a comment of a task by their IDs from port 2380
+	iR.GET(":id/comments/:commentId", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.GetComment(c)
+	})
+
+	// Edit a comment of a task by their IDs from port 2380
+	iR.PUT(":id/comments/:commentId", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.UpdateComment(c)
+	})
+
+	// Delete a comment of a task by their IDs from port 2380
+	iR.DELETE(":id/comments/:commentId", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.DeleteComment(c)
+	})
+
+	// Get the attachments of a task by its ID from port 2380
+	iR.GET(":id/attachments", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.GetAttachments(c)
+	})
+
+	// Attach a file to a task by its ID from port 2380
+	iR.POST(":id/attachments", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		c.Set("blobs", blobs)      // Set the blob store
+		handlers.AddAttachment(c)
+	})
+
+	// Download an attachment of a task by their IDs from port 2380
+	iR.GET(":id/attachments/:attachmentId", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		c.Set("blobs", blobs)      // Set the blob store
+		handlers.GetAttachment(c)
+	})
+
+	// Delete an attachment of a task by their IDs from port 2380
+	iR.DELETE(":id/attachments/:attachmentId", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		c.Set("blobs", blobs)      // Set the blob store
+		handlers.DeleteAttachment(c)
+	})
+
+	// Create a new route group for the "/trash" endpoint.
+	tR := r.Group("/trash")
+
+	// Get a list of all deleted tasks from port 2379
+	tR.GET("", func(c *gin.Context) {
+		c.Set("handler", handler1) // Set the handler for port 2379
+		handlers.GetTrash(c)
+	})
+
+	// Restore a deleted task by its ID from port 2380
+	tR.POST(":id/restore", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.RestoreTask(c)
+	})
+
+	// Permanently delete a task from the trash by its ID from port 2380
+	tR.DELETE(":id", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		c.Set("blobs", blobs)      // Set the blob store
+		handlers.PurgeTask(c)
+	})
+
+	// Permanently delete all tasks in the trash from port 2379
+	tR.DELETE("", func(c *gin.Context) {
+		c.Set("handler", handler1) // Set the handler for port 2379
+		c.Set("blobs", blobs)      // Set the blob store
+		handlers.PurgeTrash(c)
+	})
+
+	// Export all tasks as an iCalendar file from port 2379
+	r.GET("/tasks.ics", func(c *gin.Context) {
+		c.Set("handler", handler1) // Set the handler for port 2379
+		handlers.ExportCalendar(c)
+	})
+
+	// Point CalDAV clients discovering the service to its root
+	for _, method := range []string{http.MethodGet, "PROPFIND"} {
+		r.Handle(method, "/.well-known/caldav", func(c *gin.Context) {
+			c.Redirect(http.StatusMovedPermanently, "/caldav/")
+		})
+	}
+
+	// Create a new route group for the "/caldav" endpoint.
+	dR := r.Group("/caldav")
+
+	// Describe the CalDAV root and the tasks calendar from port 2379
+	for _, path := range []string{"/", "/tasks/", "/tasks/:file"} {
+		dR.OPTIONS(path, handlers.CalDAVOptions)
+		dR.Handle("PROPFIND", path, func(c *gin.Context) {
+			c.Set("handler", handler1) // Set the handler for port 2379
+			handlers.CalDAVPropfind(c)
+		})
+	}
+
+	// Query the tasks calendar from port 2379
+	dR.Handle("REPORT", "/tasks/", func(c *gin.Context) {
+		c.Set("handler", handler1) // Set the handler for port 2379
+		handlers.CalDAVReport(c)
+	})
+
+	// Get a task as an iCalendar object from port 2379
+	dR.GET("/tasks/:file", func(c *gin.Context) {
+		c.Set("handler", handler1) // Set the handler for port 2379
+		handlers.CalDAVGetTask(c)
+	})
+
+	// Create or replace a task from an iCalendar object from port 2380
+	dR.PUT("/tasks/:file", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.CalDAVPutTask(c)
+	})
+
+	// Delete a task through CalDAV from port 2380
+	dR.DELETE("/tasks/:file", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.CalDAVDeleteTask(c)
+	})
+
+	// Create a new route group for the "/filters" endpoint.
+	fR := r.Group("/filters")
+
+	// Get a list of all saved filters from port 2379
+	fR.GET("", func(c *gin.Context) {
+		c.Set("handler", handler1) // Set the handler for port 2379
+		handlers.GetFilters(c)
+	})
+
+	// Get a saved filter by its name from port 2380
+	fR.GET(":name", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.GetFilter(c)
+	})
+
+	// Save a filter under its name from port 2380
+	fR.PUT(":name", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.SaveFilter(c)
+	})
+
+	// Delete a saved filter by its name from port 2380
+	fR.DELETE(":name", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.DeleteFilter(c)
+	})
+
+	// Create a new route group for the "/workflows" endpoint.
+	wR := r.Group("/workflows")
+
+	// Get a list of all workflows from port 2379
+	wR.GET("", func(c *gin.Context) {
+		c.Set("handler", handler1) // Set the handler for port 2379
+		handlers.GetWorkflows(c)
+	})
+
+	// Get a workflow by its name from port 2380
+	wR.GET(":name", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.GetWorkflow(c)
+	})
+
+	// Save a workflow under its name from port 2380
+	wR.PUT(":name", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.SaveWorkflow(c)
+	})
+
+	// Delete a workflow by its name from port 2380
+	wR.DELETE(":name", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.DeleteWorkflow(c)
+	})
+
+	// Create a new route group for the "/labels" endpoint.
+	lR := r.Group("/labels")
+
+	// Get a list of all labels from port 2379
+	lR.GET("", func(c *gin.Context) {
+		c.Set("handler", handler1) // Set the handler for port 2379
+		handlers.GetLabels(c)
+	})
+
+	// Create a new label from port 2379
+	lR.POST("", func(c *gin.Context) {
+		c.Set("handler", handler1) // Set the handler for port 2379
+		handlers.CreateLabel(c)
+	})
+
+	// Get a label by its ID from port 2380
+	lR.GET(":id", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.GetLabel(c)
+	})
+
+	// Update a label by its ID from port 2380
+	lR.PUT(":id", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.UpdateLabel(c)
+	})
+
+	// Delete a label by its ID from port 2380
+	lR.DELETE(":id", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.DeleteLabel(c)
+	})
+
+	// Merge labels into a label by its ID from port 2380
+	lR.POST(":id/merge", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.MergeLabels(c)
+	})
+
+	// Create a new route group for the "/admin" endpoint, open only to
+	// requests carrying the admin token.
+	aR := r.Group("/admin", handlers.RequireAdmin(handlers.AdminToken()))
+
+	// Rebuild the search index from port 2379
+	aR.POST("search/rebuild", func(c *gin.Context) {
+		c.Set("handler", handler1) // Set the handler for port 2379
+		c.Set("index", index)      // Set the search index
+		handlers.RebuildSearchIndex(c)
+	})
+
+	// Back up the task keyspace from port 2379
+	aR.GET("backup", func(c *gin.Context) {
+		c.Set("handler", handler1) // Set the handler for port 2379
+		handlers.BackupTasks(c)
+	})
+
+	// Restore the task keyspace from a backup from port 2380
+	aR.POST("restore", func(c *gin.Context) {
+		c.Set("handler", handler2) // Set the handler for port 2380
+		handlers.RestoreBackup(c)
+	})
+
+	// Get the audit log of task mutations from port 2379
+	aR.GET("audit", func(c *gin.Context) {
+		c.Set("handler", handler1) // Set the handler for port 2379
+		handlers.GetAuditLog(c)
+	})
+
+	// Serve GraphQL queries and mutations, and subscriptions over WebSocket,
+	// through the etcd clients on ports 2379 and 2380
+	gql := graphqlserver.New(handler1, handler2)
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		r.Handle(method, "/graphql", func(c *gin.Context) {
+			c.Set("graphql", gql) // Set the GraphQL handler
+			handlers.GraphQL(c)
+		})
+	}
 }
@@ -0,0 +1,272 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"task-organizer/models"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// titleWeight is how much more a word in the title counts than one in the description.
+const titleWeight = 2
+
+// prefixWeight is how much a word matched only by prefix counts compared to an exact match.
+const prefixWeight = 0.5
+
+// Highlights holds the matched fields of a task as HTML, escaped, with the
+// matching words marked.
+type Highlights struct {
+	Title       string `json:"title"`                 // Escaped title with matches wrapped in <mark> tags
+	Description string `json:"description,omitempty"` // Escaped description with matches wrapped in <mark> tags
+}
+
+// Result represents a task matching a search query.
+type Result struct {
+	Task       models.Task `json:"task"`       // The matching task
+	Score      float64     `json:"score"`      // Relevance of the task, higher is better
+	Highlights Highlights  `json:"highlights"` // Matched words in the task
+}
+
+// Index is an in-process inverted index over task titles and descriptions.
+type Index struct {
+	mu       sync.RWMutex
+	tasks    map[string]models.Task        // Indexed tasks by ID
+	postings map[string]map[string]float64 // Weighted term frequencies by term and task ID
+	terms    []string                      // Sorted terms for prefix matching, nil when stale
+	revision int64                         // etcd revision the index reflects
+}
+
+// New creates an empty index.
+func New() *Index {
+	return &Index{tasks: map[string]models.Task{}, postings: map[string]map[string]float64{}}
+}
+
+// Load replaces the contents of the index with the tasks currently stored in etcd.
+func (ix *Index) Load(ctx context.Context, cli *clientv3.Client) error {
+	resp, err := cli.Get(ctx, models.TaskPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+
+	fresh := New()
+	for _, kv := range resp.Kvs {
+		var task models.Task
+		if err := json.Unmarshal(kv.Value, &task); err != nil {
+			return err
+		}
+		fresh.put(task)
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.tasks, ix.postings, ix.terms = fresh.tasks, fresh.postings, nil
+	ix.revision = resp.Header.Revision
+	return nil
+}
+
+// Watch keeps the index current by following changes of the tasks in etcd
+// until ctx is done. The index is reloaded if the watch falls behind compaction.
+func (ix *Index) Watch(ctx context.Context, cli *clientv3.Client) {
+	for ctx.Err() == nil {
+		ix.mu.RLock()
+		rev := ix.revision
+		ix.mu.RUnlock()
+
+		wch := cli.Watch(clientv3.WithRequireLeader(ctx), models.TaskPrefix, clientv3.WithPrefix(), clientv3.WithRev(rev+1))
+		for resp := range wch {
+			if err := resp.Err(); err != nil {
+				log.Println("Search index watch failed:", err)
+				break
+			}
+			ix.apply(resp.Events)
+		}
+
+		// The watch ended; reload everything before watching again
+		if ctx.Err() != nil {
+			return
+		}
+		if err := ix.Load(ctx, cli); err != nil {
+			log.Println("Failed to reload search index:", err)
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+// apply updates the index with watched changes not reflected yet.
+func (ix *Index) apply(events []*clientv3.Event) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	for _, ev := range events {
+		if ev.Kv.ModRevision <= ix.revision {
+			continue
+		}
+		id := strings.TrimPrefix(string(ev.Kv.Key), models.TaskPrefix)
+		ix.remove(id)
+		if ev.Type == mvccpb.PUT {
+			var task models.Task
+			if err := json.Unmarshal(ev.Kv.Value, &task); err != nil {
+				log.Println("Failed to index task", id+":", err)
+				continue
+			}
+			ix.put(task)
+		}
+		ix.revision = ev.Kv.ModRevision
+	}
+}
+
+// put adds a task to the index. The caller must hold the write lock.
+func (ix *Index) put(task models.Task) {
+	ix.tasks[task.ID] = task
+	weights := map[string]float64{}
+	for _, term := range Tokenize(task.Title) {
+		weights[term] += titleWeight
+	}
+	for _, term := range Tokenize(task.Description) {
+		weights[term]++
+	}
+	for term, w := range weights {
+		if ix.postings[term] == nil {
+			ix.postings[term] = map[string]float64{}
+			ix.terms = nil
+		}
+		ix.postings[term][task.ID] = w
+	}
+}
+
+// remove drops a task from the index. The caller must hold the write lock.
+func (ix *Index) remove(id string) {
+	task, ok := ix.tasks[id]
+	if !ok {
+		return
+	}
+	delete(ix.tasks, id)
+	for _, term := range append(Tokenize(task.Title), Tokenize(task.Description)...) {
+		if docs, ok := ix.postings[term]; ok {
+			delete(docs, id)
+			if len(docs) == 0 {
+				delete(ix.postings, term)
+				ix.terms = nil
+			}
+		}
+	}
+}
+
+// sortedTerms returns the indexed terms in order, rebuilding the list if stale.
+func (ix *Index) sortedTerms() []string {
+	ix.mu.RLock()
+	terms := ix.terms
+	ix.mu.RUnlock()
+	if terms != nil {
+		return terms
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.terms == nil {
+		ix.terms = make([]string, 0, len(ix.postings))
+		for term := range ix.postings {
+			ix.terms = append(ix.terms, term)
+		}
+		sort.Strings(ix.terms)
+	}
+	return ix.terms
+}
+
+// Search returns up to limit tasks containing every word of the query, each word
+// matching either exactly or as a prefix of a word in the title or description.
+// Results are ranked by a TF-IDF score, title matches weighing more.
+func (ix *Index) Search(query string, limit int) []Result {
+	queryTerms := unique(Tokenize(query))
+	if len(queryTerms) == 0 {
+		return []Result{}
+	}
+	terms := ix.sortedTerms()
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	scores := map[string]float64{}
+	matchedTerms := map[string]map[string]bool{} // Words matched per task, for highlighting
+	for i, q := range queryTerms {
+		found := map[string]bool{}
+
+		// Expand the query word to every indexed word it is a prefix of
+		for j := sort.SearchStrings(terms, q); j < len(terms) && strings.HasPrefix(terms[j], q); j++ {
+			term := terms[j]
+			docs := ix.postings[term]
+			if len(docs) == 0 {
+				continue
+			}
+			idf := math.Log(1 + float64(len(ix.tasks))/float64(len(docs)))
+			weight := 1.0
+			if term != q {
+				weight = prefixWeight
+			}
+			for id, tf := range docs {
+				// Every query word must match, so only tasks matched so far stay candidates
+				if i > 0 && matchedTerms[id] == nil {
+					continue
+				}
+				found[id] = true
+				scores[id] += tf * idf * weight
+				if matchedTerms[id] == nil {
+					matchedTerms[id] = map[string]bool{}
+				}
+				matchedTerms[id][term] = true
+			}
+		}
+
+		for id := range matchedTerms {
+			if !found[id] {
+				delete(matchedTerms, id)
+				delete(scores, id)
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		task := ix.tasks[id]
+		results = append(results, Result{
+			Task:  task,
+			Score: score,
+			Highlights: Highlights{
+				Title:       highlight(task.Title, matchedTerms[id]),
+				Description: highlight(task.Description, matchedTerms[id]),
+			},
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Task.ID < results[j].Task.ID
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// unique returns the distinct strings in order of first occurrence.
+func unique(values []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,62 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// token is a word of a text together with its byte offsets in the text.
+type token struct {
+	term       string // Lower-cased word
+	start, end int    // Byte offsets of the word in the text
+}
+
+// Tokenize splits text into lower-cased words made of letters and digits.
+func Tokenize(text string) []string {
+	tokens := tokenize(text)
+	terms := make([]string, len(tokens))
+	for i, t := range tokens {
+		terms[i] = t.term
+	}
+	return terms
+}
+
+// tokenize splits text into words, keeping their positions for highlighting.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			tokens = append(tokens, token{term: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{term: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// highlight escapes text as HTML and wraps the words matching any of the
+// given terms in <mark> tags, so that the result can be shown as HTML.
+func highlight(text string, matches map[string]bool) string {
+	var b strings.Builder
+	last := 0
+	for _, t := range tokenize(text) {
+		if !matches[t.term] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[last:t.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[t.start:t.end]))
+		b.WriteString("</mark>")
+		last = t.end
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
@@ -0,0 +1,26 @@
+package search
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text    string
+		matches []string
+		want    string
+	}{
+		{"Write the report", []string{"report"}, "Write the <mark>report</mark>"},
+		{"Fix <script>alert(1)</script> in report", []string{"report"}, "Fix &lt;script&gt;alert(1)&lt;/script&gt; in <mark>report</mark>"},
+		{"<b>bold</b> & co", []string{"b"}, "&lt;<mark>b</mark>&gt;bold&lt;/<mark>b</mark>&gt; &amp; co"},
+		{`"Quoted" words`, nil, "&#34;Quoted&#34; words"},
+		{"", []string{"x"}, ""},
+	}
+	for _, tt := range tests {
+		matches := map[string]bool{}
+		for _, m := range tt.matches {
+			matches[m] = true
+		}
+		if got := highlight(tt.text, matches); got != tt.want {
+			t.Errorf("highlight(%q, %v) = %q, want %q", tt.text, tt.matches, got, tt.want)
+		}
+	}
+}
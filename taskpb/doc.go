@@ -0,0 +1,5 @@
+// Package taskpb holds the protobuf messages and gRPC service of the task API,
+// generated from tasks.proto.
+package taskpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative tasks.proto
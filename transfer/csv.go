@@ -0,0 +1,246 @@
+package transfer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"task-organizer/models"
+	"time"
+)
+
+func init() {
+	register(&Format{Name: "csv", ContentType: "text/csv", Extension: "csv", newEncoder: newCSVEncoder, decode: decodeCSV})
+}
+
+// csvColumns are the columns of CSV files, in the order they are exported.
+// Tags and labels are joined with tagSeparator, escaped by a backslash within
+// them, and cells are read as they are; times are RFC 3339; the status
+// transitions are a JSON array, as in JSON exports.
+var csvColumns = []string{"id", "title", "description", "completed", "status", "workflow", "transitions", "priority", "tags", "labels", "due", "assignee", "recurrence", "position", "created_at", "updated_at", "expires_at"}
+
+const tagSeparator = ";"
+
+// csvEncoder writes tasks as CSV records below a header.
+type csvEncoder struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newCSVEncoder(w io.Writer) Encoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) Encode(task models.Task) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	transitions := ""
+	if len(task.Transitions) > 0 {
+		data, err := json.Marshal(task.Transitions)
+		if err != nil {
+			return err
+		}
+		transitions = string(data)
+	}
+	return e.w.Write([]string{
+		task.ID,
+		task.Title,
+		task.Description,
+		strconv.FormatBool(task.Completed),
+		task.Status,
+		task.Workflow,
+		transitions,
+		task.Priority,
+		joinList(task.Tags),
+		joinList(task.Labels),
+		formatTime(task.Due),
+		task.Assignee,
+		task.Recurrence,
+		task.Position,
+		formatTime(&task.CreatedAt),
+		formatTime(&task.UpdatedAt),
+		formatTime(task.ExpiresAt),
+	})
+}
+
+func (e *csvEncoder) Close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// writeHeader writes the header before the first record.
+func (e *csvEncoder) writeHeader() error {
+	if e.header {
+		return nil
+	}
+	e.header = true
+	return e.w.Write(csvColumns)
+}
+
+// decodeCSV reads tasks from CSV records. The header names the columns, which may
+// come in any order; only title is required.
+func decodeCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("missing CSV header")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !contains(csvColumns, name) {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("missing CSV column \"title\"")
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		row := Row{Line: line}
+		if len(record) != len(header) {
+			row.Err = fmt.Errorf("expected %d fields, got %d", len(header), len(record))
+		} else {
+			row.Task, row.Err = csvTask(record, columns)
+		}
+		rows = append(rows, row)
+	}
+}
+
+// csvTask reads a task from a CSV record.
+func csvTask(record []string, columns map[string]int) (models.Task, error) {
+	var task models.Task
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return record[i]
+		}
+		return ""
+	}
+
+	task.ID = field("id")
+	task.Title = field("title")
+	task.Description = field("description")
+	task.Priority = field("priority")
+	task.Assignee = field("assignee")
+	task.Recurrence = field("recurrence")
+	task.Status = field("status")
+	task.Workflow = field("workflow")
+	task.Position = field("position")
+	if v := field("tags"); v != "" {
+		task.Tags = splitList(v)
+	}
+	if v := field("labels"); v != "" {
+		task.Labels = splitList(v)
+	}
+	if v := field("transitions"); v != "" {
+		if err := json.Unmarshal([]byte(v), &task.Transitions); err != nil {
+			return task, fmt.Errorf("invalid transitions %q, expected a JSON array", v)
+		}
+	}
+	if v := field("completed"); v != "" {
+		completed, err := strconv.ParseBool(v)
+		if err != nil {
+			return task, fmt.Errorf("invalid completed %q", v)
+		}
+		task.Completed = completed
+	}
+
+	var err error
+	if task.Due, err = parseTime(field("due"), "due"); err != nil {
+		return task, err
+	}
+	if task.ExpiresAt, err = parseTime(field("expires_at"), "expires_at"); err != nil {
+		return task, err
+	}
+	for name, target := range map[string]*time.Time{"created_at": &task.CreatedAt, "updated_at": &task.UpdatedAt} {
+		t, err := parseTime(field(name), name)
+		if err != nil {
+			return task, err
+		}
+		if t != nil {
+			*target = *t
+		}
+	}
+	return task, nil
+}
+
+// joinList joins the values of a cell with tagSeparator, escaping the
+// separator and backslashes within them by a backslash.
+func joinList(values []string) string {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = strings.NewReplacer(`\`, `\\`, tagSeparator, `\`+tagSeparator).Replace(v)
+	}
+	return strings.Join(escaped, tagSeparator)
+}
+
+// splitList reverses joinList. A backslash before any other character is kept.
+func splitList(cell string) []string {
+	var values []string
+	var value strings.Builder
+	for i := 0; i < len(cell); i++ {
+		switch {
+		case cell[i] == '\\' && i+1 < len(cell) && (cell[i+1] == '\\' || cell[i+1] == tagSeparator[0]):
+			i++
+			value.WriteByte(cell[i])
+		case cell[i] == tagSeparator[0]:
+			values = append(values, value.String())
+			value.Reset()
+		default:
+			value.WriteByte(cell[i])
+		}
+	}
+	return append(values, value.String())
+}
+
+// formatTime formats an optional time as RFC 3339, or as an empty string if absent.
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// parseTime parses an optional RFC 3339 time of the named field.
+func parseTime(v, name string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q, expected an RFC 3339 time", name, v)
+	}
+	return &t, nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
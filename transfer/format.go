@@ -0,0 +1,67 @@
+package transfer
+
+import (
+	"io"
+	"sort"
+	"task-organizer/models"
+)
+
+// Encoder writes tasks in an export format.
+type Encoder interface {
+	Encode(task models.Task) error // Writes a task
+	Close() error                  // Completes the output after the last task
+}
+
+// Row is a task read from an import, or the reason it could not be read.
+type Row struct {
+	Line int         // Position of the row in the input: line, record or array element, from 1
+	Task models.Task // Task read from the row
+	Err  error       // Why the row could not be read
+}
+
+// Format describes a file format tasks can be exported to and imported from.
+type Format struct {
+	Name        string // Name of the format, as used in requests
+	ContentType string // MIME type of exported files
+	Extension   string // File name extension of exported files
+	Lossy       bool   // Whether exports leave out stored fields, so imports cannot overwrite tasks
+
+	newEncoder func(w io.Writer) Encoder
+	decode     func(r io.Reader) ([]Row, error)
+}
+
+// NewEncoder returns an encoder writing tasks in the format to w.
+func (f *Format) NewEncoder(w io.Writer) Encoder {
+	return f.newEncoder(w)
+}
+
+// Decode reads the tasks of an import in the format. Rows that cannot be read
+// are returned with an error; an error is only returned if the input as a whole
+// is unreadable.
+func (f *Format) Decode(r io.Reader) ([]Row, error) {
+	return f.decode(r)
+}
+
+// formats lists the supported formats by name.
+var formats = map[string]*Format{}
+
+// register adds a format to the supported ones.
+func register(f *Format) {
+	formats[f.Name] = f
+}
+
+// Lookup returns the format with the given name.
+func Lookup(name string) (*Format, bool) {
+	f, ok := formats[name]
+	return f, ok
+}
+
+// Names returns the names of the supported formats in order.
+func Names() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,376 @@
+package transfer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"task-organizer/models"
+	"time"
+	"unicode/utf8"
+)
+
+func init() {
+	register(&Format{Name: "ics", ContentType: "text/calendar; charset=utf-8", Extension: "ics", Lossy: true, newEncoder: newICalEncoder, decode: decodeICal})
+}
+
+// ProdID identifies the product that created exported calendars.
+const ProdID = "-//task-organizer//Tasks//EN"
+
+// icalTimeFormat is the UTC date-time form of RFC 5545.
+const icalTimeFormat = "20060102T150405Z"
+
+// icalPriorities maps task priorities to iCalendar priorities, where 1 is the highest.
+var icalPriorities = map[string]int{"urgent": 1, "high": 3, "medium": 5, "low": 9}
+
+// icalEncoder writes tasks as the VTODO components of an RFC 5545 calendar.
+// Fields calendars have no property for, such as the assignee, are left out.
+type icalEncoder struct {
+	w      io.Writer
+	header bool
+}
+
+func newICalEncoder(w io.Writer) Encoder {
+	return &icalEncoder{w: w}
+}
+
+func (e *icalEncoder) Encode(task models.Task) error {
+	var b strings.Builder
+	e.writeHeader(&b)
+	writeLine(&b, "BEGIN", "VTODO")
+	writeLine(&b, "UID", escapeText(task.ID))
+	writeLine(&b, "DTSTAMP", time.Now().UTC().Format(icalTimeFormat))
+	writeLine(&b, "CREATED", task.CreatedAt.UTC().Format(icalTimeFormat))
+	writeLine(&b, "LAST-MODIFIED", task.UpdatedAt.UTC().Format(icalTimeFormat))
+	writeLine(&b, "SUMMARY", escapeText(task.Title))
+	if task.Description != "" {
+		writeLine(&b, "DESCRIPTION", escapeText(task.Description))
+	}
+	if task.Completed {
+		writeLine(&b, "STATUS", "COMPLETED")
+	} else {
+		writeLine(&b, "STATUS", "NEEDS-ACTION")
+	}
+	if p, ok := icalPriorities[task.Priority]; ok {
+		writeLine(&b, "PRIORITY", strconv.Itoa(p))
+	}
+	if task.Due != nil {
+		writeLine(&b, "DUE", task.Due.UTC().Format(icalTimeFormat))
+	}
+	if len(task.Tags) > 0 {
+		tags := make([]string, len(task.Tags))
+		for i, tag := range task.Tags {
+			tags[i] = escapeText(tag)
+		}
+		writeLine(&b, "CATEGORIES", strings.Join(tags, ","))
+	}
+	if task.Recurrence != "" {
+		writeLine(&b, "RRULE", task.Recurrence)
+	}
+	writeLine(&b, "END", "VTODO")
+
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
+
+func (e *icalEncoder) Close() error {
+	var b strings.Builder
+	e.writeHeader(&b)
+	writeLine(&b, "END", "VCALENDAR")
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
+
+// writeHeader writes the start of the calendar before the first component.
+func (e *icalEncoder) writeHeader(b *strings.Builder) {
+	if e.header {
+		return
+	}
+	e.header = true
+	writeLine(b, "BEGIN", "VCALENDAR")
+	writeLine(b, "VERSION", "2.0")
+	writeLine(b, "PRODID", ProdID)
+	writeLine(b, "CALSCALE", "GREGORIAN")
+}
+
+// writeLine writes a content line, folded into lines of at most 75 octets.
+func writeLine(b *strings.Builder, name, value string) {
+	line := name + ":" + value
+	for len(line) > 75 {
+		// Fold before the last complete character that fits
+		cut := 75
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+	}
+	b.WriteString(line + "\r\n")
+}
+
+// escapeText escapes a TEXT value.
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// contentLine is a property of an iCalendar component.
+type contentLine struct {
+	line   int               // Line the property starts on
+	name   string            // Upper-cased property name
+	params map[string]string // Parameters by upper-cased name
+	value  string            // Raw value
+}
+
+// decodeICal reads tasks from the VTODO components of an RFC 5545 calendar.
+// Other components are ignored.
+func decodeICal(r io.Reader) ([]Row, error) {
+	lines, err := readContentLines(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || lines[0].name != "BEGIN" || !strings.EqualFold(lines[0].value, "VCALENDAR") {
+		return nil, errors.New("expected an iCalendar object starting with BEGIN:VCALENDAR")
+	}
+
+	var rows []Row
+	var todo []contentLine
+	depth, todoDepth := 0, 0
+	for _, cl := range lines {
+		switch cl.name {
+		case "BEGIN":
+			depth++
+			if todoDepth == 0 && strings.EqualFold(cl.value, "VTODO") {
+				todoDepth = depth
+				todo = []contentLine{cl}
+			}
+			continue
+		case "END":
+			if todoDepth == depth {
+				task, err := icalTask(todo[1:])
+				rows = append(rows, Row{Line: todo[0].line, Task: task, Err: err})
+				todoDepth = 0
+			}
+			depth--
+			continue
+		}
+		// Only the properties of the VTODO itself, not of nested alarms, are read
+		if todoDepth != 0 && depth == todoDepth {
+			todo = append(todo, cl)
+		}
+	}
+	if depth != 0 {
+		return nil, errors.New("unterminated iCalendar component")
+	}
+	return rows, nil
+}
+
+// readContentLines unfolds and parses the content lines of an iCalendar object.
+func readContentLines(r io.Reader) ([]contentLine, error) {
+	var lines []contentLine
+	var current strings.Builder
+	start := 0
+	flush := func() error {
+		if current.Len() == 0 {
+			return nil
+		}
+		cl, err := parseContentLine(current.String())
+		if err != nil {
+			return fmt.Errorf("line %d: %w", start, err)
+		}
+		cl.line = start
+		lines = append(lines, cl)
+		current.Reset()
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") {
+			current.WriteString(text[1:])
+			continue
+		}
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		start = n
+		current.WriteString(text)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// parseContentLine splits a content line into its name, parameters and value.
+func parseContentLine(line string) (contentLine, error) {
+	cl := contentLine{params: map[string]string{}}
+	end := strings.IndexAny(line, ";:")
+	if end <= 0 {
+		return cl, errors.New("expected NAME:VALUE")
+	}
+	cl.name = strings.ToUpper(line[:end])
+
+	// Parameters are separated by semicolons and may have quoted values
+	rest := line[end:]
+	for rest[0] == ';' {
+		rest = rest[1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return cl, errors.New("malformed parameter")
+		}
+		name := strings.ToUpper(rest[:eq])
+		rest = rest[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			q := strings.IndexByte(rest[1:], '"')
+			if q < 0 {
+				return cl, errors.New("unterminated parameter value")
+			}
+			value, rest = rest[1:q+1], rest[q+2:]
+		} else {
+			n := strings.IndexAny(rest, ";:")
+			if n < 0 {
+				return cl, errors.New("expected NAME:VALUE")
+			}
+			value, rest = rest[:n], rest[n:]
+		}
+		cl.params[name] = value
+		if rest == "" {
+			return cl, errors.New("expected NAME:VALUE")
+		}
+	}
+	cl.value = rest[1:]
+	return cl, nil
+}
+
+// icalTask reads a task from the properties of a VTODO.
+func icalTask(props []contentLine) (models.Task, error) {
+	var task models.Task
+	status := ""
+	completedAt := false
+	for _, p := range props {
+		var err error
+		switch p.name {
+		case "UID":
+			task.ID = unescapeText(p.value)
+		case "SUMMARY":
+			task.Title = unescapeText(p.value)
+		case "DESCRIPTION":
+			task.Description = unescapeText(p.value)
+		case "STATUS":
+			status = strings.ToUpper(p.value)
+		case "COMPLETED":
+			completedAt = true
+		case "PRIORITY":
+			task.Priority, err = taskPriority(p.value)
+		case "DUE":
+			task.Due, err = parseICalTime(p)
+		case "CATEGORIES":
+			task.Tags = append(task.Tags, splitText(p.value)...)
+		case "RRULE":
+			task.Recurrence = p.value
+		case "CREATED":
+			var t *time.Time
+			if t, err = parseICalTime(p); t != nil {
+				task.CreatedAt = *t
+			}
+		case "LAST-MODIFIED":
+			var t *time.Time
+			if t, err = parseICalTime(p); t != nil {
+				task.UpdatedAt = *t
+			}
+		}
+		if err != nil {
+			return task, fmt.Errorf("line %d: invalid %s: %w", p.line, p.name, err)
+		}
+	}
+	task.Completed = status == "COMPLETED" || (status == "" && completedAt)
+	return task, nil
+}
+
+// taskPriority maps an iCalendar priority to a task priority; 0 means none.
+func taskPriority(value string) (string, error) {
+	p, err := strconv.Atoi(value)
+	switch {
+	case err != nil || p < 0 || p > 9:
+		return "", errors.New("expected an integer from 0 to 9")
+	case p == 0:
+		return "", nil
+	case p <= 2:
+		return "urgent", nil
+	case p <= 4:
+		return "high", nil
+	case p == 5:
+		return "medium", nil
+	default:
+		return "low", nil
+	}
+}
+
+// parseICalTime parses a DATE or DATE-TIME value. Floating times and unknown
+// time zones are taken as UTC.
+func parseICalTime(p contentLine) (*time.Time, error) {
+	if strings.EqualFold(p.params["VALUE"], "DATE") || len(p.value) == len("20060102") {
+		t, err := time.Parse("20060102", p.value)
+		if err != nil {
+			return nil, errors.New("expected a date such as 20261101")
+		}
+		return &t, nil
+	}
+
+	loc := time.UTC
+	if tzid := p.params["TZID"]; tzid != "" && !strings.HasSuffix(p.value, "Z") {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", strings.TrimSuffix(p.value, "Z"), loc)
+	if err != nil {
+		return nil, errors.New("expected a date-time such as 20261101T090000Z")
+	}
+	t = t.UTC()
+	return &t, nil
+}
+
+// unescapeText reverses the escaping of a TEXT value.
+func unescapeText(s string) string {
+	return splitEscaped(s, 0)[0]
+}
+
+// splitText splits a list of TEXT values at unescaped commas and unescapes them.
+func splitText(s string) []string {
+	return splitEscaped(s, ',')
+}
+
+// splitEscaped unescapes a TEXT value, splitting it at unescaped occurrences of sep
+// unless sep is 0.
+func splitEscaped(s string, sep rune) []string {
+	var parts []string
+	var b strings.Builder
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			if r == 'n' || r == 'N' {
+				r = '\n'
+			}
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case sep != 0 && r == sep:
+			parts = append(parts, b.String())
+			b.Reset()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return append(parts, b.String())
+}
@@ -0,0 +1,106 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"task-organizer/models"
+)
+
+func init() {
+	register(&Format{Name: "json", ContentType: "application/json", Extension: "json", newEncoder: newJSONEncoder, decode: decodeJSON})
+	register(&Format{Name: "ndjson", ContentType: "application/x-ndjson", Extension: "ndjson", newEncoder: newNDJSONEncoder, decode: decodeNDJSON})
+}
+
+// jsonEncoder writes tasks as the elements of a JSON array.
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func newJSONEncoder(w io.Writer) Encoder {
+	return &jsonEncoder{w: w}
+}
+
+func (e *jsonEncoder) Encode(task models.Task) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if e.count == 0 {
+		sep = "[\n"
+	}
+	e.count++
+	_, err = e.w.Write(append([]byte(sep), data...))
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// decodeJSON reads a JSON array of tasks. Elements that are not valid tasks are
+// reported on their own; malformed JSON ends the import.
+func decodeJSON(r io.Reader) ([]Row, error) {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, errors.New("expected a JSON array of tasks")
+	}
+
+	var rows []Row
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		row := Row{Line: len(rows) + 1}
+		row.Err = models.DecodeStrict(raw, &row.Task)
+		rows = append(rows, row)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ndjsonEncoder writes tasks as one JSON object per line.
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func newNDJSONEncoder(w io.Writer) Encoder {
+	return &ndjsonEncoder{enc: json.NewEncoder(w)}
+}
+
+func (e *ndjsonEncoder) Encode(task models.Task) error {
+	return e.enc.Encode(task)
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return nil
+}
+
+// decodeNDJSON reads one task per line, skipping blank lines.
+func decodeNDJSON(r io.Reader) ([]Row, error) {
+	var rows []Row
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		data := scanner.Bytes()
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		row := Row{Line: line}
+		row.Err = models.DecodeStrict(data, &row.Task)
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
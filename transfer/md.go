@@ -0,0 +1,93 @@
+package transfer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"task-organizer/models"
+)
+
+func init() {
+	register(&Format{Name: "md", ContentType: "text/markdown; charset=utf-8", Extension: "md", Lossy: true, newEncoder: newMarkdownEncoder, decode: decodeMarkdown})
+}
+
+// checklistItem matches a Markdown checklist item such as "- [x] Title".
+var checklistItem = regexp.MustCompile(`^[-*+] \[([ xX])\](?: (.*))?$`)
+
+// markdownEncoder writes tasks as a Markdown checklist. Descriptions follow
+// their item as indented lines; the other fields are left out.
+type markdownEncoder struct {
+	w io.Writer
+}
+
+func newMarkdownEncoder(w io.Writer) Encoder {
+	return &markdownEncoder{w: w}
+}
+
+func (e *markdownEncoder) Encode(task models.Task) error {
+	check := " "
+	if task.Completed {
+		check = "x"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "- [%s] %s\n", check, oneLine(task.Title))
+	if task.Description != "" {
+		for _, line := range strings.Split(task.Description, "\n") {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
+
+func (e *markdownEncoder) Close() error {
+	return nil
+}
+
+// decodeMarkdown reads tasks from the items of a Markdown checklist. Indented
+// lines below an item make up its description; headings and blank lines are skipped.
+func decodeMarkdown(r io.Reader) ([]Row, error) {
+	var rows []Row
+	var description []string
+	finish := func() {
+		if len(rows) > 0 && len(description) > 0 {
+			rows[len(rows)-1].Task.Description = strings.Join(description, "\n")
+		}
+		description = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		switch {
+		case text == "", strings.HasPrefix(text, "#"):
+			continue
+		case strings.HasPrefix(text, "  "), strings.HasPrefix(text, "\t"):
+			if len(rows) > 0 && rows[len(rows)-1].Err == nil {
+				description = append(description, strings.TrimPrefix(strings.TrimPrefix(text, "\t"), "  "))
+				continue
+			}
+		}
+
+		finish()
+		row := Row{Line: line}
+		if m := checklistItem.FindStringSubmatch(text); m != nil {
+			row.Task.Title = strings.TrimSpace(m[2])
+			row.Task.Completed = m[1] != " "
+		} else {
+			row.Err = errors.New("expected a checklist item such as \"- [ ] Title\"")
+		}
+		rows = append(rows, row)
+	}
+	finish()
+	return rows, scanner.Err()
+}
+
+// oneLine joins the lines of a text with spaces, for use in a list item.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
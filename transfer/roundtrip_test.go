@@ -0,0 +1,227 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"task-organizer/models"
+)
+
+func date(year int, month time.Month, day, hour, min, sec, nsec int) *time.Time {
+	t := time.Date(year, month, day, hour, min, sec, nsec, time.UTC)
+	return &t
+}
+
+// sampleTasks are tasks with every field a format may carry set, and titles
+// that look like the syntax of the formats.
+func sampleTasks() []models.Task {
+	return []models.Task{
+		{
+			ID:          "0a1b2c3d-0000-4000-8000-000000000001",
+			Title:       "Write the report",
+			Description: "First line\nSecond line; with, punctuation \\ and 100%",
+			Priority:    "high",
+			Tags:        []string{"@office", "backend"},
+			Labels:      []string{"01M591X39198VN1PGB736XX2B3", "01M591X3D3Y0DT846NZ09R5KZD"},
+			Due:         date(2026, 11, 1, 0, 0, 0, 0), // A day without a time
+			Assignee:    "Ada Lovelace",
+			Recurrence:  "FREQ=WEEKLY;BYDAY=MO",
+			Position:    "a0V",
+			Workflow:    "review",
+			Status:      "in review",
+			Transitions: []models.Transition{{To: "open", At: *date(2026, 10, 1, 8, 42, 17, 123456789)}, {From: "open", To: "in review", At: *date(2026, 10, 2, 16, 5, 3, 987654321)}},
+			CreatedAt:   *date(2026, 10, 1, 8, 42, 17, 123456789),
+			UpdatedAt:   *date(2026, 10, 2, 16, 5, 3, 987654321),
+			ExpiresAt:   date(2026, 12, 24, 18, 30, 0, 500000000),
+		},
+		{
+			ID:          "2",
+			Title:       "Ship it",
+			Completed:   true,
+			Priority:    "urgent",
+			Status:      "done",
+			Transitions: []models.Transition{{To: "todo", At: *date(2026, 9, 30, 23, 59, 59, 999999999)}, {From: "todo", To: "done", At: *date(2026, 10, 20, 12, 0, 1, 1000)}},
+			Due:         date(2026, 10, 20, 9, 15, 30, 250000000),
+			CreatedAt:   *date(2026, 9, 30, 23, 59, 59, 999999999),
+			UpdatedAt:   *date(2026, 10, 20, 12, 0, 1, 1000),
+		},
+		{
+			// Title words that read as todo.txt tags, extensions and escapes
+			ID:        "3",
+			Title:     "%2Bfoo bar +baz @home due:soon label:x 100% %40 %25",
+			Priority:  "low",
+			CreatedAt: *date(2026, 10, 3, 0, 0, 0, 1),
+			UpdatedAt: *date(2026, 10, 3, 7, 30, 0, 0),
+		},
+		{
+			// Long enough for iCalendar to fold it inside a multi-byte character
+			ID:        "4",
+			Title:     "Überprüfung der Änderungen an der Bestandsführung für das nächste Geschäftsjahr",
+			Priority:  "medium",
+			Tags:      []string{"finance", "q4 planning", " padded; with \\; escapes\\ "},
+			CreatedAt: *date(2026, 10, 4, 14, 15, 16, 0),
+			UpdatedAt: *date(2026, 10, 4, 14, 15, 16, 0),
+		},
+	}
+}
+
+// lossyKeep returns, for the formats marked Lossy, the fields of a task they
+// carry. The other formats must carry every field.
+var lossyKeep = map[string]func(models.Task) models.Task{
+	"ics": func(task models.Task) models.Task {
+		// iCalendar times have no fractions of seconds
+		second := func(t time.Time) time.Time { return t.Truncate(time.Second) }
+		var due *time.Time
+		if task.Due != nil {
+			d := second(*task.Due)
+			due = &d
+		}
+		return models.Task{
+			ID:          task.ID,
+			Title:       task.Title,
+			Description: task.Description,
+			Completed:   task.Completed,
+			Priority:    task.Priority,
+			Tags:        task.Tags,
+			Due:         due,
+			Recurrence:  task.Recurrence,
+			CreatedAt:   second(task.CreatedAt),
+			UpdatedAt:   second(task.UpdatedAt),
+		}
+	},
+	"md": func(task models.Task) models.Task {
+		return models.Task{Title: task.Title, Completed: task.Completed, Description: task.Description}
+	},
+}
+
+// roundTrip exports tasks in a format and imports them again, returning the
+// rows read and the export.
+func roundTrip(t *testing.T, format *Format, tasks []models.Task) ([]Row, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := format.NewEncoder(&buf)
+	for _, task := range tasks {
+		if err := enc.Encode(task); err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	rows, err := format.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Decode: %v\n%s", err, buf.String())
+	}
+	if len(rows) != len(tasks) {
+		t.Fatalf("got %d rows, want %d\n%s", len(rows), len(tasks), buf.String())
+	}
+	for _, row := range rows {
+		if row.Err != nil {
+			t.Fatalf("row %d: %v\n%s", row.Line, row.Err, buf.String())
+		}
+	}
+	return rows, buf.String()
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+			format, _ := Lookup(name)
+			keep := func(task models.Task) models.Task { return task }
+			if format.Lossy {
+				var ok bool
+				if keep, ok = lossyKeep[name]; !ok {
+					t.Fatalf("lossy format %s does not say which fields it carries", name)
+				}
+			}
+
+			tasks := sampleTasks()
+			rows, exported := roundTrip(t, format, tasks)
+			for i, row := range rows {
+				want, got := mustJSON(t, keep(tasks[i])), mustJSON(t, row.Task)
+				if want != got {
+					t.Errorf("task %d changed in a round trip:\nwant %s\ngot  %s\nexported as:\n%s", i, want, got, exported)
+				}
+			}
+		})
+	}
+}
+
+func TestRoundTripEmpty(t *testing.T) {
+	for _, name := range []string{"json", "ndjson", "csv", "todotxt", "ics", "md"} {
+		t.Run(name, func(t *testing.T) {
+			format, _ := Lookup(name)
+			var buf bytes.Buffer
+			if err := format.NewEncoder(&buf).Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			rows, err := format.Decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("Decode: %v\n%s", err, buf.String())
+			}
+			if len(rows) != 0 {
+				t.Errorf("got %d rows from an empty export", len(rows))
+			}
+		})
+	}
+}
+
+func TestTodoTxtTitleWords(t *testing.T) {
+	tests := []string{
+		"%2Bfoo bar",
+		"+foo",
+		"@foo",
+		"%40foo",
+		"due:tomorrow",
+		"due%3Atomorrow",
+		"50%",
+		"%",
+		"%25",
+		"+",
+		"x marks the spot",
+	}
+	for _, title := range tests {
+		t.Run(title, func(t *testing.T) {
+			line := formatTodoTxt(models.Task{Title: title})
+			task, err := parseTodoTxt(line)
+			if err != nil {
+				t.Fatalf("parseTodoTxt(%q): %v", line, err)
+			}
+			if task.Title != title {
+				t.Errorf("title %q came back as %q from %q", title, task.Title, line)
+			}
+			if len(task.Tags) != 0 || task.Due != nil {
+				t.Errorf("title %q read as tags %v or due %v from %q", title, task.Tags, task.Due, line)
+			}
+		})
+	}
+}
+
+func TestLossyFormats(t *testing.T) {
+	for _, name := range Names() {
+		format, _ := Lookup(name)
+		tasks := sampleTasks()
+		rows, _ := roundTrip(t, format, tasks)
+		lossless := true
+		for i, row := range rows {
+			if mustJSON(t, row.Task) != mustJSON(t, tasks[i]) {
+				lossless = false
+			}
+		}
+		if format.Lossy == lossless {
+			t.Errorf("format %s: Lossy = %v, but a round trip lossless = %v", name, format.Lossy, lossless)
+		}
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,357 @@
+package transfer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"task-organizer/models"
+	"time"
+)
+
+func init() {
+	register(&Format{Name: "todotxt", ContentType: "text/plain; charset=utf-8", Extension: "txt", newEncoder: newTodoTxtEncoder, decode: decodeTodoTxt})
+}
+
+// todo.txt lines look like
+//
+//	(A) 2026-10-01 Write the report +backend @office due:2026-11-01
+//	x 2026-10-20 2026-10-01 Ship it +release pri:B
+//
+// The completion marker, priority and dates come first, followed by the title.
+// Tags become +project words, except tags starting with @, which are contexts.
+// Fields todo.txt has no syntax for are written as key:value extensions, with
+// one label:ID per label and one transition:time,from,to per status change.
+// Extensions and tags are percent-escaped where they contain spaces; title
+// words that would read as a tag or known extension are escaped too, so that
+// tasks survive a round trip. The leading dates only keep the day, so the
+// full creation and update times follow as created: and updated: extensions.
+
+// todoDateFormat is the date format of todo.txt.
+const todoDateFormat = "2006-01-02"
+
+// todoPriorities maps task priorities to todo.txt priorities.
+var todoPriorities = map[string]string{"urgent": "A", "high": "B", "medium": "C", "low": "D"}
+
+// todoExtensions lists the key:value extensions read into task fields.
+var todoExtensions = map[string]bool{"id": true, "due": true, "pri": true, "assignee": true, "rrule": true, "desc": true, "expires": true,
+	"status": true, "workflow": true, "label": true, "pos": true, "created": true, "updated": true, "transition": true}
+
+var (
+	todoPriority = regexp.MustCompile(`^\(([A-Z])\)$`)
+	todoDate     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// todoTxtEncoder writes tasks as todo.txt lines.
+type todoTxtEncoder struct {
+	w io.Writer
+}
+
+func newTodoTxtEncoder(w io.Writer) Encoder {
+	return &todoTxtEncoder{w: w}
+}
+
+func (e *todoTxtEncoder) Encode(task models.Task) error {
+	_, err := io.WriteString(e.w, formatTodoTxt(task)+"\n")
+	return err
+}
+
+func (e *todoTxtEncoder) Close() error {
+	return nil
+}
+
+// formatTodoTxt renders a task as a todo.txt line.
+func formatTodoTxt(task models.Task) string {
+	var words []string
+	priority := todoPriorities[task.Priority]
+	if task.Completed {
+		// Completed tasks carry their priority as an extension, as is customary
+		words = append(words, "x", task.UpdatedAt.UTC().Format(todoDateFormat))
+	} else if priority != "" {
+		words = append(words, "("+priority+")")
+	}
+	words = append(words, task.CreatedAt.UTC().Format(todoDateFormat))
+
+	for _, word := range strings.Split(task.Title, " ") {
+		words = append(words, escapeTitleWord(word))
+	}
+	for _, tag := range task.Tags {
+		if strings.HasPrefix(tag, "@") {
+			words = append(words, "@"+escapeWord(tag[1:]))
+		} else {
+			words = append(words, "+"+escapeWord(tag))
+		}
+	}
+
+	if task.Completed && priority != "" {
+		words = append(words, "pri:"+priority)
+	}
+	if task.Due != nil {
+		due := task.Due.UTC()
+		if due.Equal(due.Truncate(24 * time.Hour)) {
+			words = append(words, "due:"+due.Format(todoDateFormat))
+		} else {
+			words = append(words, "due:"+due.Format(time.RFC3339Nano))
+		}
+	}
+	if task.Assignee != "" {
+		words = append(words, "assignee:"+escapeWord(task.Assignee))
+	}
+	if task.Recurrence != "" {
+		words = append(words, "rrule:"+escapeWord(task.Recurrence))
+	}
+	if task.ExpiresAt != nil {
+		words = append(words, "expires:"+task.ExpiresAt.UTC().Format(time.RFC3339Nano))
+	}
+	if task.Description != "" {
+		words = append(words, "desc:"+escapeWord(task.Description))
+	}
+	if task.Workflow != "" {
+		words = append(words, "workflow:"+escapeWord(task.Workflow))
+	}
+	if task.Status != "" {
+		words = append(words, "status:"+escapeWord(task.Status))
+	}
+	for _, label := range task.Labels {
+		words = append(words, "label:"+escapeWord(label))
+	}
+	if task.Position != "" {
+		words = append(words, "pos:"+escapeWord(task.Position))
+	}
+	for _, tr := range task.Transitions {
+		words = append(words, "transition:"+tr.At.UTC().Format(time.RFC3339Nano)+","+escapeField(tr.From)+","+escapeField(tr.To))
+	}
+	if !task.CreatedAt.IsZero() {
+		words = append(words, "created:"+task.CreatedAt.UTC().Format(time.RFC3339Nano))
+	}
+	if !task.UpdatedAt.IsZero() {
+		words = append(words, "updated:"+task.UpdatedAt.UTC().Format(time.RFC3339Nano))
+	}
+	if task.ID != "" {
+		words = append(words, "id:"+escapeWord(task.ID))
+	}
+	return strings.Join(words, " ")
+}
+
+// decodeTodoTxt reads one task per line, skipping blank lines.
+func decodeTodoTxt(r io.Reader) ([]Row, error) {
+	var rows []Row
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		row := Row{Line: line}
+		row.Task, row.Err = parseTodoTxt(text)
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
+
+// parseTodoTxt reads a task from a todo.txt line.
+func parseTodoTxt(line string) (models.Task, error) {
+	var task models.Task
+	words := strings.Split(line, " ")
+	next := func(match func(string) bool) (string, bool) {
+		if len(words) > 0 && match(words[0]) {
+			word := words[0]
+			words = words[1:]
+			return word, true
+		}
+		return "", false
+	}
+	isDate := todoDate.MatchString
+
+	// Completion marker, priority and dates
+	if _, ok := next(func(w string) bool { return w == "x" }); ok {
+		task.Completed = true
+	}
+	if p, ok := next(todoPriority.MatchString); ok {
+		task.Priority = taskPriorityFromTodo(p[1:2])
+	}
+	first, hasFirst := next(isDate)
+	second, hasSecond := "", false
+	if task.Completed && hasFirst {
+		second, hasSecond = next(isDate)
+	}
+	var err error
+	switch {
+	case task.Completed && hasSecond:
+		if task.UpdatedAt, err = parseTodoDate(first, "completion date"); err == nil {
+			task.CreatedAt, err = parseTodoDate(second, "creation date")
+		}
+	case task.Completed && hasFirst:
+		task.UpdatedAt, err = parseTodoDate(first, "completion date")
+	case hasFirst:
+		task.CreatedAt, err = parseTodoDate(first, "creation date")
+	}
+	if err != nil {
+		return task, err
+	}
+
+	// Title words, tags and extensions
+	var title []string
+	for _, word := range words {
+		switch {
+		case len(word) > 1 && word[0] == '+':
+			task.Tags = append(task.Tags, unescapeWord(word[1:]))
+		case len(word) > 1 && word[0] == '@':
+			task.Tags = append(task.Tags, "@"+unescapeWord(word[1:]))
+		case isExtension(word):
+			key, value, _ := strings.Cut(word, ":")
+			if err := setTodoExtension(&task, key, value); err != nil {
+				return task, err
+			}
+		default:
+			title = append(title, unescapeTitleWord(word))
+		}
+	}
+	task.Title = strings.Join(title, " ")
+	return task, nil
+}
+
+// setTodoExtension sets the task field of a known key:value extension, given
+// its escaped value.
+func setTodoExtension(task *models.Task, key, value string) error {
+	if key == "transition" {
+		// The parts are unescaped once split, since they may contain escaped commas
+		parts := strings.Split(value, ",")
+		if len(parts) != 3 {
+			return fmt.Errorf("invalid transition %q, expected time,from,to", value)
+		}
+		at, err := time.Parse(time.RFC3339, parts[0])
+		if err != nil {
+			return fmt.Errorf("invalid transition time %q, expected an RFC 3339 time", parts[0])
+		}
+		task.Transitions = append(task.Transitions, models.Transition{From: unescapeWord(parts[1]), To: unescapeWord(parts[2]), At: at})
+		return nil
+	}
+
+	value = unescapeWord(value)
+	switch key {
+	case "id":
+		task.ID = value
+	case "pri":
+		task.Priority = taskPriorityFromTodo(value)
+	case "assignee":
+		task.Assignee = value
+	case "rrule":
+		task.Recurrence = value
+	case "desc":
+		task.Description = value
+	case "workflow":
+		task.Workflow = value
+	case "status":
+		task.Status = value
+	case "label":
+		task.Labels = append(task.Labels, value)
+	case "pos":
+		task.Position = value
+	case "due", "expires":
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			if t, err = parseTodoDate(value, key); err != nil {
+				return err
+			}
+		}
+		if key == "due" {
+			task.Due = &t
+		} else {
+			task.ExpiresAt = &t
+		}
+	case "created", "updated":
+		// These hold the full times the leading dates only keep the day of
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q, expected an RFC 3339 time", key, value)
+		}
+		if key == "created" {
+			task.CreatedAt = t
+		} else {
+			task.UpdatedAt = t
+		}
+	}
+	return nil
+}
+
+// taskPriorityFromTodo maps a todo.txt priority to a task priority;
+// priorities below D are low.
+func taskPriorityFromTodo(p string) string {
+	for priority, letter := range todoPriorities {
+		if letter == p {
+			return priority
+		}
+	}
+	if len(p) == 1 && p[0] > 'D' && p[0] <= 'Z' {
+		return "low"
+	}
+	return ""
+}
+
+// parseTodoDate parses a todo.txt date of the named field.
+func parseTodoDate(value, name string) (time.Time, error) {
+	t, err := time.Parse(todoDateFormat, value)
+	if err != nil {
+		return t, fmt.Errorf("invalid %s %q, expected YYYY-MM-DD", name, value)
+	}
+	return t, nil
+}
+
+// isExtension reports whether a word is a known key:value extension.
+func isExtension(word string) bool {
+	key, value, ok := strings.Cut(word, ":")
+	return ok && value != "" && todoExtensions[key]
+}
+
+// escapeWord percent-escapes the characters that would split a word.
+func escapeWord(s string) string {
+	return strings.NewReplacer("%", "%25", " ", "%20", "\t", "%09", "\n", "%0A", "\r", "%0D").Replace(s)
+}
+
+// escapeField escapes a part of a comma-separated extension value.
+func escapeField(s string) string {
+	return strings.ReplaceAll(escapeWord(s), ",", "%2C")
+}
+
+// unescapeWord reverses escapeWord, keeping words that are not validly escaped as they are.
+func unescapeWord(s string) string {
+	if u, err := url.PathUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
+
+// escapeTitleWord escapes a title word that would otherwise read as a tag or
+// extension. Percent signs are escaped first, so that words which only look
+// escaped come back as they were.
+func escapeTitleWord(word string) string {
+	word = strings.ReplaceAll(word, "%", "%25")
+	switch {
+	case len(word) > 1 && word[0] == '+':
+		return "%2B" + word[1:]
+	case len(word) > 1 && word[0] == '@':
+		return "%40" + word[1:]
+	case isExtension(word):
+		return strings.Replace(word, ":", "%3A", 1)
+	}
+	return word
+}
+
+// unescapeTitleWord reverses escapeTitleWord.
+func unescapeTitleWord(word string) string {
+	switch {
+	case strings.HasPrefix(word, "%2B") && len(word) > 3:
+		word = "+" + word[3:]
+	case strings.HasPrefix(word, "%40") && len(word) > 3:
+		word = "@" + word[3:]
+	default:
+		if key, value, ok := strings.Cut(word, "%3A"); ok && value != "" && todoExtensions[key] {
+			word = key + ":" + value
+		}
+	}
+	return strings.ReplaceAll(word, "%25", "%")
+}